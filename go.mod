@@ -6,5 +6,5 @@ require (
 	github.com/onsi/ginkgo v1.2.0-beta
 	github.com/onsi/gomega v0.0.0-20140708193218-efd113280743
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
-	gopkg.in/yaml.v2 v2.0.0-20150224225758-49c95bdc2184
+	gopkg.in/yaml.v3 v3.0.1
 )
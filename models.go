@@ -1,17 +1,67 @@
 package resource
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Source struct {
-	API           string `json:"api"`
-	Username      string `json:"username"`
-	Password      string `json:"password"`
-	ClientID      string `json:"client_id"`
-	ClientSecret  string `json:"client_secret"`
-	Organization  string `json:"organization"`
-	Space         string `json:"space"`
-	SkipCertCheck bool   `json:"skip_cert_check"`
-	Verbose       bool   `json:"verbose"`
+	API           string         `json:"api"`
+	Username      string         `json:"username"`
+	Password      string         `json:"password"`
+	ClientID      string         `json:"client_id"`
+	ClientSecret  string         `json:"client_secret"`
+	Organization  string         `json:"organization"`
+	Space         string         `json:"space"`
+	SkipCertCheck bool           `json:"skip_cert_check"`
+	Verbose       bool           `json:"verbose"`
+	Policy        Policy         `json:"policy"`
+	FreezeWindows []FreezeWindow `json:"freeze_windows"`
+}
+
+// FreezeWindow is a time when out refuses to deploy. It either repeats,
+// starting whenever Cron matches and lasting Duration, or runs once from
+// Start to End. Times are in TimeZone, UTC by default.
+type FreezeWindow struct {
+	Name     string `json:"name"`
+	Cron     string `json:"cron"`
+	Duration string `json:"duration"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"time_zone"`
+}
+
+// Policy limits what can be pushed. It is given inline, or as the path of a
+// YAML file with the same keys.
+type Policy struct {
+	File               string   `json:"-" yaml:"-"`
+	MaxMemory          string   `json:"max_memory" yaml:"max_memory"`
+	MaxInstances       int      `json:"max_instances" yaml:"max_instances"`
+	AllowedBuildpacks  []string `json:"allowed_buildpacks" yaml:"allowed_buildpacks"`
+	AllowedStacks      []string `json:"allowed_stacks" yaml:"allowed_stacks"`
+	RequiredEnv        []string `json:"required_env" yaml:"required_env"`
+	BanSkipCertCheck   bool     `json:"ban_skip_cert_check" yaml:"ban_skip_cert_check"`
+	RequireHealthCheck bool     `json:"require_health_check" yaml:"require_health_check"`
+}
+
+type policyFields Policy
+
+func (policy *Policy) UnmarshalJSON(data []byte) error {
+	var file string
+	if err := json.Unmarshal(data, &file); err == nil {
+		*policy = Policy{File: file}
+		return nil
+	}
+
+	*policy = Policy{}
+	return json.Unmarshal(data, (*policyFields)(policy))
+}
+
+func (policy Policy) MarshalJSON() ([]byte, error) {
+	if policy.File != "" {
+		return json.Marshal(policy.File)
+	}
+	return json.Marshal(policyFields(policy))
 }
 
 type Version struct {
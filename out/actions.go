@@ -0,0 +1,212 @@
+package out
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+const (
+	ActionPush    = "push"
+	ActionRestart = "restart"
+	ActionRestage = "restage"
+	ActionStart   = "start"
+	ActionStop    = "stop"
+	ActionDelete  = "delete"
+	ActionDestroy = "destroy"
+	ActionCleanup = "cleanup"
+)
+
+// StateDeleted is reported as the state of an app that was deleted or
+// destroyed.
+const StateDeleted = "DELETED"
+
+// pushes reports whether the put pushes the apps, rather than running a
+// lifecycle action on them.
+func (params Params) pushes() bool {
+	return params.Action == "" || params.Action == ActionPush
+}
+
+// checkAction fails on an unknown action, and on params that only apply to
+// a push, which a lifecycle action would silently ignore.
+func checkAction(params Params) error {
+	switch params.Action {
+	case "", ActionPush:
+		return nil
+	case ActionRestart, ActionRestage, ActionStart, ActionStop, ActionDelete, ActionCleanup:
+	case ActionDestroy:
+		if !params.Review.enabled() {
+			return fmt.Errorf("action %s destroys a review app, so it needs review.app_name", params.Action)
+		}
+	default:
+		return fmt.Errorf("unknown action %q, expected %q, %q, %q, %q, %q, %q, %q or %q", params.Action,
+			ActionPush, ActionRestart, ActionRestage, ActionStart, ActionStop, ActionDelete, ActionDestroy, ActionCleanup)
+	}
+
+	var pushOnly []string
+	if params.Path != "" {
+		pushOnly = append(pushOnly, "path")
+	}
+	if params.Strategy != "" {
+		pushOnly = append(pushOnly, "strategy")
+	}
+	if len(params.Scale) > 0 {
+		pushOnly = append(pushOnly, "scale")
+	}
+	// destroy deletes the review app's services
+	if len(params.Services) > 0 && params.Action != ActionDestroy {
+		pushOnly = append(pushOnly, "services")
+	}
+	if len(params.Routes.Map) > 0 || len(params.Routes.Unmap) > 0 {
+		pushOnly = append(pushOnly, "routes")
+	}
+	if len(params.Tasks.BeforeSwitch) > 0 || len(params.Tasks.AfterPush) > 0 {
+		pushOnly = append(pushOnly, "tasks")
+	}
+	if params.Action == ActionCleanup && params.Lock != "" {
+		pushOnly = append(pushOnly, "lock")
+	}
+	if len(pushOnly) > 0 {
+		return fmt.Errorf("action %s doesn't push, so it can't take %s", params.Action, strings.Join(pushOnly, ", "))
+	}
+	return nil
+}
+
+// startsApps reports whether the action leaves the apps running, so that
+// wait_for_running applies to it.
+func startsApps(action string) bool {
+	return action == ActionRestart || action == ActionRestage || action == ActionStart
+}
+
+// runAction restarts, restages, starts, stops or deletes the apps, one at a
+// time. Restarts and restages are rolling when the cf CLI supports it and the
+// app is running; a stopped app has nothing to keep up. Destroy deletes the
+// review app along with its route and services.
+func (command *Command) runAction(params Params, apps []string, version cli.Version) error {
+	action := params.Action
+	for _, name := range apps {
+		if action == ActionDelete || action == ActionDestroy {
+			fmt.Fprintf(command.log, "%s %s...\n", actionVerb(action), name)
+			if err := command.paas.DeleteApp(name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		app, err := command.paas.GetApp(name)
+		if err != nil {
+			return err
+		}
+
+		rolling := false
+		if action == ActionRestart || action == ActionRestage {
+			rolling = app.State == "STARTED" && version.Supports(cli.RollingStrategy)
+			if app.State == "STARTED" && !rolling {
+				fmt.Fprintf(command.log, "warning: cf CLI %s can't %s without downtime, %s %s in place\n", version, action, strings.ToLower(actionVerb(action)), name)
+			}
+		}
+
+		fmt.Fprintf(command.log, "%s %s...\n", actionVerb(action), name)
+		switch action {
+		case ActionRestart:
+			err = command.paas.RestartApp(name, rolling)
+		case ActionRestage:
+			err = command.paas.RestageApp(name, rolling)
+		case ActionStart:
+			err = command.paas.StartApp(name)
+		case ActionStop:
+			err = command.paas.StopApp(name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if action == ActionDestroy {
+		return command.destroyReview(params.Review, params.Services)
+	}
+	return nil
+}
+
+// actionMetadata reports each app as the action left it, including its
+// state.
+func (command *Command) actionMetadata(action string, apps []string, instanceCounts map[string]*InstanceCount) []resource.MetadataPair {
+	var pairs []resource.MetadataPair
+	for _, name := range apps {
+		if action == ActionDelete || action == ActionDestroy {
+			pairs = append(pairs,
+				resource.MetadataPair{Name: "app", Value: name},
+				resource.MetadataPair{Name: "state", Value: StateDeleted},
+			)
+			continue
+		}
+
+		app, err := command.paas.GetApp(name)
+		if err != nil {
+			// the action itself worked, so don't fail the put over metadata
+			fmt.Fprintf(command.log, "warning: could not read %s for the metadata: %s\n", name, err)
+			continue
+		}
+		pairs = append(pairs, appMetadata(app, instanceCounts[name])...)
+		pairs = append(pairs, resource.MetadataPair{Name: "state", Value: app.State})
+	}
+	return append(pairs, resource.MetadataPair{Name: "action", Value: action})
+}
+
+func actionSteps(action string, apps []string) []string {
+	steps := make([]string, len(apps))
+	for i, app := range apps {
+		steps[i] = fmt.Sprintf("%s %s", action, app)
+	}
+	return steps
+}
+
+func actionVerb(action string) string {
+	switch action {
+	case ActionRestart:
+		return "Restarting"
+	case ActionRestage:
+		return "Restaging"
+	case ActionStart:
+		return "Starting"
+	case ActionStop:
+		return "Stopping"
+	default:
+		return "Deleting"
+	}
+}
+
+// RestartApp restarts the app, with rolling restarting one instance at a
+// time so the app stays up.
+func (cf *CloudFoundry) RestartApp(name string, rolling bool) error {
+	return cf.run(rollingArgs([]string{"restart", name}, rolling)...)
+}
+
+// RestageApp stages the app again, e.g. to pick up a new buildpack, and
+// restarts it, with rolling one instance at a time.
+func (cf *CloudFoundry) RestageApp(name string, rolling bool) error {
+	return cf.run(rollingArgs([]string{"restage", name}, rolling)...)
+}
+
+func (cf *CloudFoundry) StartApp(name string) error {
+	return cf.run("start", name)
+}
+
+func (cf *CloudFoundry) StopApp(name string) error {
+	return cf.run("stop", name)
+}
+
+// DeleteApp deletes the app, leaving its routes and service instances. cf
+// succeeds when the app doesn't exist.
+func (cf *CloudFoundry) DeleteApp(name string) error {
+	return cf.run("delete", name, "-f")
+}
+
+func rollingArgs(args []string, rolling bool) []string {
+	if rolling {
+		return append(args, "--strategy", "rolling")
+	}
+	return args
+}
@@ -0,0 +1,197 @@
+package out_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Actions", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		log          *gbytes.Buffer
+		states       map[string]string
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+
+		states = map[string]string{"app1": "STARTED", "app2": "STARTED"}
+		cloudFoundry.GetAppStub = func(name string) (out.App, error) {
+			return out.App{Name: name, GUID: name + "-guid", State: states[name], Instances: 2}, nil
+		}
+		cloudFoundry.StopAppStub = func(name string) error {
+			states[name] = "STOPPED"
+			return nil
+		}
+
+		request = newRequest()
+		request.Params.Action = out.ActionRestart
+	})
+
+	It("restarts the manifest's apps with a rolling restart, instead of pushing", func() {
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+		Expect(cloudFoundry.RestartAppCallCount()).To(Equal(2))
+		name, rolling := cloudFoundry.RestartAppArgsForCall(1)
+		Expect(name).To(Equal("app2"))
+		Expect(rolling).To(BeTrue())
+
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "state", Value: "STARTED"}))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "action", Value: "restart"}))
+		Expect(response.Metadata[len(response.Metadata)-1].Name).To(Equal("durations"))
+		Expect(response.Metadata[len(response.Metadata)-1].Value).To(MatchRegexp(`^login \S+, target \S+, restart \S+, total \S+$`))
+	})
+
+	It("restages in place when the cf CLI can't do it without downtime", func() {
+		cloudFoundry.VersionReturns(cli.Version{Major: 6, Minor: 53}, nil)
+		request.Params.Action = out.ActionRestage
+		request.Params.CurrentAppName = "app1"
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.RestageAppCallCount()).To(Equal(1))
+		_, rolling := cloudFoundry.RestageAppArgsForCall(0)
+		Expect(rolling).To(BeFalse())
+		Expect(log).To(gbytes.Say("warning: cf CLI 6.53.0 can't restage without downtime, restaging app1 in place\n"))
+	})
+
+	It("doesn't roll a restart of a stopped app", func() {
+		states["app1"] = "STOPPED"
+		request.Params.CurrentAppName = "app1"
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, rolling := cloudFoundry.RestartAppArgsForCall(0)
+		Expect(rolling).To(BeFalse())
+	})
+
+	It("reports the state the action left the apps in", func() {
+		request.Params.Action = out.ActionStop
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.StopAppCallCount()).To(Equal(2))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "state", Value: "STOPPED"}))
+		Expect(response.Metadata).NotTo(ContainElement(resource.MetadataPair{Name: "state", Value: "STARTED"}))
+	})
+
+	It("waits for started apps with wait_for_running", func() {
+		request.Params.Action = out.ActionStart
+		request.Params.WaitForRunning = true
+		request.Params.StabilityWindow = "0s"
+		cloudFoundry.InstanceStatesReturns([]string{"RUNNING", "RUNNING"}, nil)
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.StartAppCallCount()).To(Equal(2))
+		Expect(cloudFoundry.InstanceStatesCallCount()).To(BeNumerically(">=", 2))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "instances", Value: "2/2 running"}))
+	})
+
+	It("deletes the apps and reports them as deleted", func() {
+		request.Params.Action = out.ActionDelete
+		request.Params.CurrentAppName = "app2"
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.DeleteAppCallCount()).To(Equal(1))
+		Expect(cloudFoundry.DeleteAppArgsForCall(0)).To(Equal("app2"))
+		Expect(cloudFoundry.GetAppCallCount()).To(Equal(0))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "app", Value: "app2"}))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "state", Value: out.StateDeleted}))
+	})
+
+	It("fails when the action fails", func() {
+		request.Params.Action = out.ActionStart
+		cloudFoundry.StartAppReturns(&cli.Error{Kind: cli.AppCrashed, Command: "start", Line: "Start unsuccessful"})
+
+		_, err := command.Run(request)
+		Expect(cli.Is(err, cli.AppCrashed)).To(BeTrue())
+		Expect(cloudFoundry.StartAppCallCount()).To(Equal(1))
+	})
+
+	It("plans the action in a dry run", func() {
+		request.Params.DryRun = true
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log).To(gbytes.Say(`1\. restart app1\n  2\. restart app2\n`))
+		Expect(cloudFoundry.RestartAppCallCount()).To(Equal(0))
+	})
+
+	It("doesn't let the policy or lint stop an app from being stopped", func() {
+		request.Params.Action = out.ActionStop
+		request.Params.ManifestPath = "assets/deprecatedManifest.yml"
+		request.Params.Lint = out.LintError
+		request.Source.Policy = resource.Policy{File: "assets/policy.yml"}
+		request.Source.SkipCertCheck = true
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.StopAppCallCount()).To(Equal(3))
+	})
+
+	It("rejects an unknown action, and params only a push takes, before logging in", func() {
+		request.Params.Action = "redeploy"
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(`unknown action "redeploy", expected "push", "restart", "restage", "start", "stop", "delete", "destroy" or "cleanup"`))
+
+		request.Params.Action = out.ActionStop
+		request.Params.Strategy = out.StrategyRolling
+		request.Params.Routes = out.Routes{Unmap: []out.Route{{Domain: "example.com"}}}
+		_, err = command.Run(request)
+		Expect(err).To(MatchError("action stop doesn't push, so it can't take strategy, routes"))
+
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+})
+
+var _ = Describe("CloudFoundry actions", func() {
+	var (
+		runner       *clifakes.FakeRunner
+		cloudFoundry *out.CloudFoundry
+	)
+
+	BeforeEach(func() {
+		runner = &clifakes.FakeRunner{}
+		cloudFoundry = out.NewCloudFoundry(context.Background(), runner)
+	})
+
+	It("runs the cf commands for each action", func() {
+		Expect(cloudFoundry.RestartApp("web", true)).To(BeNil())
+		Expect(cloudFoundry.RestartApp("web", false)).To(BeNil())
+		Expect(cloudFoundry.RestageApp("web", true)).To(BeNil())
+		Expect(cloudFoundry.StartApp("web")).To(BeNil())
+		Expect(cloudFoundry.StopApp("web")).To(BeNil())
+		Expect(cloudFoundry.DeleteApp("web")).To(BeNil())
+
+		Expect(commands(runner)).To(Equal([]string{
+			"cf restart web --strategy rolling",
+			"cf restart web",
+			"cf restage web --strategy rolling",
+			"cf start web",
+			"cf stop web",
+			"cf delete web -f",
+		}))
+	})
+})
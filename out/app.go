@@ -0,0 +1,200 @@
+package out
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+// App is the deployed state of an application, as reported by the Cloud
+// Controller.
+type App struct {
+	Name               string
+	GUID               string
+	State              string
+	Instances          int
+	MemoryMB           int
+	DiskMB             int
+	Buildpacks         []string
+	Stack              string
+	DropletGUID        string
+	DetectedBuildpacks []string
+	Env                map[string]string
+	Routes             []string
+	Services           []string
+}
+
+type v3App struct {
+	GUID      string `json:"guid"`
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	Lifecycle struct {
+		Data struct {
+			Buildpacks []string `json:"buildpacks"`
+			Stack      string   `json:"stack"`
+		} `json:"data"`
+	} `json:"lifecycle"`
+	Metadata struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+type v3Process struct {
+	Instances  int `json:"instances"`
+	MemoryInMB int `json:"memory_in_mb"`
+	DiskInMB   int `json:"disk_in_mb"`
+}
+
+type v3Droplet struct {
+	GUID       string `json:"guid"`
+	Stack      string `json:"stack"`
+	Buildpacks []struct {
+		Name          string `json:"name"`
+		BuildpackName string `json:"buildpack_name"`
+	} `json:"buildpacks"`
+}
+
+type v3EnvironmentVariables struct {
+	Var map[string]interface{} `json:"var"`
+}
+
+type v3Routes struct {
+	Resources []struct {
+		URL string `json:"url"`
+	} `json:"resources"`
+}
+
+type v3ServiceBindings struct {
+	Included struct {
+		ServiceInstances []struct {
+			Name string `json:"name"`
+		} `json:"service_instances"`
+	} `json:"included"`
+}
+
+func (cf *CloudFoundry) GetApp(name string) (App, error) {
+	guid, err := cf.appGUID(name)
+	if err != nil {
+		return App{}, err
+	}
+
+	var app v3App
+	if err := cf.curl("/v3/apps/"+guid, &app); err != nil {
+		return App{}, err
+	}
+
+	var process v3Process
+	if err := cf.curl("/v3/apps/"+guid+"/processes/web", &process); err != nil {
+		return App{}, err
+	}
+
+	// an app that has never been staged has no droplet
+	var droplet v3Droplet
+	if err := cf.curl("/v3/apps/"+guid+"/droplets/current", &droplet); err != nil && !cli.Is(err, cli.ResourceNotFound) {
+		return App{}, err
+	}
+
+	var env v3EnvironmentVariables
+	if err := cf.curl("/v3/apps/"+guid+"/environment_variables", &env); err != nil {
+		return App{}, err
+	}
+
+	var routes v3Routes
+	if err := cf.curl("/v3/apps/"+guid+"/routes", &routes); err != nil {
+		return App{}, err
+	}
+
+	var bindings v3ServiceBindings
+	if err := cf.curl("/v3/service_credential_bindings?app_guids="+guid+"&include=service_instance", &bindings); err != nil {
+		return App{}, err
+	}
+
+	deployed := App{
+		Name:        app.Name,
+		GUID:        app.GUID,
+		State:       app.State,
+		Instances:   process.Instances,
+		MemoryMB:    process.MemoryInMB,
+		DiskMB:      process.DiskInMB,
+		Buildpacks:  app.Lifecycle.Data.Buildpacks,
+		Stack:       app.Lifecycle.Data.Stack,
+		DropletGUID: droplet.GUID,
+		Env:         map[string]string{},
+	}
+
+	if droplet.Stack != "" {
+		deployed.Stack = droplet.Stack
+	}
+
+	for _, buildpack := range droplet.Buildpacks {
+		name := buildpack.BuildpackName
+		if name == "" {
+			name = buildpack.Name
+		}
+		deployed.DetectedBuildpacks = append(deployed.DetectedBuildpacks, name)
+	}
+
+	for key, value := range env.Var {
+		deployed.Env[key] = envString(value)
+	}
+
+	for _, route := range routes.Resources {
+		deployed.Routes = append(deployed.Routes, route.URL)
+	}
+	sort.Strings(deployed.Routes)
+
+	for _, instance := range bindings.Included.ServiceInstances {
+		deployed.Services = append(deployed.Services, instance.Name)
+	}
+	sort.Strings(deployed.Services)
+
+	return deployed, nil
+}
+
+type v3ProcessStats struct {
+	Resources []struct {
+		Index int    `json:"index"`
+		State string `json:"state"`
+	} `json:"resources"`
+}
+
+// InstanceStates returns the state (RUNNING, STARTING, CRASHED, DOWN) of
+// each desired instance of the app's web process, by index.
+func (cf *CloudFoundry) InstanceStates(name string) ([]string, error) {
+	guid, err := cf.appGUID(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats v3ProcessStats
+	if err := cf.curl("/v3/apps/"+guid+"/processes/web/stats", &stats); err != nil {
+		return nil, err
+	}
+
+	states := make([]string, len(stats.Resources))
+	for i, instance := range stats.Resources {
+		states[i] = instance.State
+	}
+
+	return states, nil
+}
+
+func (cf *CloudFoundry) appGUID(name string) (string, error) {
+	result, err := cf.runner.Run(cli.Quietly(cf.ctx), "app", name, "--guid")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// envString formats an environment variable value the way Cloud Foundry
+// hands it to the app.
+func envString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
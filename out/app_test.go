@@ -0,0 +1,97 @@
+package out_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+)
+
+// fakeAPI answers `cf curl` calls from a map of path to JSON body.
+func fakeAPI(runner *clifakes.FakeRunner, responses map[string]string) {
+	runner.RunStub = func(ctx context.Context, args ...string) (cli.Result, error) {
+		switch {
+		case args[0] == "app" && len(args) == 3 && args[2] == "--guid":
+			if guid, found := responses["guid:"+args[1]]; found {
+				return cli.Result{Stdout: guid + "\n"}, nil
+			}
+			return cli.Result{ExitCode: 1}, cli.Classify(args, "App '"+args[1]+"' not found.", errors.New("exit status 1"))
+		case args[0] == "curl":
+			if body, found := responses[args[1]]; found {
+				return cli.Result{Stdout: body}, nil
+			}
+			return cli.Result{Stdout: `{"errors":[{"code":10010,"title":"CF-ResourceNotFound","detail":"` + args[1] + ` not found"}]}`}, nil
+		default:
+			return cli.Result{Stdout: strings.Join(args, " ")}, nil
+		}
+	}
+}
+
+var _ = Describe("GetApp", func() {
+	var (
+		runner       *clifakes.FakeRunner
+		cloudFoundry *out.CloudFoundry
+	)
+
+	BeforeEach(func() {
+		runner = &clifakes.FakeRunner{}
+		cloudFoundry = out.NewCloudFoundry(context.Background(), runner)
+
+		fakeAPI(runner, map[string]string{
+			"guid:my-app":                             "app-guid",
+			"/v3/apps/app-guid":                       `{"guid":"app-guid","name":"my-app","state":"STARTED","lifecycle":{"data":{"buildpacks":["go_buildpack"],"stack":"cflinuxfs3"}}}`,
+			"/v3/apps/app-guid/processes/web":         `{"instances":2,"memory_in_mb":512,"disk_in_mb":1024}`,
+			"/v3/apps/app-guid/environment_variables": `{"var":{"A":"a","PORT":8080,"EMPTY":null}}`,
+			"/v3/apps/app-guid/routes":                `{"resources":[{"url":"b.example.com"},{"url":"a.example.com/path"}]}`,
+			"/v3/service_credential_bindings?app_guids=app-guid&include=service_instance": `{"included":{"service_instances":[{"name":"db"}]}}`,
+		})
+	})
+
+	It("reads the deployed state through the v3 API", func() {
+		app, err := cloudFoundry.GetApp("my-app")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(app).To(Equal(out.App{
+			Name:       "my-app",
+			GUID:       "app-guid",
+			State:      "STARTED",
+			Instances:  2,
+			MemoryMB:   512,
+			DiskMB:     1024,
+			Buildpacks: []string{"go_buildpack"},
+			Stack:      "cflinuxfs3",
+			Env:        map[string]string{"A": "a", "PORT": "8080", "EMPTY": ""},
+			Routes:     []string{"a.example.com/path", "b.example.com"},
+			Services:   []string{"db"},
+		}))
+	})
+
+	It("doesn't echo the API calls to the log", func() {
+		_, err := cloudFoundry.GetApp("my-app")
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := 0; i < runner.RunCallCount(); i++ {
+			ctx, _ := runner.RunArgsForCall(i)
+			Expect(ctx).To(Equal(cli.Quietly(context.Background())))
+		}
+	})
+
+	It("reports a missing app as AppNotFound", func() {
+		_, err := cloudFoundry.GetApp("missing-app")
+		Expect(cli.Is(err, cli.AppNotFound)).To(BeTrue())
+	})
+
+	It("reports API errors", func() {
+		fakeAPI(runner, map[string]string{"guid:my-app": "app-guid"})
+
+		_, err := cloudFoundry.GetApp("my-app")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("CF-ResourceNotFound: /v3/apps/app-guid not found"))
+	})
+})
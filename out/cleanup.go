@@ -0,0 +1,394 @@
+package out
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/concourse/cf-resource"
+)
+
+const (
+	DefaultCleanupLimit = 10
+
+	// DefaultCleanupAge keeps cleanup away from anything a deploy is still
+	// working on, such as the app a zero downtime push has just renamed
+	DefaultCleanupAge = 24 * time.Hour
+)
+
+// Cleanup selects the apps and unmapped routes of the targeted space that
+// the cleanup action deletes. Every filter that is set has to match.
+type Cleanup struct {
+	Apps      string `json:"apps"`
+	Routes    string `json:"routes"`
+	OlderThan string `json:"older_than"`
+	Stopped   bool   `json:"stopped"`
+	NoRoutes  bool   `json:"no_routes"`
+	Limit     *int   `json:"limit"`
+}
+
+// SpaceApp is an app of the targeted space, as the cleanup action sees it.
+type SpaceApp struct {
+	Name      string
+	GUID      string
+	State     string
+	UpdatedAt time.Time
+	Routes    int
+}
+
+// SpaceRoute is a route of the targeted space that is mapped to no app.
+type SpaceRoute struct {
+	Route     Route
+	UpdatedAt time.Time
+}
+
+// checkCleanup fails on filters that are invalid, or that would select
+// nothing, before logging in.
+func checkCleanup(cleanup Cleanup) error {
+	var problems []string
+	if cleanup.Apps == "" && cleanup.Routes == "" {
+		problems = append(problems, "apps or routes is required, e.g. apps: \"*-venerable\"")
+	}
+	if _, err := parseDuration(cleanup.OlderThan, DefaultCleanupAge); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid older_than: %s", err))
+	}
+	if cleanup.Limit != nil && *cleanup.Limit < 1 {
+		problems = append(problems, "limit must be at least 1")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid cleanup:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// globPattern turns a glob, where * matches anything, dots and slashes
+// included, and ? matches a single character, into a regexp.
+func globPattern(glob string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+type cleanupCandidate struct {
+	kind      string
+	name      string
+	updatedAt time.Time
+	route     Route
+}
+
+func (candidate cleanupCandidate) String() string {
+	return fmt.Sprintf("delete %s %s (last changed %s)", candidate.kind, candidate.name, candidate.updatedAt.Format(time.RFC3339))
+}
+
+// cleanup deletes the apps and unmapped routes of the targeted space that
+// match the filters, the oldest first and no more than the limit, so a
+// mistaken filter can only do so much damage in one put.
+func (command *Command) cleanup(request Request, phases *phases) (Response, error) {
+	cleanup := request.Params.Cleanup
+	if err := checkCleanup(cleanup); err != nil {
+		return Response{}, err
+	}
+
+	frozenBy, err := command.checkFreeze(request)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if err := command.logIn(request, phases); err != nil {
+		return Response{}, err
+	}
+
+	candidates, err := command.cleanupCandidates(cleanup, time.Now())
+	if err != nil {
+		return Response{}, err
+	}
+
+	limit := DefaultCleanupLimit
+	if cleanup.Limit != nil {
+		limit = *cleanup.Limit
+	}
+	if len(candidates) > limit {
+		fmt.Fprintf(command.log, "warning: %d apps and routes match, only the oldest %d are deleted; the rest are left for the next put\n", len(candidates), limit)
+		candidates = candidates[:limit]
+	}
+
+	if request.Params.DryRun {
+		plan := Plan{}
+		for _, candidate := range candidates {
+			plan.Steps = append(plan.Steps, candidate.String())
+		}
+		plan.Write(command.log)
+
+		response := newResponse(request)
+		response.Metadata = append(response.Metadata, resource.MetadataPair{Name: "dry_run", Value: "true"})
+		return response, nil
+	}
+
+	var deletedApps, deletedRoutes []string
+	err = phases.time(ActionCleanup, func() error {
+		for _, candidate := range candidates {
+			fmt.Fprintf(command.log, "Deleting %s %s...\n", candidate.kind, candidate.name)
+			if candidate.kind == "app" {
+				if err := command.paas.DeleteApp(candidate.name); err != nil {
+					return err
+				}
+				deletedApps = append(deletedApps, candidate.name)
+			} else {
+				if err := command.paas.DeleteRoute(candidate.route); err != nil {
+					return err
+				}
+				deletedRoutes = append(deletedRoutes, candidate.name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(command.log, "Durations: %s\n", phases)
+		return Response{}, err
+	}
+
+	response := newResponse(request)
+	response.Metadata = append(response.Metadata,
+		resource.MetadataPair{Name: "deleted_apps", Value: strings.Join(deletedApps, ", ")},
+		resource.MetadataPair{Name: "deleted_routes", Value: strings.Join(deletedRoutes, ", ")},
+		resource.MetadataPair{Name: "action", Value: ActionCleanup},
+		resource.MetadataPair{Name: "durations", Value: phases.String()},
+	)
+	response.Metadata = append(response.Metadata, freezeMetadata(request, frozenBy)...)
+	return response, nil
+}
+
+// cleanupCandidates lists what matches the filters, the oldest first. Apps
+// that a deploy holds the lock on are left alone, including the venerable
+// app of a zero downtime push, which is its way back.
+func (command *Command) cleanupCandidates(cleanup Cleanup, now time.Time) ([]cleanupCandidate, error) {
+	olderThan, _ := parseDuration(cleanup.OlderThan, DefaultCleanupAge)
+	cutoff := now.Add(-olderThan)
+
+	var candidates []cleanupCandidate
+
+	if cleanup.Apps != "" {
+		apps, err := command.paas.SpaceApps()
+		if err != nil {
+			return nil, err
+		}
+		pattern := globPattern(cleanup.Apps)
+		for _, app := range apps {
+			if !pattern.MatchString(app.Name) || app.UpdatedAt.After(cutoff) {
+				continue
+			}
+			if cleanup.Stopped && app.State != "STOPPED" {
+				continue
+			}
+			if cleanup.NoRoutes && app.Routes > 0 {
+				continue
+			}
+			held, err := command.heldLock(app.Name, now)
+			if err != nil {
+				return nil, err
+			}
+			if held != nil {
+				fmt.Fprintf(command.log, "Skipping %s: %s\n", app.Name, held)
+				continue
+			}
+			candidates = append(candidates, cleanupCandidate{kind: "app", name: app.Name, updatedAt: app.UpdatedAt})
+		}
+	}
+
+	if cleanup.Routes != "" {
+		routes, err := command.paas.UnmappedRoutes()
+		if err != nil {
+			return nil, err
+		}
+		pattern := globPattern(cleanup.Routes)
+		for _, route := range routes {
+			if !pattern.MatchString(route.Route.URL()) || route.UpdatedAt.After(cutoff) {
+				continue
+			}
+			candidates = append(candidates, cleanupCandidate{kind: "route", name: route.Route.URL(), updatedAt: route.UpdatedAt, route: route.Route})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].updatedAt.Before(candidates[j].updatedAt)
+	})
+	return candidates, nil
+}
+
+// heldLock returns the lock a deploy holds on the app, or on the app it is
+// the venerable copy of, if any.
+func (command *Command) heldLock(app string, now time.Time) (*LockError, error) {
+	names := []string{app}
+	if pushed := strings.TrimSuffix(app, "-venerable"); pushed != app {
+		names = append(names, pushed)
+	}
+
+	for _, name := range names {
+		current, err := command.readLock(lockKey(name))
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && now.Before(current.Expires) {
+			return &LockError{App: name, Owner: current.Owner, Expires: current.Expires}, nil
+		}
+	}
+	return nil, nil
+}
+
+type v3SpaceApps struct {
+	Pagination v3Pagination `json:"pagination"`
+	Resources  []struct {
+		GUID      string    `json:"guid"`
+		Name      string    `json:"name"`
+		State     string    `json:"state"`
+		UpdatedAt time.Time `json:"updated_at"`
+	} `json:"resources"`
+}
+
+type v3SpaceRouteDestinations struct {
+	Pagination v3Pagination `json:"pagination"`
+	Resources  []struct {
+		Host         string    `json:"host"`
+		Path         string    `json:"path"`
+		Port         int       `json:"port"`
+		UpdatedAt    time.Time `json:"updated_at"`
+		Destinations []struct {
+			App struct {
+				GUID string `json:"guid"`
+			} `json:"app"`
+		} `json:"destinations"`
+		Relationships struct {
+			Domain struct {
+				Data struct {
+					GUID string `json:"guid"`
+				} `json:"data"`
+			} `json:"domain"`
+		} `json:"relationships"`
+	} `json:"resources"`
+	Included struct {
+		Domains []struct {
+			GUID string `json:"guid"`
+			Name string `json:"name"`
+		} `json:"domains"`
+	} `json:"included"`
+}
+
+type v3Pagination struct {
+	Next *struct {
+		Href string `json:"href"`
+	} `json:"next"`
+}
+
+// nextPath returns the path of the next page, for cf curl, or "" on the last
+// page.
+func (pagination v3Pagination) nextPath() (string, error) {
+	if pagination.Next == nil || pagination.Next.Href == "" {
+		return "", nil
+	}
+	next, err := url.Parse(pagination.Next.Href)
+	if err != nil {
+		return "", fmt.Errorf("cf curl: reading the link to the next page: %s", err)
+	}
+	return next.RequestURI(), nil
+}
+
+// maxPerPage is the most the Cloud Controller returns in one page. Cleanup
+// reads every page, since an app whose routes it missed would look unmapped.
+const maxPerPage = "5000"
+
+// SpaceApps lists the apps of the targeted space, with how many routes are
+// mapped to each.
+func (cf *CloudFoundry) SpaceApps() ([]SpaceApp, error) {
+	spaceGUID, err := cf.spaceGUID()
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"space_guids": {spaceGUID}, "per_page": {maxPerPage}}
+
+	var apps v3SpaceApps
+	for path := "/v3/apps?" + query.Encode(); path != ""; {
+		var page v3SpaceApps
+		if err := cf.curl(path, &page); err != nil {
+			return nil, err
+		}
+		apps.Resources = append(apps.Resources, page.Resources...)
+		if path, err = page.Pagination.nextPath(); err != nil {
+			return nil, err
+		}
+	}
+	routes, err := cf.spaceRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	mapped := map[string]int{}
+	for _, route := range routes.Resources {
+		for _, destination := range route.Destinations {
+			mapped[destination.App.GUID]++
+		}
+	}
+
+	spaceApps := make([]SpaceApp, len(apps.Resources))
+	for i, app := range apps.Resources {
+		spaceApps[i] = SpaceApp{Name: app.Name, GUID: app.GUID, State: app.State, UpdatedAt: app.UpdatedAt, Routes: mapped[app.GUID]}
+	}
+	return spaceApps, nil
+}
+
+// UnmappedRoutes lists the routes of the targeted space that are mapped to
+// no app.
+func (cf *CloudFoundry) UnmappedRoutes() ([]SpaceRoute, error) {
+	routes, err := cf.spaceRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := map[string]string{}
+	for _, domain := range routes.Included.Domains {
+		domains[domain.GUID] = domain.Name
+	}
+
+	var unmapped []SpaceRoute
+	for _, route := range routes.Resources {
+		if len(route.Destinations) > 0 {
+			continue
+		}
+		domain, found := domains[route.Relationships.Domain.Data.GUID]
+		if !found {
+			return nil, errors.New("cf curl /v3/routes: a route's domain is missing from the response")
+		}
+		unmapped = append(unmapped, SpaceRoute{
+			Route:     Route{Host: route.Host, Domain: domain, Path: route.Path, Port: route.Port},
+			UpdatedAt: route.UpdatedAt,
+		})
+	}
+	return unmapped, nil
+}
+
+func (cf *CloudFoundry) spaceRoutes() (v3SpaceRouteDestinations, error) {
+	var routes v3SpaceRouteDestinations
+
+	spaceGUID, err := cf.spaceGUID()
+	if err != nil {
+		return routes, err
+	}
+	query := url.Values{"space_guids": {spaceGUID}, "include": {"domain"}, "per_page": {maxPerPage}}
+	for path := "/v3/routes?" + query.Encode(); path != ""; {
+		var page v3SpaceRouteDestinations
+		if err := cf.curl(path, &page); err != nil {
+			return routes, err
+		}
+		routes.Resources = append(routes.Resources, page.Resources...)
+		routes.Included.Domains = append(routes.Included.Domains, page.Included.Domains...)
+		if path, err = page.Pagination.nextPath(); err != nil {
+			return routes, err
+		}
+	}
+	return routes, nil
+}
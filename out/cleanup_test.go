@@ -0,0 +1,240 @@
+package out_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Cleanup", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		log          *gbytes.Buffer
+		deleted      []string
+	)
+
+	daysAgo := func(days int) time.Time { return time.Now().Add(-time.Duration(days) * 24 * time.Hour) }
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+		deleted = nil
+
+		cloudFoundry.SpaceAppsReturns([]out.SpaceApp{
+			{Name: "web", State: "STARTED", UpdatedAt: daysAgo(30), Routes: 1},
+			{Name: "web-venerable", State: "STOPPED", UpdatedAt: daysAgo(5)},
+			{Name: "api-venerable", State: "STARTED", UpdatedAt: daysAgo(9), Routes: 1},
+			{Name: "jobs-venerable", State: "STOPPED", UpdatedAt: daysAgo(0)},
+		}, nil)
+		cloudFoundry.UnmappedRoutesReturns([]out.SpaceRoute{
+			{Route: out.Route{Host: "myapp-pr-7", Domain: "review.example.com"}, UpdatedAt: daysAgo(7)},
+			{Route: out.Route{Host: "www", Domain: "example.com", Path: "/old"}, UpdatedAt: daysAgo(60)},
+		}, nil)
+		cloudFoundry.DeleteAppStub = func(name string) error {
+			deleted = append(deleted, "app "+name)
+			return nil
+		}
+		cloudFoundry.DeleteRouteStub = func(route out.Route) error {
+			deleted = append(deleted, "route "+route.URL())
+			return nil
+		}
+
+		request = newRequest()
+		request.Params.Action = out.ActionCleanup
+		request.Params.Cleanup = out.Cleanup{
+			Apps:      "*-venerable",
+			OlderThan: "72h",
+		}
+	})
+
+	It("deletes the matching apps, the oldest first, without a manifest", func() {
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(deleted).To(Equal([]string{"app api-venerable", "app web-venerable"}))
+		Expect(cloudFoundry.UnmappedRoutesCallCount()).To(Equal(0))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "deleted_apps", Value: "api-venerable, web-venerable"}))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "action", Value: "cleanup"}))
+	})
+
+	It("only deletes stopped apps without routes when asked to", func() {
+		request.Params.Cleanup.Stopped = true
+		request.Params.Cleanup.NoRoutes = true
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal([]string{"app web-venerable"}))
+	})
+
+	It("deletes unmapped routes that match", func() {
+		request.Params.Cleanup = out.Cleanup{Routes: "*.review.example.com"}
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(deleted).To(Equal([]string{"route myapp-pr-7.review.example.com"}))
+		Expect(cloudFoundry.DeleteRouteArgsForCall(0)).To(Equal(out.Route{Host: "myapp-pr-7", Domain: "review.example.com"}))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "deleted_routes", Value: "myapp-pr-7.review.example.com"}))
+	})
+
+	It("deletes no more than the limit, leaving the newest", func() {
+		limit := 2
+		request.Params.Cleanup = out.Cleanup{Apps: "*", Routes: "*", Limit: &limit}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(deleted).To(Equal([]string{"route www.example.com/old", "app web"}))
+		Expect(log).To(gbytes.Say("warning: 5 apps and routes match, only the oldest 2 are deleted; the rest are left for the next put\n"))
+	})
+
+	It("leaves apps alone for a day unless older_than says otherwise", func() {
+		request.Params.Cleanup = out.Cleanup{Apps: "jobs-*"}
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(BeEmpty())
+
+		request.Params.Cleanup.OlderThan = "0s"
+		_, err = command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal([]string{"app jobs-venerable"}))
+	})
+
+	It("leaves the venerable app of a deploy that holds the lock", func() {
+		held := otherLock("web", time.Now().Add(time.Hour))
+		cloudFoundry.SpaceAnnotationStub = func(key string) (string, error) {
+			if key == lockKey+"web" {
+				return held, nil
+			}
+			return "", nil
+		}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal([]string{"app api-venerable"}))
+		Expect(log).To(gbytes.Say("Skipping web-venerable: web is being deployed by other-pipeline/deploy #7, whose lock expires at "))
+	})
+
+	It("lists what it would delete in a dry run", func() {
+		request.Params.DryRun = true
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(deleted).To(BeEmpty())
+		Expect(log).To(gbytes.Say(`1\. delete app api-venerable \(last changed \S+\)\n  2\. delete app web-venerable \(last changed \S+\)\n`))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "dry_run", Value: "true"}))
+	})
+
+	It("stops at the first failure", func() {
+		cloudFoundry.DeleteAppReturnsOnCall(0, &cli.Error{Kind: cli.AppNotFound, Command: "delete"})
+
+		_, err := command.Run(request)
+		Expect(cli.Is(err, cli.AppNotFound)).To(BeTrue())
+		Expect(cloudFoundry.DeleteAppCallCount()).To(Equal(1))
+	})
+
+	It("rejects filters that select nothing, before logging in", func() {
+		zero := 0
+		request.Params.Cleanup = out.Cleanup{OlderThan: "a week", Limit: &zero}
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(`invalid cleanup:
+  apps or routes is required, e.g. apps: "*-venerable"
+  invalid older_than: time: invalid duration "a week"
+  limit must be at least 1`))
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+
+		request.Params.Cleanup = out.Cleanup{Apps: "*-venerable"}
+		request.Params.Lock = out.LockWait
+		_, err = command.Run(request)
+		Expect(err).To(MatchError("action cleanup doesn't push, so it can't take lock"))
+	})
+})
+
+var _ = Describe("CloudFoundry cleanup", func() {
+	var (
+		runner       *clifakes.FakeRunner
+		cloudFoundry *out.CloudFoundry
+		responses    map[string]string
+	)
+
+	BeforeEach(func() {
+		runner = &clifakes.FakeRunner{}
+		cloudFoundry = out.NewCloudFoundry(context.Background(), runner)
+
+		responses = map[string]string{
+			"/v3/apps?per_page=5000&space_guids=space-guid": `{"resources": [
+				{"guid": "web-guid", "name": "web", "state": "STARTED", "updated_at": "2026-10-01T12:00:00Z"},
+				{"guid": "old-guid", "name": "web-venerable", "state": "STOPPED", "updated_at": "2026-09-01T12:00:00Z"}
+			]}`,
+			"/v3/routes?include=domain&per_page=5000&space_guids=space-guid": `{
+				"resources": [
+					{"host": "www", "updated_at": "2026-10-01T12:00:00Z", "destinations": [{"app": {"guid": "web-guid"}}], "relationships": {"domain": {"data": {"guid": "example-guid"}}}},
+					{"host": "", "port": 1024, "updated_at": "2026-08-01T12:00:00Z", "destinations": [], "relationships": {"domain": {"data": {"guid": "tcp-guid"}}}}
+				],
+				"included": {"domains": [{"guid": "example-guid", "name": "example.com"}, {"guid": "tcp-guid", "name": "tcp.example.com"}]}
+			}`,
+		}
+		runner.RunStub = func(ctx context.Context, args ...string) (cli.Result, error) {
+			switch args[0] {
+			case "space":
+				return cli.Result{Stdout: "space-guid\n"}, nil
+			case "curl":
+				return cli.Result{Stdout: responses[args[1]]}, nil
+			}
+			return cli.Result{}, nil
+		}
+
+		Expect(cloudFoundry.Target("org", "my-space")).To(BeNil())
+	})
+
+	It("lists the space's apps with how many routes each has", func() {
+		apps, err := cloudFoundry.SpaceApps()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apps).To(Equal([]out.SpaceApp{
+			{Name: "web", GUID: "web-guid", State: "STARTED", UpdatedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), Routes: 1},
+			{Name: "web-venerable", GUID: "old-guid", State: "STOPPED", UpdatedAt: time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)},
+		}))
+	})
+
+	It("counts the routes on every page", func() {
+		responses["/v3/routes?include=domain&per_page=5000&space_guids=space-guid"] = `{
+			"pagination": {"next": {"href": "https://api.example.com/v3/routes?include=domain&page=2&per_page=5000&space_guids=space-guid"}},
+			"resources": [],
+			"included": {"domains": []}
+		}`
+		responses["/v3/routes?include=domain&page=2&per_page=5000&space_guids=space-guid"] = `{
+			"pagination": {"next": null},
+			"resources": [
+				{"host": "old", "updated_at": "2026-09-01T12:00:00Z", "destinations": [{"app": {"guid": "old-guid"}}], "relationships": {"domain": {"data": {"guid": "example-guid"}}}}
+			],
+			"included": {"domains": [{"guid": "example-guid", "name": "example.com"}]}
+		}`
+
+		apps, err := cloudFoundry.SpaceApps()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apps[1].Name).To(Equal("web-venerable"))
+		Expect(apps[1].Routes).To(Equal(1))
+	})
+
+	It("lists the space's routes that are mapped to no app", func() {
+		routes, err := cloudFoundry.UnmappedRoutes()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(routes).To(Equal([]out.SpaceRoute{
+			{Route: out.Route{Domain: "tcp.example.com", Port: 1024}, UpdatedAt: time.Date(2026, 8, 1, 12, 0, 0, 0, time.UTC)},
+		}))
+	})
+})
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type apiErrors struct {
+	Errors []struct {
+		Code   int    `json:"code"`
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// Curl calls the Cloud Controller API through `cf curl`, which reuses the
+// CLI's session, and decodes the JSON response into v. Extra args, such as
+// `-X POST -d {...}`, are passed on to cf curl. `cf curl` exits 0 on API
+// errors, so those are read from the response body instead.
+func Curl(ctx context.Context, cf Runner, path string, v interface{}, args ...string) error {
+	curlArgs := append([]string{"curl", path}, args...)
+
+	result, err := cf.Run(Quietly(ctx), curlArgs...)
+	if err != nil {
+		return err
+	}
+
+	body := strings.TrimSpace(result.Stdout)
+	if body == "" {
+		return nil
+	}
+
+	var failure apiErrors
+	if err := json.Unmarshal([]byte(body), &failure); err != nil {
+		return fmt.Errorf("cf curl %s: invalid response: %s", path, err)
+	}
+
+	if len(failure.Errors) > 0 {
+		details := make([]string, len(failure.Errors))
+		for i, e := range failure.Errors {
+			details[i] = fmt.Sprintf("%s: %s", e.Title, e.Detail)
+		}
+
+		return Classify([]string{"curl", path}, strings.Join(details, "\n"), fmt.Errorf("cf curl %s failed", path))
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(body), v)
+}
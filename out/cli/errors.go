@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Unknown Kind = iota
+	AuthenticationFailed
+	OrganizationNotFound
+	SpaceNotFound
+	AppNotFound
+	QuotaExceeded
+	StagingFailed
+	StartTimeout
+	AppCrashed
+	RouteTaken
+	BuildpackNotFound
+	ResourceNotFound
+	TaskFailed
+	ServiceFailed
+)
+
+func (kind Kind) String() string {
+	switch kind {
+	case AuthenticationFailed:
+		return "authentication failed"
+	case OrganizationNotFound:
+		return "organization not found"
+	case SpaceNotFound:
+		return "space not found"
+	case AppNotFound:
+		return "app not found"
+	case QuotaExceeded:
+		return "quota exceeded"
+	case StagingFailed:
+		return "staging failed"
+	case StartTimeout:
+		return "start timed out"
+	case AppCrashed:
+		return "app crashed"
+	case RouteTaken:
+		return "route taken"
+	case BuildpackNotFound:
+		return "buildpack not found"
+	case ResourceNotFound:
+		return "not found"
+	case TaskFailed:
+		return "task failed"
+	case ServiceFailed:
+		return "service failed"
+	default:
+		return "command failed"
+	}
+}
+
+// Hint is a short remediation suggestion shown alongside the error.
+func (kind Kind) Hint() string {
+	switch kind {
+	case AuthenticationFailed:
+		return "check source.username/password or source.client_id/client_secret and that the user can log in to source.api"
+	case OrganizationNotFound:
+		return "check source.organization and that the user is a member of it"
+	case SpaceNotFound:
+		return "check source.space and that the user has the SpaceDeveloper role in it"
+	case AppNotFound:
+		return "check current_app_name and that the app exists in the targeted space"
+	case QuotaExceeded:
+		return "lower memory, disk or instances in the manifest, or ask an admin to raise the org/space quota"
+	case StagingFailed:
+		return "inspect the staging output above; set show_app_log to see the recent app logs"
+	case StartTimeout:
+		return "check the app's health check and start command; set show_app_log to see why it did not start"
+	case AppCrashed:
+		return "the app started but did not stay up; set show_app_log or run `cf logs --recent` to see why"
+	case RouteTaken:
+		return "choose a different host or path in the manifest, or unmap the route from the app that owns it"
+	case BuildpackNotFound:
+		return "check the buildpack names in the manifest against `cf buildpacks`"
+	case ResourceNotFound:
+		return "check the name and that it exists in the targeted space"
+	case TaskFailed:
+		return "inspect the task's logs above, or run `cf tasks` and `cf logs --recent` against the app"
+	case ServiceFailed:
+		return "check the service's offering, plan and parameters against `cf marketplace`, and `cf service` for the broker's message"
+	default:
+		return ""
+	}
+}
+
+type pattern struct {
+	kind Kind
+	re   *regexp.Regexp
+	// commands limits the pattern to the output of these cf commands, for
+	// messages that only mean something there.
+	commands []string
+}
+
+func (p pattern) appliesTo(command string) bool {
+	if len(p.commands) == 0 {
+		return true
+	}
+	for _, c := range p.commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// patterns are checked in order, so the more specific ones come first. A
+// push's output includes the app's staging and start logs, so what went
+// wrong with the app outranks what looks like cf failing to log in, and the
+// authentication patterns only match cf's own messages.
+var patterns = []pattern{
+	{kind: QuotaExceeded, re: regexp.MustCompile(`(?i)exceeded .*(memory|instance|route|quota) limit|quota exceeded|quota_exceeded|limit_exceeded`)},
+	{kind: BuildpackNotFound, re: regexp.MustCompile(`(?i)buildpack '?[^\s']*'? not found|unknown buildpack|buildpack not found|NoCompatibleBuildpack`)},
+	{kind: RouteTaken, re: regexp.MustCompile(`(?i)route .* (is already in use|has already been taken)|host is taken|already in use by another app`)},
+	{kind: StagingFailed, re: regexp.MustCompile(`(?i)staging ?error|error staging application|staging failed|NoAppDetectedError`)},
+	{kind: StartTimeout, re: regexp.MustCompile(`(?i)start app timeout|start unsuccessful|timed out waiting for .*start|instances? failed to start`)},
+	{kind: AppCrashed, re: regexp.MustCompile(`(?i)instances? crashed|app instance exited|crashing`)},
+	{kind: AuthenticationFailed, re: regexp.MustCompile(`(?i)^(credentials were rejected|authentication has expired|not logged in)`)},
+	{kind: AuthenticationFailed, re: regexp.MustCompile(`(?i)^unauthorized|bad credentials|invalid_client`), commands: []string{"api", "auth", "login"}},
+	{kind: OrganizationNotFound, re: regexp.MustCompile(`(?i)organization '?[^\s']*'? not found`)},
+	{kind: SpaceNotFound, re: regexp.MustCompile(`(?i)space '?[^\s']*'? not found`)},
+	{kind: AppNotFound, re: regexp.MustCompile(`(?i)app '?[^\s']*'? not found|app .* does not exist`)},
+	{kind: ResourceNotFound, re: regexp.MustCompile(`CF-ResourceNotFound|CF-\w+NotFound`)},
+}
+
+type Error struct {
+	Kind    Kind
+	Command string
+	Line    string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Line == "" {
+		return fmt.Sprintf("cf %s: %s: %s", e.Command, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("cf %s: %s: %s", e.Command, e.Kind, e.Line)
+}
+
+func (e *Error) Hint() string {
+	return e.Kind.Hint()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify turns the failure of `cf args...` into an *Error, using the
+// captured output to work out what went wrong.
+func Classify(args []string, output string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	command := ""
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	lines := strings.Split(output, "\n")
+	for _, p := range patterns {
+		if !p.appliesTo(command) {
+			continue
+		}
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if p.re.MatchString(line) {
+				return &Error{Kind: p.kind, Command: command, Line: line, Err: err}
+			}
+		}
+	}
+
+	return &Error{Kind: Unknown, Command: command, Line: lastLine(output), Err: err}
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// Is reports whether err, or any error it wraps, is an *Error of the given kind.
+func Is(err error, kind Kind) bool {
+	var cliErr *Error
+	return errors.As(err, &cliErr) && cliErr.Kind == kind
+}
@@ -0,0 +1,99 @@
+package cli_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+var _ = Describe("Classify", func() {
+	exitErr := errors.New("exit status 1")
+
+	classify := func(output string) *cli.Error {
+		err := cli.Classify([]string{"push", "my-app"}, output, exitErr)
+		Expect(err).To(HaveOccurred())
+
+		cliErr, ok := err.(*cli.Error)
+		Expect(ok).To(BeTrue())
+		return cliErr
+	}
+
+	It("returns nil when the command succeeded", func() {
+		Expect(cli.Classify([]string{"push"}, "OK", nil)).To(BeNil())
+	})
+
+	It("recognises known failures", func() {
+		examples := map[string]cli.Kind{
+			"Credentials were rejected, please try again.":                           cli.AuthenticationFailed,
+			"Organization 'nope' not found.":                                         cli.OrganizationNotFound,
+			"Space 'nope' not found.":                                                cli.SpaceNotFound,
+			"App 'my-app' not found.":                                                cli.AppNotFound,
+			"You have exceeded your organization's memory limit: app requested more": cli.QuotaExceeded,
+			"StagingError - Staging error: staging failed":                           cli.StagingFailed,
+			"Start app timeout":                                                      cli.StartTimeout,
+			"The route my-app.example.com is already in use.":                        cli.RouteTaken,
+			"Buildpack 'rust_buildpack' not found":                                   cli.BuildpackNotFound,
+		}
+
+		for output, kind := range examples {
+			cliErr := classify(fmt.Sprintf("Pushing app my-app...\n%s\nFAILED\n", output))
+			Expect(cliErr.Kind).To(Equal(kind), output)
+			Expect(cliErr.Line).To(Equal(output))
+			Expect(cliErr.Hint()).NotTo(BeEmpty())
+		}
+	})
+
+	It("prefers what went wrong with the app over what its logs say", func() {
+		output := strings.Join([]string{
+			"Staging app and tracing logs...",
+			"   npm ERR! code E401",
+			"   npm ERR! 401 Unauthorized - GET https://npm.example.com/left-pad",
+			"Error staging application: App staging failed in the buildpack compile phase",
+			"FAILED",
+		}, "\n")
+
+		cliErr := classify(output)
+		Expect(cliErr.Kind).To(Equal(cli.StagingFailed))
+		Expect(cliErr.Line).To(Equal("Error staging application: App staging failed in the buildpack compile phase"))
+	})
+
+	It("only takes unauthorized as a failed login from the commands that log in", func() {
+		err := cli.Classify([]string{"auth"}, "Unauthorized", exitErr)
+		Expect(cli.Is(err, cli.AuthenticationFailed)).To(BeTrue())
+
+		Expect(classify("GET /private 401 Unauthorized").Kind).To(Equal(cli.Unknown))
+	})
+
+	It("falls back to the last line of output for unknown failures", func() {
+		cliErr := classify("Pushing app my-app...\nsomething odd happened\n")
+		Expect(cliErr.Kind).To(Equal(cli.Unknown))
+		Expect(cliErr.Hint()).To(BeEmpty())
+		Expect(cliErr).To(MatchError("cf push: command failed: something odd happened"))
+	})
+
+	It("falls back to the exit error when there was no output", func() {
+		cliErr := classify("")
+		Expect(cliErr).To(MatchError("cf push: command failed: exit status 1"))
+	})
+
+	It("keeps the underlying error", func() {
+		cliErr := classify("Start unsuccessful")
+		Expect(errors.Is(cliErr, exitErr)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Is", func() {
+	It("finds the kind through wrapped errors", func() {
+		err := cli.Classify([]string{"auth"}, "Credentials were rejected", errors.New("exit status 1"))
+		wrapped := fmt.Errorf("logging in: %w", err)
+
+		Expect(cli.Is(wrapped, cli.AuthenticationFailed)).To(BeTrue())
+		Expect(cli.Is(wrapped, cli.RouteTaken)).To(BeFalse())
+		Expect(cli.Is(errors.New("plain"), cli.Unknown)).To(BeFalse())
+	})
+})
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+const Redacted = "[REDACTED]"
+
+// shorter values, such as "true" or "1", are too common to mask
+const minSecretLength = 4
+
+// tokenPatterns match credentials in CF_TRACE output, whatever their value.
+// The first group of each is kept.
+var tokenPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(authorization:\s*(?:bearer|basic)\s+)\S+`),
+	regexp.MustCompile(`(?i)("(?:access_token|refresh_token|id_token|token|password|client_secret)"\s*:\s*")[^"]*`),
+	regexp.MustCompile(`(?i)(\b(?:access_token|refresh_token|password|client_secret|passcode)=)[^&\s"]+`),
+	regexp.MustCompile(`()eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+}
+
+// Redactor masks tokens and known secrets in everything written through it.
+// It works a line at a time, so that a secret split across writes is still
+// masked; Flush writes out a final partial line.
+type Redactor struct {
+	mu      sync.Mutex
+	w       io.Writer
+	secrets [][]byte
+	line    []byte
+}
+
+func NewRedactor(w io.Writer, secrets ...string) *Redactor {
+	redactor := &Redactor{w: w}
+	redactor.Add(secrets...)
+	return redactor
+}
+
+// Add adds secrets to mask from now on.
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, secret := range secrets {
+		if len(secret) >= minSecretLength {
+			r.secrets = append(r.secrets, []byte(secret))
+		}
+	}
+
+	// longest first, so a secret that contains another is masked whole
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+func (r *Redactor) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.line = append(r.line, p...)
+	end := bytes.LastIndexByte(r.line, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+
+	lines := r.line[:end+1]
+	r.line = append([]byte(nil), r.line[end+1:]...)
+
+	if _, err := r.w.Write(r.redact(lines)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes out whatever is left of the last line.
+func (r *Redactor) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.line) == 0 {
+		return nil
+	}
+	_, err := r.w.Write(r.redact(r.line))
+	r.line = nil
+	return err
+}
+
+// Redact masks tokens and known secrets in s.
+func (r *Redactor) Redact(s string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return string(r.redact([]byte(s)))
+}
+
+func (r *Redactor) redact(data []byte) []byte {
+	for _, secret := range r.secrets {
+		data = bytes.ReplaceAll(data, secret, []byte(Redacted))
+	}
+	for _, pattern := range tokenPatterns {
+		data = pattern.ReplaceAll(data, []byte("${1}"+Redacted))
+	}
+	return data
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Output is everything cf printed, in the order the streams were read.
+func (result Result) Output() string {
+	return result.Stdout + result.Stderr
+}
+
+//go:generate counterfeiter . Runner
+type Runner interface {
+	Run(ctx context.Context, args ...string) (Result, error)
+}
+
+type quietKey struct{}
+
+// Quietly returns a context under which commands are not echoed to the log,
+// for calls whose output is parsed rather than read by a person.
+func Quietly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, quietKey{}, true)
+}
+
+func isQuiet(ctx context.Context) bool {
+	quiet, _ := ctx.Value(quietKey{}).(bool)
+	return quiet
+}
+
+type envKey struct{}
+
+// WithEnv returns a context under which commands also get these environment
+// variables, for credentials that shouldn't be on the process list.
+func WithEnv(ctx context.Context, env ...string) context.Context {
+	return context.WithValue(ctx, envKey{}, append(contextEnv(ctx), env...))
+}
+
+func contextEnv(ctx context.Context) []string {
+	env, _ := ctx.Value(envKey{}).([]string)
+	return append([]string(nil), env...)
+}
+
+// Detached returns a context with ctx's values that isn't cancelled along
+// with it, only by its own timeout, for cleanup that has to run even after
+// the build is aborted.
+func Detached(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(detached{ctx}, timeout)
+}
+
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+type ExecRunner struct {
+	path string
+	env  []string
+	log  io.Writer
+}
+
+func NewRunner(verbose bool, log io.Writer) *ExecRunner {
+	env := []string{"CF_COLOR=true", "CF_DIAL_TIMEOUT=30"}
+	if verbose {
+		env = append(env, "CF_TRACE=true")
+	}
+
+	return &ExecRunner{
+		path: "cf",
+		env:  env,
+		log:  log,
+	}
+}
+
+func (runner *ExecRunner) Run(ctx context.Context, args ...string) (Result, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, runner.path, args...)
+	cmd.Env = append(append(os.Environ(), runner.env...), contextEnv(ctx)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if runner.log != nil && !isQuiet(ctx) {
+		cmd.Stdout = io.MultiWriter(runner.log, &stdout)
+		cmd.Stderr = io.MultiWriter(runner.log, &stderr)
+	}
+
+	err := cmd.Run()
+
+	result := Result{
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		result.ExitCode = -1
+	}
+
+	return result, Classify(args, result.Output(), err)
+}
@@ -0,0 +1,111 @@
+package cli_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+var _ = Describe("ExecRunner", func() {
+	var (
+		tmpDir  string
+		oldPath string
+		log     *gbytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		tmpDir, err = ioutil.TempDir("", "cf_resource_cli")
+		Expect(err).NotTo(HaveOccurred())
+
+		script := `#!/bin/bash
+echo "out: $*"
+echo "err: CF_COLOR=$CF_COLOR CF_TRACE=$CF_TRACE" >&2
+if [ -n "$CF_USERNAME" ]; then
+  echo "env: CF_USERNAME=$CF_USERNAME"
+fi
+if [ "$1" == "fail" ]; then
+  echo "Credentials were rejected, please try again."
+  exit 3
+fi
+`
+		err = ioutil.WriteFile(filepath.Join(tmpDir, "cf"), []byte(script), 0755)
+		Expect(err).NotTo(HaveOccurred())
+
+		oldPath = os.Getenv("PATH")
+		os.Setenv("PATH", fmt.Sprintf("%s:%s", tmpDir, oldPath))
+
+		log = gbytes.NewBuffer()
+	})
+
+	AfterEach(func() {
+		os.Setenv("PATH", oldPath)
+		os.RemoveAll(tmpDir)
+	})
+
+	It("captures stdout and stderr and tees them to the log", func() {
+		result, err := cli.NewRunner(false, log).Run(context.Background(), "apps")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(result.Stdout).To(Equal("out: apps\n"))
+		Expect(result.Stderr).To(Equal("err: CF_COLOR=true CF_TRACE=\n"))
+		Expect(result.ExitCode).To(Equal(0))
+		Expect(log).To(gbytes.Say("out: apps"))
+	})
+
+	It("traces when verbose", func() {
+		result, err := cli.NewRunner(true, log).Run(context.Background(), "apps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Stderr).To(ContainSubstring("CF_TRACE=true"))
+	})
+
+	It("doesn't write to the log when told to be quiet", func() {
+		result, err := cli.NewRunner(false, log).Run(cli.Quietly(context.Background()), "apps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Stdout).To(Equal("out: apps\n"))
+		Expect(log.Contents()).To(BeEmpty())
+	})
+
+	It("adds environment variables from the context", func() {
+		result, err := cli.NewRunner(false, log).Run(cli.WithEnv(context.Background(), "CF_USERNAME=admin"), "auth")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Stdout).To(Equal("out: auth\nenv: CF_USERNAME=admin\n"))
+	})
+
+	It("returns the exit code and a classified error on failure", func() {
+		result, err := cli.NewRunner(false, log).Run(context.Background(), "fail")
+		Expect(result.ExitCode).To(Equal(3))
+		Expect(cli.Is(err, cli.AuthenticationFailed)).To(BeTrue())
+	})
+
+	It("stops the command when the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := cli.NewRunner(false, log).Run(ctx, "apps")
+		Expect(err).To(HaveOccurred())
+		Expect(result.ExitCode).NotTo(Equal(0))
+	})
+
+	It("still runs commands under a context detached from a cancelled one", func() {
+		ctx, cancel := context.WithCancel(cli.WithEnv(context.Background(), "CF_USERNAME=admin"))
+		cancel()
+
+		detached, stop := cli.Detached(ctx, time.Minute)
+		defer stop()
+
+		result, err := cli.NewRunner(false, log).Run(detached, "apps")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Stdout).To(ContainSubstring("CF_USERNAME=admin"))
+	})
+})
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+var versionPattern = regexp.MustCompile(`version (\d+)\.(\d+)\.(\d+)`)
+
+// ParseVersion reads the output of `cf version`. Output it does not
+// recognise, such as a development build, gives the zero Version.
+func ParseVersion(output string) Version {
+	match := versionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return Version{}
+	}
+
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+
+	return Version{major, minor, patch}
+}
+
+func DetectVersion(ctx context.Context, cf Runner) (Version, error) {
+	result, err := cf.Run(Quietly(ctx), "version")
+	if err != nil {
+		return Version{}, fmt.Errorf("detecting cf CLI version: %s", err)
+	}
+
+	return ParseVersion(result.Output()), nil
+}
+
+// Unknown reports whether the version could not be detected, in which case
+// every feature is assumed to be available.
+func (version Version) Unknown() bool {
+	return version == Version{}
+}
+
+func (version Version) AtLeast(other Version) bool {
+	if version.Major != other.Major {
+		return version.Major > other.Major
+	}
+	if version.Minor != other.Minor {
+		return version.Minor > other.Minor
+	}
+	return version.Patch >= other.Patch
+}
+
+func (version Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+}
+
+type Feature struct {
+	Name       string
+	MinVersion Version
+}
+
+var (
+	RollingStrategy = Feature{"rolling deployments (strategy: rolling)", Version{7, 0, 0}}
+	TaskCommandFlag = Feature{"cf run-task --command", Version{7, 0, 0}}
+)
+
+func (version Version) Supports(feature Feature) bool {
+	return version.Unknown() || version.AtLeast(feature.MinVersion)
+}
+
+func (version Version) Check(feature Feature) error {
+	if version.Supports(feature) {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"cf CLI %s does not support %s; version %s or later is required",
+		version, feature.Name, feature.MinVersion,
+	)
+}
@@ -0,0 +1,62 @@
+package cli_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+)
+
+var _ = Describe("Version", func() {
+	It("parses the output of each major cf CLI", func() {
+		Expect(cli.ParseVersion("cf version 6.53.0+8e2b70a4a.2020-10-01\n")).To(Equal(cli.Version{6, 53, 0}))
+		Expect(cli.ParseVersion("cf version 7.2.0+be4a5ce2b.2020-12-10\n")).To(Equal(cli.Version{7, 2, 0}))
+		Expect(cli.ParseVersion("cf version 8.5.0+73aa161.2022-09-12\n")).To(Equal(cli.Version{8, 5, 0}))
+	})
+
+	It("treats unrecognised output as unknown", func() {
+		version := cli.ParseVersion("cf version BUILT_FROM_SOURCE")
+		Expect(version.Unknown()).To(BeTrue())
+		Expect(version.Supports(cli.RollingStrategy)).To(BeTrue())
+	})
+
+	It("compares versions", func() {
+		Expect(cli.Version{7, 0, 0}.AtLeast(cli.Version{7, 0, 0})).To(BeTrue())
+		Expect(cli.Version{8, 0, 0}.AtLeast(cli.Version{7, 9, 9})).To(BeTrue())
+		Expect(cli.Version{6, 53, 0}.AtLeast(cli.Version{7, 0, 0})).To(BeFalse())
+		Expect(cli.Version{7, 1, 0}.AtLeast(cli.Version{7, 1, 1})).To(BeFalse())
+	})
+
+	It("explains which version a feature needs", func() {
+		Expect(cli.Version{7, 2, 0}.Check(cli.RollingStrategy)).NotTo(HaveOccurred())
+		Expect(cli.Version{6, 53, 0}.Check(cli.RollingStrategy)).To(MatchError(
+			"cf CLI 6.53.0 does not support rolling deployments (strategy: rolling); version 7.0.0 or later is required",
+		))
+	})
+
+	Describe("DetectVersion", func() {
+		It("asks cf quietly", func() {
+			cf := &clifakes.FakeRunner{}
+			cf.RunReturns(cli.Result{Stdout: "cf version 8.5.0+73aa161.2022-09-12\n"}, nil)
+
+			version, err := cli.DetectVersion(context.Background(), cf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal(cli.Version{8, 5, 0}))
+
+			_, args := cf.RunArgsForCall(0)
+			Expect(args).To(Equal([]string{"version"}))
+		})
+
+		It("fails when cf can't be run", func() {
+			cf := &clifakes.FakeRunner{}
+			cf.RunReturns(cli.Result{}, errors.New("executable file not found in $PATH"))
+
+			_, err := cli.DetectVersion(context.Background(), cf)
+			Expect(err).To(MatchError("detecting cf CLI version: executable file not found in $PATH"))
+		})
+	})
+})
@@ -1,25 +1,81 @@
 package out
 
 import (
-	"fmt"
-	"github.com/concourse/cf-resource/out/zdt"
+	"context"
+	"errors"
 	"os"
-	"os/exec"
+	"time"
+
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/zdt"
 )
 
 //go:generate counterfeiter . PAAS
 type PAAS interface {
 	Login(api string, username string, password string, clientID string, clientSecret string, insecure bool) error
 	Target(organization string, space string) error
-	PushApp(manifest string, path string, currentAppName string, vars map[string]interface{}, varsFiles []string, dockerUser string, showLogs bool, noStart bool) error
+	PushApp(manifest string, path string, currentAppName string, dockerUser string, showLogs bool, noStart bool, strategy string, afterPush func() error) (PushResult, error)
+	Version() (cli.Version, error)
+	GetApp(name string) (App, error)
+	InstanceStates(name string) ([]string, error)
+	SpaceAnnotation(key string) (string, error)
+	SetSpaceAnnotation(key string, value string) error
+	SetAppMetadata(name string, labels map[string]string, annotations map[string]string) error
+	AppAnnotations(name string) (map[string]string, error)
+	RunTask(app string, task Task) (TaskRun, error)
+	TaskState(app string, id int) (TaskState, error)
+	TaskLogs(app string, name string) ([]string, error)
+	TerminateTask(app string, id int) error
+	ServiceInstance(name string) (ServiceInstance, error)
+	CreateService(service Service) error
+	UpdateService(service Service) error
+	MapRoute(app string, route Route) error
+	UnmapRoute(app string, route Route) error
+	RouteInOtherSpace(url string) (bool, error)
+	RestartApp(name string, rolling bool) error
+	RestageApp(name string, rolling bool) error
+	StartApp(name string) error
+	StopApp(name string) error
+	DeleteApp(name string) error
+	DeleteRoute(route Route) error
+	DeleteService(name string) error
+	SpaceApps() ([]SpaceApp, error)
+	UnmappedRoutes() ([]SpaceRoute, error)
+	Detached(timeout time.Duration) (PAAS, context.CancelFunc)
 }
 
 type CloudFoundry struct {
-	verbose bool
+	ctx     context.Context
+	runner  cli.Runner
+	version *cli.Version
+	space   string
+}
+
+func NewCloudFoundry(ctx context.Context, runner cli.Runner) *CloudFoundry {
+	return &CloudFoundry{ctx: ctx, runner: runner}
 }
 
-func NewCloudFoundry(verbose bool) *CloudFoundry {
-	return &CloudFoundry{verbose}
+// Detached returns a CloudFoundry whose commands still run once the build is
+// aborted, until the timeout, for cleanup that mustn't be skipped.
+func (cf *CloudFoundry) Detached(timeout time.Duration) (PAAS, context.CancelFunc) {
+	ctx, cancel := cli.Detached(cf.ctx, timeout)
+	detached := *cf
+	detached.ctx = ctx
+	return &detached, cancel
+}
+
+// Version returns the version of the installed cf CLI, detecting it on the
+// first call.
+func (cf *CloudFoundry) Version() (cli.Version, error) {
+	if cf.version == nil {
+		version, err := cli.DetectVersion(cf.ctx, cf.runner)
+		if err != nil {
+			return cli.Version{}, err
+		}
+		cf.version = &version
+	}
+
+	return *cf.version, nil
 }
 
 func (cf *CloudFoundry) Login(api string, username string, password string, clientID string, clientSecret string, insecure bool) error {
@@ -28,39 +84,82 @@ func (cf *CloudFoundry) Login(api string, username string, password string, clie
 		args = append(args, "--skip-ssl-validation")
 	}
 
-	err := cf.cf(args...).Run()
+	err := cf.run(args...)
 	if err != nil {
 		return err
 	}
 
+	// cf auth reads the credentials from the environment, which keeps them
+	// off the process list
 	if clientID != "" && clientSecret != "" {
-		return cf.cf("auth", "--client-credentials", clientID, clientSecret).Run()
+		_, err = cf.runner.Run(cli.WithEnv(cf.ctx, "CF_USERNAME="+clientID, "CF_PASSWORD="+clientSecret), "auth", "--client-credentials")
+		return err
 	}
-	return cf.cf("auth", username, password).Run()
+	_, err = cf.runner.Run(cli.WithEnv(cf.ctx, "CF_USERNAME="+username, "CF_PASSWORD="+password), "auth")
+	return err
 }
 
 func (cf *CloudFoundry) Target(organization string, space string) error {
-	return cf.cf("target", "-o", organization, "-s", space).Run()
+	if err := cf.run("target", "-o", organization, "-s", space); err != nil {
+		return err
+	}
+	cf.space = space
+	return nil
 }
 
 func (cf *CloudFoundry) PushApp(
 	manifest string,
 	path string,
 	currentAppName string,
-	vars map[string]interface{},
-	varsFiles []string,
 	dockerUser string,
 	showLogs bool,
 	noStart bool,
-) error {
+	strategy string,
+	afterPush func() error,
+) (PushResult, error) {
+
+	if afterPush == nil {
+		afterPush = func() error { return nil }
+	}
+
+	if strategy == StrategyRolling {
+		result := PushResult{Strategy: StrategyRolling}
 
-	if zdt.CanPush(cf.cf, currentAppName) {
-		pushFunction := func() error {
-			return cf.simplePush(manifest, path, currentAppName, vars, varsFiles, dockerUser, noStart)
+		version, err := cf.Version()
+		if err != nil {
+			return result, err
+		}
+		if err := version.Check(cli.RollingStrategy); err != nil {
+			return result, err
+		}
+
+		err = cf.simplePush(manifest, path, currentAppName, dockerUser, noStart, strategy)
+		if err != nil {
+			return result, err
+		}
+		return result, afterPush()
+	}
+
+	// afterPush is part of the push so that a zero downtime deploy rolls
+	// back when it fails
+	pushFunction := func() error {
+		err := cf.simplePush(manifest, path, currentAppName, dockerUser, noStart, "")
+		if err != nil {
+			return err
 		}
-		return zdt.Push(cf.cf, currentAppName, pushFunction, showLogs)
+		return afterPush()
+	}
+
+	if zdt.CanPush(cf.ctx, cf.runner, currentAppName) {
+		err := zdt.Push(cf.ctx, cf.runner, currentAppName, pushFunction, showLogs)
+
+		var rolledBack *zdt.RolledBackError
+		return PushResult{
+			Strategy:   StrategyZeroDowntime,
+			RolledBack: errors.As(err, &rolledBack),
+		}, err
 	} else {
-		return cf.simplePush(manifest, path, currentAppName, vars, varsFiles, dockerUser, noStart)
+		return PushResult{Strategy: StrategyPlain}, pushFunction()
 	}
 }
 
@@ -68,10 +167,9 @@ func (cf *CloudFoundry) simplePush(
 	manifest string,
 	path string,
 	currentAppName string,
-	vars map[string]interface{},
-	varsFiles []string,
 	dockerUser string,
 	noStart bool,
+	strategy string,
 ) error {
 
 	args := []string{"push"}
@@ -86,12 +184,8 @@ func (cf *CloudFoundry) simplePush(
 		args = append(args, "--no-start")
 	}
 
-	for name, value := range vars {
-		args = append(args, "--var", fmt.Sprintf("%s=%s", name, value))
-	}
-
-	for _, varsFile := range varsFiles {
-		args = append(args, "--vars-file", varsFile)
+	if strategy != "" {
+		args = append(args, "--strategy", strategy)
 	}
 
 	if dockerUser != "" {
@@ -105,14 +199,14 @@ func (cf *CloudFoundry) simplePush(
 		}
 		if stat.IsDir() {
 			args = append(args, "-p", ".")
-			return chdir(path, cf.cf(args...).Run)
+			return chdir(path, func() error { return cf.run(args...) })
 		}
 
 		// path is a zip file, add it to the args
 		args = append(args, "-p", path)
 	}
 
-	return cf.cf(args...).Run()
+	return cf.run(args...)
 }
 
 func chdir(path string, f func() error) error {
@@ -129,15 +223,11 @@ func chdir(path string, f func() error) error {
 	return f()
 }
 
-func (cf *CloudFoundry) cf(args ...string) *exec.Cmd {
-	cmd := exec.Command("cf", args...)
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
-	cmd.Env = append(os.Environ(), "CF_COLOR=true", "CF_DIAL_TIMEOUT=30")
-
-	if cf.verbose {
-		cmd.Env = append(cmd.Env, "CF_TRACE=true")
-	}
+func (cf *CloudFoundry) curl(path string, v interface{}, args ...string) error {
+	return cli.Curl(cf.ctx, cf.runner, path, v, args...)
+}
 
-	return cmd
+func (cf *CloudFoundry) run(args ...string) error {
+	_, err := cf.runner.Run(cf.ctx, args...)
+	return err
 }
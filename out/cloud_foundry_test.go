@@ -0,0 +1,130 @@
+package out_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+)
+
+func commands(runner *clifakes.FakeRunner) []string {
+	var commands []string
+	for i := 0; i < runner.RunCallCount(); i++ {
+		_, args := runner.RunArgsForCall(i)
+		commands = append(commands, "cf "+strings.Join(args, " "))
+	}
+	return commands
+}
+
+var _ = Describe("CloudFoundry", func() {
+	var (
+		runner       *clifakes.FakeRunner
+		cloudFoundry *out.CloudFoundry
+	)
+
+	BeforeEach(func() {
+		runner = &clifakes.FakeRunner{}
+		cloudFoundry = out.NewCloudFoundry(context.Background(), runner)
+	})
+
+	Describe("Login", func() {
+		ctx := func(i int) context.Context {
+			ctx, _ := runner.RunArgsForCall(i)
+			return ctx
+		}
+
+		It("passes the credentials to cf auth in its environment", func() {
+			Expect(cloudFoundry.Login("https://api.example.com", "admin", "hunter2", "", "", false)).To(BeNil())
+
+			Expect(commands(runner)).To(Equal([]string{"cf api https://api.example.com", "cf auth"}))
+			Expect(ctx(1)).To(Equal(cli.WithEnv(context.Background(), "CF_USERNAME=admin", "CF_PASSWORD=hunter2")))
+		})
+
+		It("authenticates with client credentials", func() {
+			Expect(cloudFoundry.Login("https://api.example.com", "", "", "my-client", "s3cret", true)).To(BeNil())
+
+			Expect(commands(runner)).To(Equal([]string{"cf api https://api.example.com --skip-ssl-validation", "cf auth --client-credentials"}))
+			Expect(ctx(1)).To(Equal(cli.WithEnv(context.Background(), "CF_USERNAME=my-client", "CF_PASSWORD=s3cret")))
+		})
+	})
+
+	Describe("PushApp", func() {
+		crashed := &cli.Error{Kind: cli.AppCrashed, Command: "push", Line: "instance 0 of my-app crashed"}
+
+		It("runs afterPush once the app is pushed", func() {
+			afterPushCalls := 0
+			afterPush := func() error {
+				afterPushCalls++
+				Expect(commands(runner)).To(ContainElement("cf push -f manifest.yml"))
+				return nil
+			}
+
+			result, err := cloudFoundry.PushApp("manifest.yml", "", "", "", false, false, "", afterPush)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(afterPushCalls).To(Equal(1))
+			Expect(result).To(Equal(out.PushResult{Strategy: "plain"}))
+		})
+
+		It("rolls a zero downtime deploy back when afterPush fails", func() {
+			result, err := cloudFoundry.PushApp("manifest.yml", "", "my-app", "", false, false, "", func() error {
+				return crashed
+			})
+			Expect(errors.Is(err, crashed)).To(BeTrue())
+			Expect(result).To(Equal(out.PushResult{Strategy: "zdt", RolledBack: true}))
+
+			Expect(commands(runner)).To(Equal([]string{
+				"cf app my-app",
+				"cf rename my-app my-app-venerable",
+				"cf push my-app -f manifest.yml",
+				"cf app my-app",
+				"cf delete -f my-app",
+				"cf rename my-app-venerable my-app",
+			}))
+		})
+
+		It("doesn't run afterPush when the push fails", func() {
+			runner.RunStub = func(ctx context.Context, args ...string) (cli.Result, error) {
+				if args[0] == "push" {
+					return cli.Result{ExitCode: 1}, errors.New("push failed")
+				}
+				return cli.Result{}, nil
+			}
+
+			_, err := cloudFoundry.PushApp("manifest.yml", "", "", "", false, false, "", func() error {
+				Fail("afterPush should not be called")
+				return nil
+			})
+			Expect(err).To(MatchError("push failed"))
+		})
+
+		It("pushes with the rolling strategy", func() {
+			result, err := cloudFoundry.PushApp("manifest.yml", "", "my-app", "", false, false, "rolling", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Strategy).To(Equal("rolling"))
+
+			Expect(commands(runner)).To(Equal([]string{
+				"cf version",
+				"cf push my-app -f manifest.yml --strategy rolling",
+			}))
+		})
+	})
+
+	Describe("InstanceStates", func() {
+		It("reads the state of each instance of the web process", func() {
+			fakeAPI(runner, map[string]string{
+				"guid:my-app":                           "app-guid",
+				"/v3/apps/app-guid/processes/web/stats": `{"resources":[{"index":0,"state":"RUNNING"},{"index":1,"state":"STARTING"}]}`,
+			})
+
+			states, err := cloudFoundry.InstanceStates("my-app")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(states).To(Equal([]string{"RUNNING", "STARTING"}))
+		})
+	})
+})
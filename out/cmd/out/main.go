@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
 )
 
+// log masks the request's secrets, once it has been read, in everything
+// written to stderr: cf's output, CF_TRACE included, and errors.
+var log = cli.NewRedactor(os.Stderr)
+
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Fprintf(os.Stderr, "usage: %s <sources directory>\n", os.Args[0])
@@ -16,26 +25,38 @@ func main() {
 	}
 
 	var request out.Request
-	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+	decoder := json.NewDecoder(os.Stdin)
+	// keep vars like 10000000000 exact instead of turning them into floats
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
 		fatal("reading request from stdin", err)
 	}
+	log.Add(request.Secrets()...)
 
-	cloudFoundry := out.NewCloudFoundry(request.Source.Verbose)
-	command := out.NewCommand(cloudFoundry)
+	// concourse sends SIGTERM when a build is aborted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// make it an absolute path
-	request.Params.ManifestPath = filepath.Join(os.Args[1], request.Params.ManifestPath)
+	runner := cli.NewRunner(request.Source.Verbose, log)
+	cloudFoundry := out.NewCloudFoundry(ctx, runner)
+	command := out.NewCommand(cloudFoundry, log)
 
-	manifestFiles, err := filepath.Glob(request.Params.ManifestPath)
-	if err != nil {
-		fatal("searching for manifest files", err)
-	}
+	// cleanup works on the whole space, so it has no manifest
+	if request.Params.Action != out.ActionCleanup {
+		// make it an absolute path
+		request.Params.ManifestPath = filepath.Join(os.Args[1], request.Params.ManifestPath)
 
-	if len(manifestFiles) != 1 {
-		fatal("invalid manifest path", fmt.Errorf("found %d files instead of 1 at path: %s", len(manifestFiles), request.Params.ManifestPath))
-	}
+		manifestFiles, err := filepath.Glob(request.Params.ManifestPath)
+		if err != nil {
+			fatal("searching for manifest files", err)
+		}
+
+		if len(manifestFiles) != 1 {
+			fatal("invalid manifest path", fmt.Errorf("found %d files instead of 1 at path: %s", len(manifestFiles), request.Params.ManifestPath))
+		}
 
-	request.Params.ManifestPath = manifestFiles[0]
+		request.Params.ManifestPath = manifestFiles[0]
+	}
 
 	if request.Params.Path != "" {
 		request.Params.Path = filepath.Join(os.Args[1], request.Params.Path)
@@ -51,6 +72,20 @@ func main() {
 		request.Params.Path = pathFiles[0]
 	}
 
+	if request.Source.Policy.File != "" {
+		request.Source.Policy.File = filepath.Join(os.Args[1], request.Source.Policy.File)
+	}
+
+	for _, paths := range [][]string{request.Params.AdditionalManifests, request.Params.OpsFiles, request.Params.VarsFiles} {
+		for i, path := range paths {
+			paths[i] = filepath.Join(os.Args[1], path)
+		}
+	}
+
+	// loading the manifest reports a vars file it can't read
+	varsSecrets, _ := request.Params.VarsFileSecrets()
+	log.Add(varsSecrets...)
+
 	response, err := command.Run(request)
 	if err != nil {
 		fatal("running command", err)
@@ -59,9 +94,17 @@ func main() {
 	if err := json.NewEncoder(os.Stdout).Encode(response); err != nil {
 		fatal("writing response to stdout", err)
 	}
+	log.Flush()
 }
 
 func fatal(message string, err error) {
-	fmt.Fprintf(os.Stderr, "error %s: %s\n", message, err)
+	fmt.Fprintf(log, "error %s: %s\n", message, err)
+
+	var cliErr *cli.Error
+	if errors.As(err, &cliErr) && cliErr.Hint() != "" {
+		fmt.Fprintf(log, "hint: %s\n", cliErr.Hint())
+	}
+	log.Flush()
+
 	os.Exit(1)
 }
@@ -1,68 +1,481 @@
 package out
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 
 	"os"
 
 	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out/cli"
 )
 
 const CfDockerPassword = "CF_DOCKER_PASSWORD"
 
 type Command struct {
 	paas PAAS
+	log  io.Writer
 }
 
-func NewCommand(paas PAAS) *Command {
+func NewCommand(paas PAAS, log io.Writer) *Command {
 	return &Command{
 		paas: paas,
+		log:  log,
 	}
 }
 
 func (command *Command) Run(request Request) (Response, error) {
-	err := command.paas.Login(
-		request.Source.API,
-		request.Source.Username,
-		request.Source.Password,
-		request.Source.ClientID,
-		request.Source.ClientSecret,
-		request.Source.SkipCertCheck,
-	)
+	phases := newPhases()
+
+	if err := checkParams(request.Params); err != nil {
+		return Response{}, err
+	}
+
+	version, err := command.paas.Version()
 	if err != nil {
 		return Response{}, err
 	}
 
-	err = command.paas.Target(
-		request.Source.Organization,
-		request.Source.Space,
-	)
+	if err := checkCompatibility(version, request.Params); err != nil {
+		return Response{}, err
+	}
+
+	runningTimeout, err := parseDuration(request.Params.RunningTimeout, DefaultRunningTimeout)
+	if err != nil {
+		return Response{}, fmt.Errorf("invalid running_timeout: %s", err)
+	}
+
+	stabilityWindow, err := parseDuration(request.Params.StabilityWindow, DefaultStabilityWindow)
+	if err != nil {
+		return Response{}, fmt.Errorf("invalid stability_window: %s", err)
+	}
+
+	lockTimeout, err := parseDuration(request.Params.LockTimeout, DefaultLockTimeout)
+	if err != nil {
+		return Response{}, fmt.Errorf("invalid lock_timeout: %s", err)
+	}
+
+	lockTTL, err := parseDuration(request.Params.LockTTL, DefaultLockTTL)
+	if err != nil {
+		return Response{}, fmt.Errorf("invalid lock_ttl: %s", err)
+	}
+
+	if err := checkMetadata(request.Params); err != nil {
+		return Response{}, err
+	}
+
+	// the review app's name is a template, resolved before anything uses it
+	request.Params, err = resolveReview(request.Params)
 	if err != nil {
 		return Response{}, err
 	}
 
-	if err := command.setEnvironmentVariables(request); err != nil {
+	// cleanup works on the whole space, not on the manifest's apps
+	if request.Params.Action == ActionCleanup {
+		return command.cleanup(request, phases)
+	}
+
+	// a bad manifest fails here, before anything is changed
+	manifest, err := command.loadManifest(request.Params)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// the policy limits what is pushed, so it can't stop an app being
+	// stopped or deleted
+	if request.Params.pushes() {
+		policy, err := loadPolicy(request.Source.Policy)
+		if err != nil {
+			return Response{}, err
+		}
+
+		if err := checkPolicy(policy, request, manifest); err != nil {
+			return Response{}, err
+		}
+	}
+
+	var apps []string
+	for _, app := range pushedApps(request.Params, manifest) {
+		apps = append(apps, app.Name)
+	}
+
+	if err := checkTasks(request.Params.Tasks, apps); err != nil {
+		return Response{}, err
+	}
+
+	if err := checkServices(request.Params.Services); err != nil {
 		return Response{}, err
 	}
 
+	if err := checkRoutes(request.Params.Routes, apps); err != nil {
+		return Response{}, err
+	}
+
+	frozenBy, err := command.checkFreeze(request)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if err := command.logIn(request, phases); err != nil {
+		return Response{}, err
+	}
+
+	if request.Params.pushes() {
+		if err := command.checkRouteOwnership(pushedApps(request.Params, manifest), request.Params.Routes); err != nil {
+			return Response{}, err
+		}
+	}
+
+	if request.Params.DryRun {
+		return command.dryRun(request, manifest)
+	}
+
+	if request.Params.Lock != "" {
+		// the deferred release also covers a failed or rolled back deploy
+		var release func()
+		err = phases.time("lock", func() error {
+			var err error
+			release, err = command.lock(apps, request.Params.Lock, lockTimeout, lockTTL)
+			return err
+		})
+		defer release()
+		if err != nil {
+			return Response{}, err
+		}
+	}
+
+	instanceCounts := map[string]*InstanceCount{}
+	waitForApps := request.Params.WaitForRunning && !request.Params.NoStart
+	wait := func() error {
+		return phases.time("wait_for_running", func() error {
+			counts, err := waitForRunning(command.paas, apps, runningTimeout, stabilityWindow, command.log)
+			for i := range counts {
+				instanceCounts[counts[i].App] = &counts[i]
+			}
+			return err
+		})
+	}
+
+	if action := request.Params.Action; !request.Params.pushes() {
+		err = phases.time(action, func() error {
+			return command.runAction(request.Params, apps, version)
+		})
+		if err == nil && waitForApps && startsApps(action) {
+			err = wait()
+		}
+		if err != nil {
+			fmt.Fprintf(command.log, "Durations: %s\n", phases)
+			return Response{}, err
+		}
+
+		response := newResponse(request)
+		response.Metadata = append(response.Metadata, command.actionMetadata(action, apps, instanceCounts)...)
+		response.Metadata = append(response.Metadata, reviewMetadata(request.Params.Review)...)
+		response.Metadata = append(response.Metadata, resource.MetadataPair{Name: "durations", Value: phases.String()})
+		response.Metadata = append(response.Metadata, freezeMetadata(request, frozenBy)...)
+		return response, nil
+	}
+
+	fingerprint, err := manifest.Fingerprint(request.Params.ManifestPath, request.Params)
+	if err != nil {
+		return Response{}, fmt.Errorf("fingerprinting the apps: %s", err)
+	}
+
+	if fingerprint == "" {
+		fmt.Fprintln(command.log, "The app bits can't be fingerprinted without a path or a docker image digest, so the push isn't skipped.")
+	} else if !request.Params.Force {
+		if deployedAt := command.unchangedSince(apps, fingerprint); !deployedAt.IsZero() {
+			return command.skip(request, apps, deployedAt)
+		}
+	}
+
+	// the manifest's apps may bind to them, so they have to exist first
+	if len(request.Params.Services) > 0 {
+		err = phases.time("services", func() error {
+			return command.provisionServices(request.Params.Services)
+		})
+		if err != nil {
+			fmt.Fprintf(command.log, "Durations: %s\n", phases)
+			return Response{}, err
+		}
+	}
+
+	// push a copy so the user's manifest is never modified
+	manifestPath, err := manifest.SaveTemp(request.Params.ManifestPath)
+	if err != nil {
+		return Response{}, err
+	}
+	defer os.Remove(manifestPath)
+
 	if request.Params.DockerPassword != "" {
 		os.Setenv(CfDockerPassword, request.Params.DockerPassword)
 	}
 
-	err = command.paas.PushApp(
-		request.Params.ManifestPath,
-		request.Params.Path,
-		request.Params.CurrentAppName,
-		request.Params.Vars,
-		request.Params.VarsFiles,
-		request.Params.DockerUsername,
-		request.Params.ShowAppLog,
-		request.Params.NoStart,
-	)
+	var afterPush func() error
+
+	beforeSwitch := request.Params.Tasks.BeforeSwitch
+	if waitForApps || len(beforeSwitch) > 0 {
+		// part of the push, so a zero downtime deploy rolls back when the
+		// apps don't come up or a before_switch task fails
+		afterPush = func() error {
+			if waitForApps {
+				if err := wait(); err != nil {
+					return err
+				}
+			}
+			if len(beforeSwitch) == 0 {
+				return nil
+			}
+			return phases.time("before_switch", func() error {
+				return command.runTasks(beforeSwitch, apps)
+			})
+		}
+	}
+
+	var result PushResult
+	err = phases.time("push", func() error {
+		var err error
+		result, err = command.paas.PushApp(
+			manifestPath,
+			request.Params.Path,
+			request.Params.CurrentAppName,
+			request.Params.DockerUsername,
+			request.Params.ShowAppLog,
+			request.Params.NoStart,
+			request.Params.Strategy,
+			afterPush,
+		)
+		return err
+	})
 	if err != nil {
+		if result.RolledBack {
+			fmt.Fprintf(command.log, "The %s deploy failed and was rolled back; %s is still running the previous version.\n", result.Strategy, request.Params.CurrentAppName)
+		}
+		fmt.Fprintf(command.log, "Durations: %s\n", phases)
 		return Response{}, err
 	}
 
+	if routes := request.Params.Routes; len(routes.Map) > 0 || len(routes.Unmap) > 0 {
+		err = phases.time("routes", func() error {
+			return command.applyRoutes(routes, apps)
+		})
+		if err != nil {
+			fmt.Fprintf(command.log, "Durations: %s\n", phases)
+			return Response{}, err
+		}
+	}
+
+	if afterPushTasks := request.Params.Tasks.AfterPush; len(afterPushTasks) > 0 {
+		// the apps are deployed by now, so nothing is rolled back; the put
+		// fails, and since the apps aren't stamped the next put pushes again
+		err = phases.time("after_push", func() error {
+			return command.runTasks(afterPushTasks, apps)
+		})
+		if err != nil {
+			fmt.Fprintf(command.log, "Durations: %s\n", phases)
+			return Response{}, err
+		}
+	}
+
+	response := newResponse(request)
+
+	// by name, which after a zero downtime deploy is the new app
+	labels, annotations := appMetadataFor(currentBuild(), request.Params)
+	annotations[fingerprintKey] = fingerprint
+	annotations[deployedAtKey] = response.Version.Timestamp.Format(time.RFC3339Nano)
+	command.stampApps(apps, labels, annotations)
+
+	for _, name := range apps {
+		app, err := command.paas.GetApp(name)
+		if err != nil {
+			// the deploy itself worked, so don't fail the put over metadata
+			fmt.Fprintf(command.log, "warning: could not read %s for the metadata: %s\n", name, err)
+			continue
+		}
+		response.Metadata = append(response.Metadata, appMetadata(app, instanceCounts[name])...)
+	}
+	response.Metadata = append(response.Metadata, reviewMetadata(request.Params.Review)...)
+
+	response.Metadata = append(response.Metadata,
+		resource.MetadataPair{Name: "strategy", Value: result.Strategy},
+		resource.MetadataPair{Name: "rolled_back", Value: strconv.FormatBool(result.RolledBack)},
+		resource.MetadataPair{Name: "durations", Value: phases.String()},
+	)
+
+	response.Metadata = append(response.Metadata, freezeMetadata(request, frozenBy)...)
+
+	return response, nil
+}
+
+// freezeMetadata records the freeze window the put overrode, if any.
+func freezeMetadata(request Request, frozenBy *resource.FreezeWindow) []resource.MetadataPair {
+	if frozenBy == nil {
+		return nil
+	}
+	return []resource.MetadataPair{
+		{Name: "freeze_override", Value: freezeName(frozenBy)},
+		{Name: "freeze_override_reason", Value: request.Params.OverrideFreezeReason},
+	}
+}
+
+// logIn logs in and targets the space from the source.
+func (command *Command) logIn(request Request, phases *phases) error {
+	err := phases.time("login", func() error {
+		return command.paas.Login(
+			request.Source.API,
+			request.Source.Username,
+			request.Source.Password,
+			request.Source.ClientID,
+			request.Source.ClientSecret,
+			request.Source.SkipCertCheck,
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	return phases.time("target", func() error {
+		return command.paas.Target(
+			request.Source.Organization,
+			request.Source.Space,
+		)
+	})
+}
+
+// checkFreeze fails the put inside a freeze window, unless override_freeze
+// is set with a reason. It returns the window that was overridden, if any.
+// A dry run changes nothing, so it is never frozen.
+func (command *Command) checkFreeze(request Request) (*resource.FreezeWindow, error) {
+	if request.Params.DryRun {
+		return nil, nil
+	}
+
+	if request.Params.OverrideFreeze && request.Params.OverrideFreezeReason == "" {
+		return nil, errors.New("override_freeze needs an override_freeze_reason")
+	}
+
+	window, err := ActiveFreezeWindow(request.Source.FreezeWindows, time.Now())
+	if err != nil || window == nil {
+		return nil, err
+	}
+
+	if !request.Params.OverrideFreeze {
+		return nil, fmt.Errorf("deploys are frozen by freeze window %s; set override_freeze and override_freeze_reason to deploy anyway", freezeName(window))
+	}
+
+	fmt.Fprintf(command.log, "Overriding freeze window %s: %s\n", freezeName(window), request.Params.OverrideFreezeReason)
+	return window, nil
+}
+
+func freezeName(window *resource.FreezeWindow) string {
+	if window.Name == "" {
+		return "(unnamed)"
+	}
+	return window.Name
+}
+
+// loadManifest reads the manifest, merges additional_manifests over it,
+// applies ops_files, adds environment_variables, interpolates vars and
+// vars_files into it, scales it and turns it into a review app. Only the
+// result is pushed, so cf push never sees a variable. A push also lints and
+// validates it.
+func (command *Command) loadManifest(params Params) (Manifest, error) {
+	manifest, err := NewManifest(params.ManifestPath)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	for _, additionalManifest := range params.AdditionalManifests {
+		if err := manifest.Merge(additionalManifest); err != nil {
+			return Manifest{}, err
+		}
+	}
+
+	for _, opsFile := range params.OpsFiles {
+		ops, err := ReadOps(opsFile)
+		if err != nil {
+			return Manifest{}, err
+		}
+		if err := manifest.ApplyOps(ops); err != nil {
+			return Manifest{}, fmt.Errorf("%s: %s", opsFile, err)
+		}
+	}
+
+	if err := params.EnvironmentVariables.addTo(&manifest); err != nil {
+		return Manifest{}, err
+	}
+
+	if err := manifest.Interpolate(params.Vars, params.VarsFiles); err != nil {
+		return Manifest{}, err
+	}
+
+	if err := params.Scale.addTo(&manifest, params.CurrentAppName); err != nil {
+		return Manifest{}, err
+	}
+
+	if err := params.Review.addTo(&manifest); err != nil {
+		return Manifest{}, err
+	}
+
+	// a lifecycle action only reads the app names
+	if !params.pushes() {
+		return manifest, nil
+	}
+
+	if err := command.lint(params, &manifest); err != nil {
+		return Manifest{}, err
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return Manifest{}, err
+	}
+	for _, ignored := range manifest.IgnoredKeys() {
+		fmt.Fprintf(command.log, "warning: %s\n", ignored)
+	}
+
+	return manifest, nil
+}
+
+// lint reports the manifest's lint issues as warnings, or fails on them
+// with lint: error. With lint: fix the deprecated attributes are rewritten
+// first and only what is left is reported.
+func (command *Command) lint(params Params, manifest *Manifest) error {
+	zeroDowntime := params.CurrentAppName != "" && params.Strategy != StrategyRolling
+
+	if params.Lint == LintFix {
+		manifest.FixDeprecated()
+	}
+
+	issues := manifest.Lint(zeroDowntime)
+	if len(issues) == 0 {
+		return nil
+	}
+
+	if params.Lint == LintError {
+		return fmt.Errorf("manifest lint failed:\n  %s", lintReport(issues))
+	}
+
+	for _, issue := range issues {
+		if issue.Fixable {
+			fmt.Fprintf(command.log, "warning: %s (lint: fix rewrites this)\n", issue)
+		} else {
+			fmt.Fprintf(command.log, "warning: %s\n", issue)
+		}
+	}
+	return nil
+}
+
+func parseDuration(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func newResponse(request Request) Response {
 	return Response{
 		Version: resource.Version{
 			Timestamp: time.Now(),
@@ -77,27 +490,81 @@ func (command *Command) Run(request Request) (Response, error) {
 				Value: request.Source.Space,
 			},
 		},
-	}, nil
+	}
 }
 
-func (command *Command) setEnvironmentVariables(request Request) error {
-	if len(request.Params.EnvironmentVariables) == 0 {
-		return nil
+// dryRun prints what the put would do without changing anything.
+func (command *Command) dryRun(request Request, manifest Manifest) (Response, error) {
+	plan, err := command.plan(request.Params, manifest)
+	if err != nil {
+		return Response{}, err
 	}
 
-	manifest, err := NewManifest(request.Params.ManifestPath)
-	if err != nil {
+	plan.Write(command.log)
+
+	response := newResponse(request)
+	response.Metadata = append(response.Metadata, resource.MetadataPair{
+		Name:  "dry_run",
+		Value: "true",
+	})
+
+	return response, nil
+}
+
+// skip reports the apps as they are, when they were last deployed with the
+// same fingerprint, under that deploy's version.
+func (command *Command) skip(request Request, apps []string, deployedAt time.Time) (Response, error) {
+	fmt.Fprintf(command.log, "Nothing has changed since the deploy at %s, skipping the push; set force to push anyway.\n", deployedAt.Format(time.RFC3339))
+
+	response := newResponse(request)
+	response.Version.Timestamp = deployedAt
+
+	for _, name := range apps {
+		app, err := command.paas.GetApp(name)
+		if err != nil {
+			fmt.Fprintf(command.log, "warning: could not read %s for the metadata: %s\n", name, err)
+			continue
+		}
+		response.Metadata = append(response.Metadata, appMetadata(app, nil)...)
+	}
+	response.Metadata = append(response.Metadata, reviewMetadata(request.Params.Review)...)
+
+	response.Metadata = append(response.Metadata, resource.MetadataPair{Name: "skipped", Value: "true"})
+	return response, nil
+}
+
+// checkParams fails on param values that aren't known, before anything
+// runs.
+func checkParams(params Params) error {
+	if err := checkAction(params); err != nil {
 		return err
 	}
 
-	for key, value := range request.Params.EnvironmentVariables {
-		manifest.AddEnvironmentVariable(key, value)
+	switch params.Lint {
+	case "", LintWarn, LintError, LintFix:
+	default:
+		return fmt.Errorf("unknown lint %q, expected %q, %q or %q", params.Lint, LintWarn, LintError, LintFix)
 	}
 
-	err = manifest.Save(request.Params.ManifestPath)
-	if err != nil {
-		return err
+	switch params.Lock {
+	case "", LockWait, LockFail:
+	default:
+		return fmt.Errorf("unknown lock %q, expected %q or %q", params.Lock, LockWait, LockFail)
 	}
 
+	switch params.Strategy {
+	case "", StrategyRolling:
+		return nil
+	default:
+		return fmt.Errorf("unknown strategy %q, expected %q", params.Strategy, StrategyRolling)
+	}
+}
+
+// checkCompatibility fails early when the params ask for something the
+// installed cf CLI can't do, rather than partway through a deploy.
+func checkCompatibility(version cli.Version, params Params) error {
+	if params.Strategy == StrategyRolling {
+		return version.Check(cli.RollingStrategy)
+	}
 	return nil
 }
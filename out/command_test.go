@@ -1,18 +1,20 @@
 package out_test
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 
-	"io"
 	"io/ioutil"
 
 	"github.com/concourse/cf-resource"
 	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
 	"github.com/concourse/cf-resource/out/outfakes"
 )
 
@@ -21,26 +23,17 @@ var _ = Describe("Out Command", func() {
 		cloudFoundry *outfakes.FakePAAS
 		request      out.Request
 		command      *out.Command
+		log          *gbytes.Buffer
 	)
 
 	BeforeEach(func() {
 		cloudFoundry = &outfakes.FakePAAS{}
-		command = out.NewCommand(cloudFoundry)
-
-		request = out.Request{
-			Source: resource.Source{
-				API:          "https://api.run.pivotal.io",
-				Username:     "awesome@example.com",
-				Password:     "hunter2",
-				Organization: "secret",
-				Space:        "volcano-base",
-			},
-			Params: out.Params{
-				ManifestPath: "assets/manifest.yml",
-				Vars:         map[string]interface{}{"foo": "bar"},
-				VarsFiles:    []string{"vars.yml"},
-			},
-		}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+
+		request = newRequest()
+		request.Params.Vars = map[string]interface{}{"foo": "bar"}
+		request.Params.VarsFiles = []string{"assets/vars.yml"}
 	})
 
 	Describe("running the command", func() {
@@ -83,15 +76,14 @@ var _ = Describe("Out Command", func() {
 			By("pushing the app")
 			Expect(cloudFoundry.PushAppCallCount()).To(Equal(1))
 
-			manifest, path, currentAppName, vars, varsFiles, dockerUser, showAppLog, noStart := cloudFoundry.PushAppArgsForCall(0)
-			Expect(manifest).To(Equal(request.Params.ManifestPath))
+			manifest, path, currentAppName, dockerUser, showAppLog, noStart, strategy, _ := cloudFoundry.PushAppArgsForCall(0)
+			Expect(manifest).NotTo(Equal(request.Params.ManifestPath))
 			Expect(path).To(Equal(""))
 			Expect(currentAppName).To(Equal(""))
-			Expect(vars).To(Equal(map[string]interface{}{"foo": "bar"}))
-			Expect(varsFiles).To(Equal([]string{"vars.yml"}))
 			Expect(dockerUser).To(Equal(""))
 			Expect(showAppLog).To(Equal(false))
 			Expect(noStart).To(Equal(false))
+			Expect(strategy).To(Equal(""))
 		})
 
 		Describe("handling any errors", func() {
@@ -115,8 +107,32 @@ var _ = Describe("Out Command", func() {
 				Expect(err).To(MatchError(expectedError))
 			})
 
+			It("from detecting the cf CLI version", func() {
+				cloudFoundry.VersionReturns(cli.Version{}, expectedError)
+
+				_, err := command.Run(request)
+				Expect(err).To(MatchError(expectedError))
+				Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+			})
+
+			It("from reading the manifest, before logging in", func() {
+				request.Params.ManifestPath = "assets/invalidManifest.yml"
+
+				_, err := command.Run(request)
+				Expect(err).To(HaveOccurred())
+				Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+			})
+
+			It("from validating the manifest, before logging in", func() {
+				request.Params.ManifestPath = "assets/badSchemaManifest.yml"
+
+				_, err := command.Run(request)
+				Expect(err).To(BeAssignableToTypeOf(&out.ManifestError{}))
+				Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+			})
+
 			It("from pushing the application", func() {
-				cloudFoundry.PushAppReturns(expectedError)
+				cloudFoundry.PushAppReturns(out.PushResult{}, expectedError)
 
 				_, err := command.Run(request)
 				Expect(err).To(MatchError(expectedError))
@@ -133,61 +149,242 @@ var _ = Describe("Out Command", func() {
 					_, err := command.Run(request)
 					Expect(err).NotTo(HaveOccurred())
 
-					_, _, _, _, _, _, _, noStart := cloudFoundry.PushAppArgsForCall(0)
+					_, _, _, _, _, noStart, _, _ := cloudFoundry.PushAppArgsForCall(0)
 					Expect(noStart).To(Equal(true))
 				})
 			})
 		})
 
+		Describe("strategy handling", func() {
+			Context("when a rolling deployment is requested", func() {
+				BeforeEach(func() {
+					request.Params.Strategy = "rolling"
+				})
+
+				It("pushes with the rolling strategy on a cf CLI that supports it", func() {
+					cloudFoundry.VersionReturns(cli.Version{Major: 7, Minor: 2}, nil)
+
+					_, err := command.Run(request)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, _, _, _, _, _, strategy, _ := cloudFoundry.PushAppArgsForCall(0)
+					Expect(strategy).To(Equal("rolling"))
+				})
+
+				It("fails before logging in on a cf CLI that doesn't", func() {
+					cloudFoundry.VersionReturns(cli.Version{Major: 6, Minor: 53}, nil)
+
+					_, err := command.Run(request)
+					Expect(err).To(MatchError("cf CLI 6.53.0 does not support rolling deployments (strategy: rolling); version 7.0.0 or later is required"))
+					Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+					Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+				})
+			})
+
+			It("rejects unknown strategies", func() {
+				request.Params.Strategy = "canary"
+
+				_, err := command.Run(request)
+				Expect(err).To(MatchError(`unknown strategy "canary", expected "rolling"`))
+				Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+			})
+		})
+
 		Context("setting environment variables provided as params", func() {
-			var err error
-			var tempFile *os.File
+			var (
+				err      error
+				original []byte
+				pushed   out.Manifest
+			)
 
 			BeforeEach(func() {
-				sourceFile, err := os.Open("assets/manifest.yml")
+				original, err = ioutil.ReadFile("assets/manifest.yml")
+				Expect(err).NotTo(HaveOccurred())
+
+				cloudFoundry.PushAppStub = func(manifest string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+					pushed, err = out.NewManifest(manifest)
+					return out.PushResult{}, err
+				}
+
+				request.Params.EnvironmentVariables = out.EnvironmentVariables{
+					Global: map[string]interface{}{
+						"COMMAND_TEST_A": "command_test_a",
+						"COMMAND_TEST_B": "command_test_b",
+					},
+				}
+				_, err = command.Run(request)
+			})
+
+			It("does not raise an error", func() {
 				Expect(err).NotTo(HaveOccurred())
-				defer sourceFile.Close()
+			})
+
+			It("pushes a manifest with the variables added", func() {
+				Expect(pushed.EnvironmentVariables()[0]["COMMAND_TEST_A"]).To(Equal("command_test_a"))
+				Expect(pushed.EnvironmentVariables()[0]["COMMAND_TEST_B"]).To(Equal("command_test_b"))
+				Expect(pushed.EnvironmentVariables()[1]["COMMAND_TEST_A"]).To(Equal("command_test_a"))
+				Expect(pushed.EnvironmentVariables()[1]["COMMAND_TEST_B"]).To(Equal("command_test_b"))
+			})
 
-				tempFile, err = ioutil.TempFile("assets", "command_test.yml_")
+			It("leaves the original manifest untouched", func() {
+				contents, err := ioutil.ReadFile("assets/manifest.yml")
 				Expect(err).NotTo(HaveOccurred())
-				defer tempFile.Close()
+				Expect(contents).To(Equal(original))
+			})
+
+			It("removes the pushed copy afterwards", func() {
+				manifest, _, _, _, _, _, _, _ := cloudFoundry.PushAppArgsForCall(0)
+				_, err := os.Stat(manifest)
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
 
-				_, err = io.Copy(tempFile, sourceFile)
+		Context("setting environment variables for one app", func() {
+			var pushed out.Manifest
 
-				request = out.Request{
-					Source: resource.Source{
-						API:          "https://api.run.pivotal.io",
-						Username:     "awesome@example.com",
-						Password:     "hunter2",
-						Organization: "secret",
-						Space:        "volcano-base",
-					},
-					Params: out.Params{
-						ManifestPath: tempFile.Name(),
-						EnvironmentVariables: map[string]string{
-							"COMMAND_TEST_A": "command_test_a",
-							"COMMAND_TEST_B": "command_test_b",
-						},
+			BeforeEach(func() {
+				cloudFoundry.PushAppStub = func(manifest string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+					var err error
+					pushed, err = out.NewManifest(manifest)
+					return out.PushResult{}, err
+				}
+
+				request.Params.EnvironmentVariables = out.EnvironmentVariables{
+					Global: map[string]interface{}{"COMMAND_TEST_A": "command_test_a"},
+					Apps: map[string]map[string]interface{}{
+						"app2": {"COMMAND_TEST_A": "app2_a", "COMMAND_TEST_B": "app2_b"},
 					},
 				}
+			})
+
+			It("only sets them on that app, overriding global ones", func() {
+				_, err := command.Run(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(pushed.EnvironmentVariables()[0]).To(HaveKeyWithValue("COMMAND_TEST_A", "command_test_a"))
+				Expect(pushed.EnvironmentVariables()[0]).NotTo(HaveKey("COMMAND_TEST_B"))
+				Expect(pushed.EnvironmentVariables()[1]).To(Equal(map[string]interface{}{
+					"COMMAND_TEST_A": "app2_a",
+					"COMMAND_TEST_B": "app2_b",
+				}))
+			})
+
+			It("fails before pushing when the app isn't in the manifest", func() {
+				request.Params.EnvironmentVariables.Apps["worker"] = map[string]interface{}{"QUEUE": "jobs"}
+
+				_, err := command.Run(request)
+				Expect(err).To(MatchError("environment_variables: no app named worker in the manifest"))
+				Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("setting typed environment variables", func() {
+			It("sets strings as they are and anything else as JSON", func() {
+				var pushed out.Manifest
+				cloudFoundry.PushAppStub = func(manifest string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+					var err error
+					pushed, err = out.NewManifest(manifest)
+					return out.PushResult{}, err
+				}
+
+				err := json.Unmarshal([]byte(`{
+					"STRING": "3",
+					"INT": 3,
+					"BIG_INT": 10000000000,
+					"BOOL": true,
+					"LIST": ["a", 1],
+					"app1": {"OBJECT": {"nested": {"key": "value"}}}
+				}`), &request.Params.EnvironmentVariables)
+				Expect(err).NotTo(HaveOccurred())
+
 				_, err = command.Run(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				env := pushed.EnvironmentVariables()[0]
+				Expect(env).To(HaveKeyWithValue("STRING", "3"))
+				Expect(env).To(HaveKeyWithValue("INT", "3"))
+				Expect(env).To(HaveKeyWithValue("BIG_INT", "10000000000"))
+				Expect(env).To(HaveKeyWithValue("BOOL", "true"))
+				Expect(env).To(HaveKeyWithValue("LIST", `["a",1]`))
+				Expect(env).To(HaveKeyWithValue("OBJECT", `{"nested":{"key":"value"}}`))
+				Expect(pushed.EnvironmentVariables()[1]).NotTo(HaveKey("OBJECT"))
 			})
+		})
 
-			AfterEach(func() {
-				os.Remove(tempFile.Name())
+		Context("with additional manifests and ops files", func() {
+			It("pushes the merged manifest with the ops applied", func() {
+				var pushed out.Manifest
+				cloudFoundry.PushAppStub = func(manifest string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+					var err error
+					pushed, err = out.NewManifest(manifest)
+					return out.PushResult{}, err
+				}
+
+				request.Params.AdditionalManifests = []string{"assets/overlayManifest.yml"}
+				request.Params.OpsFiles = []string{"assets/ops.yml"}
+
+				_, err := command.Run(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				apps := pushed.Applications()
+				Expect(apps).To(HaveLen(2))
+				Expect(apps[0].Name).To(Equal("app1"))
+				Expect(apps[0].Memory).To(Equal("2G"))
+				Expect(*apps[0].Instances).To(Equal(4))
+				Expect(apps[1].Name).To(Equal("app3"))
 			})
 
-			It("does not raise an error", func() {
+			It("names the ops file that failed", func() {
+				request.Params.OpsFiles = []string{"assets/ops.yml", "assets/ops.yml"}
+
+				_, err := command.Run(request)
+				Expect(err).To(MatchError("assets/ops.yml: ops /applications/name=app1/env/MANIFEST_B: MANIFEST_B not found"))
+				Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+			})
+		})
+
+		Describe("lint handling", func() {
+			BeforeEach(func() {
+				request.Params.ManifestPath = "assets/deprecatedManifest.yml"
+			})
+
+			It("warns about lint issues by default", func() {
+				_, err := command.Run(request)
 				Expect(err).NotTo(HaveOccurred())
+
+				Expect(log).To(gbytes.Say(`warning: line 6: applications\[0\].host is deprecated, use routes \(lint: fix rewrites this\)`))
 			})
 
-			It("writes the variables into the manifest", func() {
-				manifest, _ := out.NewManifest(request.Params.ManifestPath)
+			It("fails before logging in with lint: error", func() {
+				request.Params.Lint = "error"
 
-				Expect(manifest.EnvironmentVariables()[0]["COMMAND_TEST_A"]).To(Equal("command_test_a"))
-				Expect(manifest.EnvironmentVariables()[0]["COMMAND_TEST_B"]).To(Equal("command_test_b"))
-				Expect(manifest.EnvironmentVariables()[1]["COMMAND_TEST_A"]).To(Equal("command_test_a"))
-				Expect(manifest.EnvironmentVariables()[1]["COMMAND_TEST_B"]).To(Equal("command_test_b"))
+				_, err := command.Run(request)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("manifest lint failed:\n  line 1: inherit is not supported"))
+				Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+			})
+
+			It("pushes the rewritten manifest with lint: fix", func() {
+				var pushed out.Manifest
+				cloudFoundry.PushAppStub = func(manifest string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+					var err error
+					pushed, err = out.NewManifest(manifest)
+					return out.PushResult{}, err
+				}
+				request.Params.Lint = "fix"
+
+				_, err := command.Run(request)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(pushed.Applications()[0].Routes).To(Equal([]string{"web.example.com", "web.example.org"}))
+				Expect(string(log.Contents())).NotTo(ContainSubstring("applications[0].host"))
+			})
+
+			It("rejects unknown lint modes", func() {
+				request.Params.Lint = "strict"
+
+				_, err := command.Run(request)
+				Expect(err).To(MatchError(`unknown lint "strict", expected "warn", "error" or "fix"`))
 			})
 		})
 
@@ -204,19 +401,8 @@ var _ = Describe("Out Command", func() {
 		})
 
 		It("lets people skip the certificate check", func() {
-			request = out.Request{
-				Source: resource.Source{
-					API:           "https://api.run.pivotal.io",
-					Username:      "awesome@example.com",
-					Password:      "hunter2",
-					Organization:  "secret",
-					Space:         "volcano-base",
-					SkipCertCheck: true,
-				},
-				Params: out.Params{
-					ManifestPath: "a/path/to/a/manifest.yml",
-				},
-			}
+			request = newRequest()
+			request.Source.SkipCertCheck = true
 
 			_, err := command.Run(request)
 			Expect(err).NotTo(HaveOccurred())
@@ -229,18 +415,11 @@ var _ = Describe("Out Command", func() {
 		})
 
 		It("lets users authenticate with client credentials", func() {
-			request = out.Request{
-				Source: resource.Source{
-					API:          "https://api.run.pivotal.io",
-					ClientID:     "awesome",
-					ClientSecret: "hunter2",
-					Organization: "secret",
-					Space:        "volcano-base",
-				},
-				Params: out.Params{
-					ManifestPath: "a/path/to/a/manifest.yml",
-				},
-			}
+			request = newRequest()
+			request.Source.Username = ""
+			request.Source.Password = ""
+			request.Source.ClientID = "awesome"
+			request.Source.ClientSecret = "hunter2"
 
 			_, err := command.Run(request)
 			Expect(err).NotTo(HaveOccurred())
@@ -256,19 +435,8 @@ var _ = Describe("Out Command", func() {
 		})
 
 		It("lets people do a zero downtime deploy", func() {
-			request = out.Request{
-				Source: resource.Source{
-					API:          "https://api.run.pivotal.io",
-					Username:     "awesome@example.com",
-					Password:     "hunter2",
-					Organization: "secret",
-					Space:        "volcano-base",
-				},
-				Params: out.Params{
-					ManifestPath:   "a/path/to/a/manifest.yml",
-					CurrentAppName: "cool-app-name",
-				},
-			}
+			request = newRequest()
+			request.Params.CurrentAppName = "cool-app-name"
 
 			_, err := command.Run(request)
 			Expect(err).NotTo(HaveOccurred())
@@ -281,20 +449,9 @@ var _ = Describe("Out Command", func() {
 		})
 
 		It("lets people define a user for connecting to a docker registry", func() {
-			request = out.Request{
-				Source: resource.Source{
-					API:          "https://api.run.pivotal.io",
-					Username:     "awesome@example.com",
-					Password:     "hunter2",
-					Organization: "secret",
-					Space:        "volcano-base",
-				},
-				Params: out.Params{
-					ManifestPath:   "a/path/to/a/manifest.yml",
-					CurrentAppName: "cool-app-name",
-					DockerUsername: "DOCKER_USER",
-				},
-			}
+			request = newRequest()
+			request.Params.CurrentAppName = "cool-app-name"
+			request.Params.DockerUsername = "DOCKER_USER"
 
 			_, err := command.Run(request)
 			Expect(err).NotTo(HaveOccurred())
@@ -302,7 +459,7 @@ var _ = Describe("Out Command", func() {
 			By("pushing the app")
 			Expect(cloudFoundry.PushAppCallCount()).To(Equal(1))
 
-			_, _, _, _, _, dockerUser, _, _ := cloudFoundry.PushAppArgsForCall(0)
+			_, _, _, dockerUser, _, _, _, _ := cloudFoundry.PushAppArgsForCall(0)
 			Expect(dockerUser).To(Equal("DOCKER_USER"))
 		})
 
@@ -320,19 +477,8 @@ var _ = Describe("Out Command", func() {
 
 			Context("docker password provided", func() {
 				It("sets the system environment variable", func() {
-					request = out.Request{
-						Source: resource.Source{
-							API:          "https://api.run.pivotal.io",
-							Username:     "awesome@example.com",
-							Password:     "hunter2",
-							Organization: "secret",
-							Space:        "volcano-base",
-						},
-						Params: out.Params{
-							ManifestPath:   "a/path/to/a/manifest/using/docker.yml",
-							DockerPassword: "mySuperSecretPassword",
-						},
-					}
+					request = newRequest()
+					request.Params.DockerPassword = "mySuperSecretPassword"
 					_, err := command.Run(request)
 					Expect(err).NotTo(HaveOccurred())
 
@@ -344,18 +490,7 @@ var _ = Describe("Out Command", func() {
 
 			Context("no docker password provided", func() {
 				It("doesn't set the system environment variable", func() {
-					request = out.Request{
-						Source: resource.Source{
-							API:          "https://api.run.pivotal.io",
-							Username:     "awesome@example.com",
-							Password:     "hunter2",
-							Organization: "secret",
-							Space:        "volcano-base",
-						},
-						Params: out.Params{
-							ManifestPath: "a/path/to/a/manifest/using/docker.yml",
-						},
-					}
+					request = newRequest()
 					os.Setenv(out.CfDockerPassword, "MyOwnUntouchedVariable")
 
 					_, err := command.Run(request)
@@ -0,0 +1,130 @@
+package out
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EnvironmentVariables are the environment_variables param. Values are set
+// on every app in the manifest, except for objects: those are keyed by app
+// name and only set on that app, overriding any global value of the same name.
+//
+//	environment_variables:
+//	  LOG_LEVEL: info
+//	  api:
+//	    DB_PASSWORD: ((api-db-password))
+//	    WORKERS: 3
+//
+// Values can be any JSON. Apps only see strings, so anything else is set as
+// its JSON encoding, e.g. 3, true or ["a","b"]. A global object has to be
+// written as a JSON string, so that a misspelt app name fails the put rather
+// than setting that app's variables on every app.
+type EnvironmentVariables struct {
+	Global map[string]interface{}
+	Apps   map[string]map[string]interface{}
+}
+
+func (env *EnvironmentVariables) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*env = EnvironmentVariables{}
+	for key, value := range raw {
+		if bytes.HasPrefix(bytes.TrimSpace(value), []byte("{")) {
+			var appEnv map[string]interface{}
+			if err := decodeJSON(value, &appEnv); err != nil {
+				return fmt.Errorf("environment_variables.%s: %s", key, err)
+			}
+			if env.Apps == nil {
+				env.Apps = map[string]map[string]interface{}{}
+			}
+			env.Apps[key] = appEnv
+			continue
+		}
+
+		var globalValue interface{}
+		if err := decodeJSON(value, &globalValue); err != nil {
+			return fmt.Errorf("environment_variables.%s: %s", key, err)
+		}
+		if env.Global == nil {
+			env.Global = map[string]interface{}{}
+		}
+		env.Global[key] = globalValue
+	}
+
+	return nil
+}
+
+func (env EnvironmentVariables) MarshalJSON() ([]byte, error) {
+	raw := map[string]interface{}{}
+	for key, value := range env.Global {
+		raw[key] = value
+	}
+	for app, appEnv := range env.Apps {
+		raw[app] = appEnv
+	}
+	return json.Marshal(raw)
+}
+
+// decodeJSON keeps numbers as json.Number so that they are set exactly as
+// written, e.g. 10000000000 rather than 1e+10.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// envStrings serializes values the way cf sets them on an app.
+func envStrings(values map[string]interface{}) (map[string]string, error) {
+	serialized := make(map[string]string, len(values))
+	for key, value := range values {
+		if s, ok := value.(string); ok {
+			serialized[key] = s
+			continue
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("environment_variables.%s: %s", key, err)
+		}
+		serialized[key] = string(encoded)
+	}
+	return serialized, nil
+}
+
+// addTo sets the variables on the manifest's apps. Every app named in env
+// has to be in the manifest.
+func (env EnvironmentVariables) addTo(manifest *Manifest) error {
+	global, err := envStrings(env.Global)
+	if err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(global) {
+		manifest.AddEnvironmentVariable(key, global[key])
+	}
+
+	var missing []string
+	for app, values := range env.Apps {
+		appEnv, err := envStrings(values)
+		if err != nil {
+			return err
+		}
+		for _, key := range sortedKeys(appEnv) {
+			if !manifest.AddAppEnvironmentVariable(app, key, appEnv[key]) {
+				missing = append(missing, app)
+				break
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("environment_variables: no app named %s in the manifest", strings.Join(missing, ", "))
+	}
+	return nil
+}
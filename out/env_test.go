@@ -0,0 +1,90 @@
+package out_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+)
+
+var _ = Describe("EnvironmentVariables", func() {
+	It("reads objects as per app and anything else as global", func() {
+		var env out.EnvironmentVariables
+		err := json.Unmarshal([]byte(`{"LOG_LEVEL":"info","HOSTS":["a","b"],"api":{"DB_PASSWORD":"secret"},"worker":{"QUEUE":"jobs"}}`), &env)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(env.Global).To(Equal(map[string]interface{}{
+			"LOG_LEVEL": "info",
+			"HOSTS":     []interface{}{"a", "b"},
+		}))
+		Expect(env.Apps).To(Equal(map[string]map[string]interface{}{
+			"api":    {"DB_PASSWORD": "secret"},
+			"worker": {"QUEUE": "jobs"},
+		}))
+	})
+
+	It("keeps numbers exactly as written", func() {
+		var env out.EnvironmentVariables
+		err := json.Unmarshal([]byte(`{"MAX_BYTES":10000000000,"RATIO":0.5}`), &env)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(env.Global).To(Equal(map[string]interface{}{
+			"MAX_BYTES": json.Number("10000000000"),
+			"RATIO":     json.Number("0.5"),
+		}))
+	})
+
+	It("round trips through JSON", func() {
+		env := out.EnvironmentVariables{
+			Global: map[string]interface{}{"LOG_LEVEL": "info", "DEBUG": true},
+			Apps:   map[string]map[string]interface{}{"api": {"DB_PASSWORD": "secret"}},
+		}
+
+		data, err := json.Marshal(env)
+		Expect(err).NotTo(HaveOccurred())
+
+		var decoded out.EnvironmentVariables
+		err = json.Unmarshal(data, &decoded)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(Equal(env))
+	})
+})
+
+var _ = Describe("Secrets", func() {
+	It("includes the credentials and every environment variable value", func() {
+		request := out.Request{
+			Source: resource.Source{Username: "admin", Password: "hunter2", ClientSecret: "s3cret"},
+			Params: out.Params{
+				DockerPassword: "docker-s3cret",
+				EnvironmentVariables: out.EnvironmentVariables{
+					Global: map[string]interface{}{"LOG_LEVEL": "info"},
+					Apps:   map[string]map[string]interface{}{"api": {"DB_PASSWORD": "db-s3cret"}},
+				},
+			},
+		}
+
+		Expect(request.Secrets()).To(ConsistOf("hunter2", "s3cret", "docker-s3cret", "info", "db-s3cret"))
+	})
+
+	It("includes the string values of vars", func() {
+		request := out.Request{Params: out.Params{Vars: map[string]interface{}{
+			"db":    map[string]interface{}{"password": "db-s3cret", "port": json.Number("5432")},
+			"hosts": []interface{}{"a.example.com"},
+		}}}
+
+		Expect(request.Secrets()).To(ContainElement("db-s3cret"))
+		Expect(request.Secrets()).To(ContainElement("a.example.com"))
+		Expect(request.Secrets()).NotTo(ContainElement("5432"))
+	})
+
+	It("reads the string values of vars_files", func() {
+		params := out.Params{VarsFiles: []string{"assets/vars.yml"}}
+
+		secrets, err := params.VarsFileSecrets()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secrets).To(ConsistOf("from-vars-file", "database", "s3cret"))
+	})
+})
@@ -0,0 +1,192 @@
+package out
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+const (
+	fingerprintKey = "cf-resource.concourse-ci.org/fingerprint"
+	deployedAtKey  = "cf-resource.concourse-ci.org/deployed-at"
+)
+
+// Fingerprint hashes what a push would deploy: the interpolated manifest,
+// the app bits at path (or at each app's path in the manifest) and the
+// params that change how the apps are pushed. App paths are left out of the
+// manifest, since they differ from build to build; their contents are
+// hashed instead.
+//
+// It returns "" when what cf push uploads can't be hashed: an app without a
+// path uploads the working directory, and a docker image by tag can change
+// under the same manifest. Such a push is never skipped.
+func (manifest *Manifest) Fingerprint(manifestPath string, params Params) (string, error) {
+	yamlData, err := yaml.Marshal(manifest.document)
+	if err != nil {
+		return "", err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(yamlData, &document); err != nil {
+		return "", err
+	}
+	copied := Manifest{document: &document}
+
+	if err := copied.resolvePaths(manifestPath); err != nil {
+		return "", err
+	}
+
+	paths := map[string]bool{}
+	if params.Path != "" {
+		paths[params.Path] = true
+	}
+	for _, app := range copied.apps() {
+		if docker := mappingValue(app, "docker"); docker != nil {
+			if image := mappingValue(docker, "image"); image == nil || !strings.Contains(image.Value, "@sha256:") {
+				return "", nil
+			}
+		} else if path := mappingValue(app, "path"); params.Path == "" && (path == nil || path.Value == "") {
+			return "", nil
+		}
+		if path := mappingValue(app, "path"); path != nil && params.Path == "" && path.Value != "" {
+			paths[path.Value] = true
+		}
+		removeKey(app, "path")
+	}
+
+	h := sha256.New()
+
+	if copied.root() != nil {
+		yamlData, err = yaml.Marshal(copied.document)
+		if err != nil {
+			return "", err
+		}
+	}
+	fmt.Fprintf(h, "manifest %d\n", len(yamlData))
+	h.Write(yamlData)
+
+	pushed, err := json.Marshal(struct {
+		NoStart        bool              `json:"no_start"`
+		DockerUsername string            `json:"docker_username"`
+		Labels         map[string]string `json:"labels"`
+		Annotations    map[string]string `json:"annotations"`
+		Services       []Service         `json:"services"`
+		Routes         Routes            `json:"routes"`
+	}{params.NoStart, params.DockerUsername, params.Labels, params.Annotations, params.Services, params.Routes})
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "params %s\n", pushed)
+
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+	for i, path := range sorted {
+		fmt.Fprintf(h, "bits %d\n", i)
+		if err := hashBits(h, path); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashBits hashes a zip file, or the names, modes and contents of the files
+// in a directory.
+func hashBits(h hash.Hash, path string) error {
+	// cf push reports a missing path itself
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		fmt.Fprintf(h, "missing %q\n", path)
+		return nil
+	}
+
+	return filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case info.IsDir():
+			fmt.Fprintf(h, "dir %q %o\n", filepath.ToSlash(name), info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "link %q %q\n", filepath.ToSlash(name), target)
+		case info.Mode().IsRegular():
+			fmt.Fprintf(h, "file %q %o %d\n", filepath.ToSlash(name), info.Mode().Perm(), info.Size())
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(h, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// unchangedSince returns when the apps were deployed, if every one of them
+// was last deployed with this fingerprint, or the zero time.
+func (command *Command) unchangedSince(apps []string, fingerprint string) time.Time {
+	var deployedAt time.Time
+
+	for _, app := range apps {
+		annotations, err := command.paas.AppAnnotations(app)
+		if err != nil {
+			if !cli.Is(err, cli.AppNotFound) {
+				fmt.Fprintf(command.log, "warning: could not read the fingerprint of %s, pushing anyway: %s\n", app, err)
+			}
+			return time.Time{}
+		}
+
+		if annotations[fingerprintKey] != fingerprint {
+			return time.Time{}
+		}
+
+		deployed, err := time.Parse(time.RFC3339Nano, annotations[deployedAtKey])
+		if err != nil {
+			return time.Time{}
+		}
+		if deployed.After(deployedAt) {
+			deployedAt = deployed
+		}
+	}
+
+	return deployedAt
+}
+
+// AppAnnotations returns the annotations on an app.
+func (cf *CloudFoundry) AppAnnotations(name string) (map[string]string, error) {
+	guid, err := cf.appGUID(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var app v3App
+	if err := cf.curl("/v3/apps/"+guid, &app); err != nil {
+		return nil, err
+	}
+	return app.Metadata.Annotations, nil
+}
@@ -0,0 +1,176 @@
+package out_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Fingerprint", func() {
+	var builds []string
+
+	// build lays out a manifest and the app's bits the way a build would,
+	// in a directory of its own
+	build := func(manifest string, files map[string]string) string {
+		dir, err := ioutil.TempDir("", "fingerprint")
+		Expect(err).NotTo(HaveOccurred())
+		builds = append(builds, dir)
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "manifest.yml"), []byte(manifest), 0644)).To(BeNil())
+		for name, content := range files {
+			Expect(os.MkdirAll(filepath.Join(dir, "app", filepath.Dir(name)), 0755)).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "app", name), []byte(content), 0644)).To(BeNil())
+		}
+		return filepath.Join(dir, "manifest.yml")
+	}
+
+	fingerprint := func(manifestPath string, params out.Params) string {
+		manifest, err := out.NewManifest(manifestPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		fingerprint, err := manifest.Fingerprint(manifestPath, params)
+		Expect(err).NotTo(HaveOccurred())
+		return fingerprint
+	}
+
+	const manifest = "applications:\n- name: web\n  path: app\n"
+	files := map[string]string{"main.go": "package main", "static/index.html": "<h1>hi</h1>"}
+
+	AfterEach(func() {
+		for _, dir := range builds {
+			os.RemoveAll(dir)
+		}
+		builds = nil
+	})
+
+	It("is the same for the same manifest and bits in another build", func() {
+		Expect(fingerprint(build(manifest, files), out.Params{})).To(Equal(fingerprint(build(manifest, files), out.Params{})))
+	})
+
+	It("changes with the bits", func() {
+		changed := map[string]string{"main.go": "package main", "static/index.html": "<h1>hello</h1>"}
+		Expect(fingerprint(build(manifest, files), out.Params{})).NotTo(Equal(fingerprint(build(manifest, changed), out.Params{})))
+	})
+
+	It("changes with the manifest", func() {
+		Expect(fingerprint(build(manifest, files), out.Params{})).NotTo(Equal(fingerprint(build(manifest+"  instances: 2\n", files), out.Params{})))
+	})
+
+	It("changes with params that change the push", func() {
+		manifestPath := build(manifest, files)
+		Expect(fingerprint(manifestPath, out.Params{})).NotTo(Equal(fingerprint(manifestPath, out.Params{NoStart: true})))
+	})
+
+	It("is empty when an app's bits can't be hashed", func() {
+		Expect(fingerprint(build("applications:\n- name: web\n", files), out.Params{})).To(BeEmpty())
+		Expect(fingerprint(build(manifest+"- name: worker\n", files), out.Params{})).To(BeEmpty())
+		Expect(fingerprint(build("applications:\n- name: web\n  docker:\n    image: org/web:latest\n", files), out.Params{})).To(BeEmpty())
+		Expect(fingerprint(build("applications:\n- name: web\n  docker:\n    image: org/web@sha256:0123abcd\n", files), out.Params{})).NotTo(BeEmpty())
+	})
+
+	It("hashes the bits at path instead of the manifest's", func() {
+		manifestPath := build(manifest, files)
+		path := filepath.Join(filepath.Dir(manifestPath), "app", "static")
+		Expect(fingerprint(manifestPath, out.Params{Path: path})).NotTo(Equal(fingerprint(manifestPath, out.Params{})))
+	})
+})
+
+var _ = Describe("Skipping unchanged deploys", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		deployed     map[string]map[string]string
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		command = out.NewCommand(cloudFoundry, GinkgoWriter)
+
+		// the apps keep the annotations they were stamped with
+		deployed = map[string]map[string]string{}
+		cloudFoundry.SetAppMetadataStub = func(name string, _ map[string]string, annotations map[string]string) error {
+			deployed[name] = annotations
+			return nil
+		}
+		cloudFoundry.AppAnnotationsStub = func(name string) (map[string]string, error) {
+			annotations, found := deployed[name]
+			if !found {
+				return nil, &cli.Error{Kind: cli.AppNotFound}
+			}
+			return annotations, nil
+		}
+		cloudFoundry.GetAppStub = func(name string) (out.App, error) {
+			return out.App{Name: name}, nil
+		}
+
+		request = newRequest()
+		request.Params.Path = "assets"
+	})
+
+	It("skips the push and returns the version that deployed the same thing", func() {
+		first, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(1))
+
+		second, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(1))
+
+		Expect(second.Version.Timestamp.Equal(first.Version.Timestamp)).To(BeTrue())
+		Expect(second.Metadata).To(ContainElement(resource.MetadataPair{Name: "skipped", Value: "true"}))
+		Expect(second.Metadata).To(ContainElement(resource.MetadataPair{Name: "app", Value: "app2"}))
+	})
+
+	It("pushes anyway with force", func() {
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.Params.Force = true
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(2))
+		Expect(response.Metadata).NotTo(ContainElement(resource.MetadataPair{Name: "skipped", Value: "true"}))
+	})
+
+	It("pushes when anything changed", func() {
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		request.Params.EnvironmentVariables = out.EnvironmentVariables{Global: map[string]interface{}{"A": "b"}}
+		_, err = command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(2))
+	})
+
+	It("always pushes without a path, since cf push uploads the working directory", func() {
+		request.Params.Path = ""
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(2))
+		Expect(response.Metadata).NotTo(ContainElement(resource.MetadataPair{Name: "skipped", Value: "true"}))
+	})
+
+	It("pushes when any app was deployed differently", func() {
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		deployed["app2"] = map[string]string{"cf-resource.concourse-ci.org/deployed-at": time.Now().Format(time.RFC3339Nano)}
+		_, err = command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(2))
+	})
+})
@@ -0,0 +1,187 @@
+package out
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/concourse/cf-resource"
+)
+
+// maxFreezeDuration bounds how far back a repeating window is searched for
+// its start.
+const maxFreezeDuration = 31 * 24 * time.Hour
+
+var freezeTimeLayouts = []string{"2006-01-02T15:04", "2006-01-02"}
+
+// ActiveFreezeWindow returns the first window that now falls in, or nil.
+func ActiveFreezeWindow(windows []resource.FreezeWindow, now time.Time) (*resource.FreezeWindow, error) {
+	for i, window := range windows {
+		active, err := freezeActive(window, now)
+		if err != nil {
+			name := window.Name
+			if name == "" {
+				name = strconv.Itoa(i)
+			}
+			return nil, fmt.Errorf("invalid freeze window %s: %s", name, err)
+		}
+		if active {
+			return &windows[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func freezeActive(window resource.FreezeWindow, now time.Time) (bool, error) {
+	location := time.UTC
+	if window.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(window.TimeZone)
+		if err != nil {
+			return false, err
+		}
+	}
+	now = now.In(location)
+
+	if window.Cron != "" {
+		schedule, err := parseCron(window.Cron)
+		if err != nil {
+			return false, err
+		}
+
+		duration, err := time.ParseDuration(window.Duration)
+		if err != nil {
+			return false, fmt.Errorf("duration: %s", err)
+		}
+		if duration <= 0 || duration > maxFreezeDuration {
+			return false, fmt.Errorf("duration must be between 1m and %s", maxFreezeDuration)
+		}
+
+		// the window is active if it started within the last duration
+		minute := now.Truncate(time.Minute)
+		for started := minute; now.Sub(started) < duration; started = started.Add(-time.Minute) {
+			if schedule.matches(started) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if window.Start == "" || window.End == "" {
+		return false, fmt.Errorf("expected either cron and duration, or start and end")
+	}
+
+	start, _, err := parseFreezeTime(window.Start, location)
+	if err != nil {
+		return false, fmt.Errorf("start: %s", err)
+	}
+	end, dateOnly, err := parseFreezeTime(window.End, location)
+	if err != nil {
+		return false, fmt.Errorf("end: %s", err)
+	}
+	// an end date includes the whole day
+	if dateOnly {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return !now.Before(start) && now.Before(end), nil
+}
+
+func parseFreezeTime(value string, location *time.Location) (time.Time, bool, error) {
+	for i, layout := range freezeTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, i == len(freezeTimeLayouts)-1, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("%q is not a date (2006-01-02) or time (2006-01-02T15:04)", value)
+}
+
+// cronSchedule is a parsed five field cron expression: minute, hour, day of
+// month, month and day of week.
+type cronSchedule struct {
+	fields [5]map[int]bool
+	// cron matches either day field when both are restricted
+	anyDay bool
+}
+
+var cronRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+func parseCron(expression string) (cronSchedule, error) {
+	parts := strings.Fields(expression)
+	if len(parts) != 5 {
+		return cronSchedule{}, fmt.Errorf("cron %q: expected 5 fields, minute hour day-of-month month day-of-week", expression)
+	}
+
+	var schedule cronSchedule
+	for i, part := range parts {
+		values, err := parseCronField(part, cronRanges[i][0], cronRanges[i][1])
+		if err != nil {
+			return cronSchedule{}, fmt.Errorf("cron %q: %s", expression, err)
+		}
+		schedule.fields[i] = values
+	}
+
+	// 7 is Sunday as well as 0
+	if schedule.fields[4][7] {
+		schedule.fields[4][0] = true
+	}
+	schedule.anyDay = parts[2] != "*" && parts[4] != "*"
+
+	return schedule, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %q", item)
+			}
+			rangePart = item[:i]
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", item)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", item)
+				}
+			} else if step > 1 {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%q is out of range %d-%d", item, min, max)
+		}
+		for value := low; value <= high; value += step {
+			values[value] = true
+		}
+	}
+
+	return values, nil
+}
+
+func (schedule cronSchedule) matches(t time.Time) bool {
+	if !schedule.fields[0][t.Minute()] || !schedule.fields[1][t.Hour()] || !schedule.fields[3][int(t.Month())] {
+		return false
+	}
+
+	dayOfMonth := schedule.fields[2][t.Day()]
+	dayOfWeek := schedule.fields[4][int(t.Weekday())]
+	if schedule.anyDay {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
@@ -0,0 +1,164 @@
+package out_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("ActiveFreezeWindow", func() {
+	london, _ := time.LoadLocation("Europe/London")
+
+	weekend := resource.FreezeWindow{
+		Name:     "weekend",
+		Cron:     "0 18 * * 5",
+		Duration: "62h",
+		TimeZone: "Europe/London",
+	}
+	holidays := resource.FreezeWindow{
+		Name:     "holidays",
+		Start:    "2026-12-24",
+		End:      "2026-12-26",
+		TimeZone: "Europe/London",
+	}
+	windows := []resource.FreezeWindow{weekend, holidays}
+
+	activeIn := func(windows []resource.FreezeWindow, t time.Time) string {
+		window, err := out.ActiveFreezeWindow(windows, t)
+		Expect(err).NotTo(HaveOccurred())
+		if window == nil {
+			return ""
+		}
+		return window.Name
+	}
+	active := func(t time.Time) string {
+		return activeIn(windows, t)
+	}
+
+	It("repeats windows that start whenever the cron expression matches", func() {
+		Expect(active(time.Date(2026, 10, 16, 17, 59, 0, 0, london))).To(Equal(""))
+		Expect(active(time.Date(2026, 10, 16, 18, 0, 0, 0, london))).To(Equal("weekend"))
+		Expect(active(time.Date(2026, 10, 18, 23, 0, 0, 0, london))).To(Equal("weekend"))
+		Expect(active(time.Date(2026, 10, 19, 8, 0, 0, 0, london))).To(Equal(""))
+	})
+
+	It("uses the window's time zone", func() {
+		// 18:00 in London is 17:00 UTC in summer time
+		Expect(active(time.Date(2026, 7, 17, 17, 0, 0, 0, time.UTC))).To(Equal("weekend"))
+		Expect(active(time.Date(2026, 7, 17, 16, 59, 0, 0, time.UTC))).To(Equal(""))
+	})
+
+	It("includes the whole of an end date", func() {
+		only := []resource.FreezeWindow{holidays}
+		Expect(activeIn(only, time.Date(2026, 12, 23, 23, 59, 0, 0, london))).To(Equal(""))
+		Expect(activeIn(only, time.Date(2026, 12, 24, 0, 0, 0, 0, london))).To(Equal("holidays"))
+		Expect(activeIn(only, time.Date(2026, 12, 26, 23, 59, 0, 0, london))).To(Equal("holidays"))
+		Expect(activeIn(only, time.Date(2026, 12, 27, 0, 0, 0, 0, london))).To(Equal(""))
+	})
+
+	It("returns the first active window", func() {
+		// Christmas Day 2026 is a Friday
+		Expect(active(time.Date(2026, 12, 25, 20, 0, 0, 0, london))).To(Equal("weekend"))
+	})
+
+	It("matches either day field when both are set, like cron", func() {
+		window := resource.FreezeWindow{Name: "cutoff", Cron: "0 0 1 * 1", Duration: "24h"}
+
+		// the 1st of the month, a Thursday
+		found, err := out.ActiveFreezeWindow([]resource.FreezeWindow{window}, time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).NotTo(BeNil())
+
+		// a Monday
+		found, err = out.ActiveFreezeWindow([]resource.FreezeWindow{window}, time.Date(2026, 10, 12, 12, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).NotTo(BeNil())
+	})
+
+	It("rejects invalid windows", func() {
+		_, err := out.ActiveFreezeWindow([]resource.FreezeWindow{{Name: "bad", Cron: "0 25 * * *", Duration: "1h"}}, time.Now())
+		Expect(err).To(MatchError(`invalid freeze window bad: cron "0 25 * * *": "25" is out of range 0-23`))
+
+		_, err = out.ActiveFreezeWindow([]resource.FreezeWindow{{Start: "2026-12-24"}}, time.Now())
+		Expect(err).To(MatchError("invalid freeze window 0: expected either cron and duration, or start and end"))
+
+		_, err = out.ActiveFreezeWindow([]resource.FreezeWindow{{Cron: "* * * * *", Duration: "1h", TimeZone: "Mars/Olympus"}}, time.Now())
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Freeze windows", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		command = out.NewCommand(cloudFoundry, GinkgoWriter)
+
+		request = newRequest()
+		request.Source.FreezeWindows = []resource.FreezeWindow{
+			{Name: "always", Cron: "* * * * *", Duration: "1m"},
+		}
+	})
+
+	It("refuses to deploy inside a window, before logging in", func() {
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("deploys are frozen by freeze window always; set override_freeze and override_freeze_reason to deploy anyway"))
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+
+	It("deploys with an override and records it in the metadata", func() {
+		request.Params.OverrideFreeze = true
+		request.Params.OverrideFreezeReason = "hotfix for INC-123"
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(1))
+
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "freeze_override", Value: "always"}))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "freeze_override_reason", Value: "hotfix for INC-123"}))
+	})
+
+	It("needs a reason to override", func() {
+		request.Params.OverrideFreeze = true
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("override_freeze needs an override_freeze_reason"))
+	})
+
+	It("doesn't freeze dry runs", func() {
+		request.Params.DryRun = true
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(1))
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+
+		request.Params.Cleanup = out.Cleanup{Apps: "review-*"}
+		request.Params.Action = out.ActionCleanup
+
+		_, err = command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.DeleteAppCallCount()).To(Equal(0))
+	})
+
+	It("records nothing outside a window", func() {
+		request.Source.FreezeWindows = nil
+		request.Params.OverrideFreeze = true
+		request.Params.OverrideFreezeReason = "just in case"
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		for _, pair := range response.Metadata {
+			Expect(pair.Name).NotTo(Equal("freeze_override"))
+		}
+	})
+})
@@ -0,0 +1,98 @@
+package out
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+const (
+	DefaultRunningTimeout  = 5 * time.Minute
+	DefaultStabilityWindow = 30 * time.Second
+)
+
+var pollInterval = 2 * time.Second
+
+// sleepUntil waits for the next poll, but not past the earliest deadline.
+func sleepUntil(deadlines ...time.Time) {
+	sleep := pollInterval
+	for _, deadline := range deadlines {
+		if remaining := time.Until(deadline); remaining < sleep {
+			sleep = remaining
+		}
+	}
+	time.Sleep(sleep)
+}
+
+type InstanceCount struct {
+	App     string
+	Running int
+	Desired int
+}
+
+func (count InstanceCount) String() string {
+	return fmt.Sprintf("%d/%d running", count.Running, count.Desired)
+}
+
+// waitForRunning polls the apps until every desired instance has been
+// RUNNING for the whole stability window. A crashed instance fails straight
+// away; an instance that drops out of RUNNING restarts the window.
+func waitForRunning(paas PAAS, apps []string, timeout time.Duration, window time.Duration, log io.Writer) ([]InstanceCount, error) {
+	counts := make([]InstanceCount, len(apps))
+
+	for i, app := range apps {
+		fmt.Fprintf(log, "Waiting for %s to be running for %s...\n", app, window)
+
+		deadline := time.Now().Add(timeout)
+		var runningSince time.Time
+
+		for {
+			states, err := paas.InstanceStates(app)
+			if err != nil {
+				return counts, err
+			}
+
+			counts[i] = InstanceCount{App: app, Desired: len(states)}
+			for index, state := range states {
+				switch state {
+				case "RUNNING":
+					counts[i].Running++
+				case "CRASHED":
+					return counts, &cli.Error{
+						Kind:    cli.AppCrashed,
+						Command: "push",
+						Line:    fmt.Sprintf("instance %d of %s crashed", index, app),
+					}
+				}
+			}
+
+			now := time.Now()
+			if counts[i].Running == counts[i].Desired {
+				if runningSince.IsZero() {
+					runningSince = now
+				}
+				if now.Sub(runningSince) >= window {
+					break
+				}
+			} else {
+				runningSince = time.Time{}
+			}
+
+			if !now.Before(deadline) {
+				return counts, &cli.Error{
+					Kind:    cli.StartTimeout,
+					Command: "push",
+					Line:    fmt.Sprintf("%s has %s after %s", app, counts[i], timeout),
+				}
+			}
+
+			sleepUntil(deadline)
+		}
+
+		fmt.Fprintf(log, "%s: %s\n", app, counts[i])
+	}
+
+	return counts, nil
+}
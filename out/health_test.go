@@ -0,0 +1,110 @@
+package out_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Waiting for running instances", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		request      out.Request
+		command      *out.Command
+		log          *gbytes.Buffer
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+
+		cloudFoundry.PushAppStub = func(_ string, _ string, _ string, _ string, _ bool, _ bool, _ string, afterPush func() error) (out.PushResult, error) {
+			if afterPush == nil {
+				return out.PushResult{}, nil
+			}
+			return out.PushResult{}, afterPush()
+		}
+		cloudFoundry.GetAppStub = func(name string) (out.App, error) {
+			return out.App{Name: name, Instances: 3}, nil
+		}
+		cloudFoundry.InstanceStatesReturns([]string{"RUNNING", "RUNNING", "RUNNING"}, nil)
+
+		request = newRequest()
+		request.Params.WaitForRunning = true
+		request.Params.StabilityWindow = "0s"
+	})
+
+	It("checks every pushed app after the push and reports the instance counts", func() {
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.InstanceStatesCallCount()).To(Equal(2))
+		Expect(cloudFoundry.InstanceStatesArgsForCall(0)).To(Equal("app1"))
+		Expect(cloudFoundry.InstanceStatesArgsForCall(1)).To(Equal("app2"))
+
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "instances", Value: "3/3 running"}))
+		Expect(log).To(gbytes.Say("app1: 3/3 running"))
+	})
+
+	It("only checks the app being deployed with zero downtime", func() {
+		request.Params.CurrentAppName = "app2"
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.InstanceStatesCallCount()).To(Equal(1))
+		Expect(cloudFoundry.InstanceStatesArgsForCall(0)).To(Equal("app2"))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "instances", Value: "3/3 running"}))
+	})
+
+	It("fails the put when an instance crashes", func() {
+		cloudFoundry.InstanceStatesReturns([]string{"RUNNING", "CRASHED"}, nil)
+
+		_, err := command.Run(request)
+		Expect(cli.Is(err, cli.AppCrashed)).To(BeTrue())
+		Expect(err).To(MatchError("cf push: app crashed: instance 1 of app1 crashed"))
+	})
+
+	It("fails the put when the instances don't start in time", func() {
+		request.Params.RunningTimeout = "10ms"
+		cloudFoundry.InstanceStatesReturns([]string{"RUNNING", "STARTING"}, nil)
+
+		_, err := command.Run(request)
+		Expect(cli.Is(err, cli.StartTimeout)).To(BeTrue())
+		Expect(err).To(MatchError("cf push: start timed out: app1 has 1/2 running after 10ms"))
+	})
+
+	It("doesn't wait for apps that aren't started", func() {
+		request.Params.NoStart = true
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.InstanceStatesCallCount()).To(Equal(0))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "instances", Value: "3"}))
+	})
+
+	It("doesn't wait unless asked to", func() {
+		request.Params.WaitForRunning = false
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, _, _, _, _, _, afterPush := cloudFoundry.PushAppArgsForCall(0)
+		Expect(afterPush).To(BeNil())
+	})
+
+	It("rejects invalid durations before logging in", func() {
+		request.Params.StabilityWindow = "soon"
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(`invalid stability_window: time: invalid duration "soon"`))
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+})
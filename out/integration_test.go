@@ -21,6 +21,9 @@ import (
 	"github.com/concourse/cf-resource/out"
 )
 
+// the manifest is pushed from a temporary copy
+const pushedManifest = `\S+/cf-resource-manifest-\d+\.yml`
+
 var _ = Describe("Out", func() {
 	var (
 		tmpDir  string
@@ -110,11 +113,11 @@ var _ = Describe("Out", func() {
 
 			// shim outputs arguments
 			Expect(session.Err).To(gbytes.Say("cf api https://api.run.pivotal.io --skip-ssl-validation"))
-			Expect(session.Err).To(gbytes.Say("cf auth awesome@example.com hunter2"))
+			Expect(session.Err).To(gbytes.Say("cf auth\n"))
 			Expect(session.Err).To(gbytes.Say("cf target -o org -s space"))
 			Expect(session.Err).To(gbytes.Say("cf rename awesome-app awesome-app-venerable"))
 			Expect(session.Err).To(gbytes.Say("cf push awesome-app -f %s",
-				filepath.Join(tmpDir, "project/manifest.yml"),
+				pushedManifest,
 			))
 			Expect(session.Err).To(gbytes.Say(filepath.Join(tmpDir, "another-project")))
 			Expect(session.Err).To(gbytes.Say("cf delete -f awesome-app-venerable"))
@@ -123,11 +126,22 @@ var _ = Describe("Out", func() {
 			output := string(session.Err.Contents())
 			Expect(strings.Count(output, "CF_COLOR=true")).To(Equal(7))
 			Expect(strings.Count(output, "CF_TRACE=true")).To(Equal(7))
+
+			// the password goes to cf auth through its environment, and is
+			// masked wherever it is printed
+			Expect(output).To(ContainSubstring("CF_USERNAME=awesome@example.com"))
+			Expect(output).To(ContainSubstring("CF_PASSWORD=[REDACTED]"))
+			Expect(output).NotTo(ContainSubstring("hunter2"))
 		})
 	})
 
 	Context("when specifying vars", func() {
 		BeforeEach(func() {
+			err := ioutil.WriteFile(filepath.Join(tmpDir, "project", "manifest.yml"), []byte("applications:\n- name: ((foo))\n  memory: ((memory))\n"), 0444)
+			Expect(err).NotTo(HaveOccurred())
+			err = ioutil.WriteFile(filepath.Join(tmpDir, "vars.yml"), []byte("memory: 512M\n"), 0444)
+			Expect(err).NotTo(HaveOccurred())
+
 			request.Params.Vars = map[string]interface{}{"foo": "bar"}
 			request.Params.VarsFiles = []string{"vars.yml"}
 		})
@@ -150,31 +164,110 @@ var _ = Describe("Out", func() {
 
 			// shim outputs arguments
 			Expect(session.Err).To(gbytes.Say("cf api https://api.run.pivotal.io --skip-ssl-validation"))
-			Expect(session.Err).To(gbytes.Say("cf auth awesome@example.com hunter2"))
+			Expect(session.Err).To(gbytes.Say("cf auth\n"))
 			Expect(session.Err).To(gbytes.Say("cf target -o org -s space"))
 
 			Expect(session.Err).To(gbytes.Say("cf rename awesome-app awesome-app-venerable"))
-			Expect(session.Err).To(gbytes.Say("cf push awesome-app -f %s --var foo=bar --vars-file vars.yml",
-				filepath.Join(tmpDir, "project/manifest.yml"),
+			Expect(session.Err).To(gbytes.Say("cf push awesome-app -f %s -p .",
+				pushedManifest,
 			))
 			Expect(session.Err).To(gbytes.Say(filepath.Join(tmpDir, "another-project")))
 			Expect(session.Err).To(gbytes.Say("cf delete -f awesome-app-venerable"))
 
 			// color should be always
 			output := string(session.Err.Contents())
-			Expect(strings.Count(output, "CF_COLOR=true")).To(Equal(7))
-			Expect(strings.Count(output, "CF_TRACE=true")).To(Equal(7))
+			// the shim's output also ends up in the warnings about reading the
+			// app's fingerprint, stamping it and reading its metadata, which
+			// happen since the manifest has an app
+			Expect(strings.Count(output, "CF_COLOR=true")).To(Equal(10))
+			Expect(strings.Count(output, "CF_TRACE=true")).To(Equal(10))
+		})
+	})
+
+	Context("when the manifest has a variable that isn't set", func() {
+		BeforeEach(func() {
+			err := ioutil.WriteFile(filepath.Join(tmpDir, "project", "manifest.yml"), []byte("applications:\n- name: ((app_name))\n"), 0444)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("fails before logging in", func() {
+			session, err := gexec.Start(
+				cmd,
+				GinkgoWriter,
+				GinkgoWriter,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(1))
+
+			Expect(session.Err).To(gbytes.Say(`unresolved variables in manifest: \(\(app_name\)\)`))
+			Expect(string(session.Err.Contents())).NotTo(ContainSubstring("cf api"))
+		})
+	})
+
+	Context("when setting environment variables", func() {
+		var manifest []byte
+
+		BeforeEach(func() {
+			manifest = []byte("# deployed by concourse\napplications:\n- name: awesome-app # the web app\n  path: ../another-project\n")
+			err := ioutil.WriteFile(filepath.Join(tmpDir, "project", "manifest.yml"), manifest, 0444)
+			Expect(err).NotTo(HaveOccurred())
+
+			request.Params.EnvironmentVariables = out.EnvironmentVariables{Global: map[string]interface{}{"FOO": "bar"}}
+		})
+
+		It("pushes a copy of the manifest and leaves the original untouched", func() {
+			session, err := gexec.Start(
+				cmd,
+				GinkgoWriter,
+				GinkgoWriter,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Err).To(gbytes.Say("cf push awesome-app -f %s", pushedManifest))
+
+			contents, err := ioutil.ReadFile(filepath.Join(tmpDir, "project", "manifest.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(manifest))
+
+			matches, err := filepath.Glob(filepath.Join(os.TempDir(), "cf-resource-manifest-*.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(matches).To(BeEmpty())
+		})
+	})
+
+	Context("when using the rolling strategy", func() {
+		BeforeEach(func() {
+			request.Params.Strategy = "rolling"
+		})
+
+		It("pushes with cf's rolling deployment instead of renaming the app", func() {
+			session, err := gexec.Start(
+				cmd,
+				GinkgoWriter,
+				GinkgoWriter,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(session).Should(gexec.Exit(0))
+
+			Expect(session.Err).To(gbytes.Say("cf target -o org -s space"))
+			Expect(session.Err).To(gbytes.Say("cf push awesome-app -f %s --strategy rolling -p .",
+				pushedManifest,
+			))
+			Expect(string(session.Err.Contents())).NotTo(ContainSubstring("cf rename"))
 		})
 	})
 
 	Context("when my manifest and file paths contain a glob", func() {
-		var tmpFileManifest *os.File
 		var tmpFileSearch *os.File
 
 		BeforeEach(func() {
 			var err error
 
-			tmpFileManifest, err = ioutil.TempFile(tmpDir, "manifest-some-glob.yml_")
+			_, err = ioutil.TempFile(tmpDir, "manifest-some-glob.yml_")
 			Expect(err).NotTo(HaveOccurred())
 			tmpFileSearch, err = ioutil.TempFile(tmpDir, "another-path.jar_")
 			Expect(err).NotTo(HaveOccurred())
@@ -202,11 +295,11 @@ var _ = Describe("Out", func() {
 
 				// shim outputs arguments
 				Expect(session.Err).To(gbytes.Say("cf api https://api.run.pivotal.io --skip-ssl-validation"))
-				Expect(session.Err).To(gbytes.Say("cf auth awesome@example.com hunter2"))
+				Expect(session.Err).To(gbytes.Say("cf auth\n"))
 				Expect(session.Err).To(gbytes.Say("cf target -o org -s space"))
 				Expect(session.Err).To(gbytes.Say("cf rename awesome-app awesome-app-venerable"))
 				Expect(session.Err).To(gbytes.Say("cf push awesome-app -f %s -p %s",
-					tmpFileManifest.Name(),
+					pushedManifest,
 					tmpFileSearch.Name(),
 				))
 				Expect(session.Err).To(gbytes.Say("cf delete -f awesome-app-venerable"))
@@ -300,7 +393,7 @@ var _ = Describe("Out", func() {
 	Context("when accessing a protected docker registry", func() {
 		BeforeEach(func() {
 			request.Params.DockerUsername = "DOCKER_USERNAME"
-			request.Params.DockerPassword = "DOCKER_PASSWORD"
+			request.Params.DockerPassword = "docker-s3cret"
 		})
 
 		It("pushes an application to cloud foundry", func() {
@@ -321,15 +414,17 @@ var _ = Describe("Out", func() {
 
 			// shim outputs arguments
 			Expect(session.Err).To(gbytes.Say("cf api https://api.run.pivotal.io --skip-ssl-validation"))
-			Expect(session.Err).To(gbytes.Say("cf auth awesome@example.com hunter2"))
+			Expect(session.Err).To(gbytes.Say("cf auth\n"))
 			Expect(session.Err).To(gbytes.Say("cf target -o org -s space"))
 			Expect(session.Err).To(gbytes.Say("cf rename awesome-app awesome-app-venerable"))
 			Expect(session.Err).To(gbytes.Say("cf push awesome-app -f %s --docker-username %s",
-				filepath.Join(tmpDir, "project/manifest.yml"),
+				pushedManifest,
 				request.Params.DockerUsername,
 			))
 			Expect(session.Err).To(gbytes.Say("cf delete -f awesome-app-venerable"))
-			Expect(session.Err).To(gbytes.Say("CF_DOCKER_PASSWORD=DOCKER_PASSWORD"))
+			// set for cf push in its environment, and masked in the log
+			Expect(session.Err).To(gbytes.Say(`CF_DOCKER_PASSWORD=\[REDACTED\]`))
+			Expect(string(session.Err.Contents())).NotTo(ContainSubstring("docker-s3cret"))
 		})
 	})
 	Context("when doing an ordinary cf push", func() {
@@ -355,10 +450,10 @@ var _ = Describe("Out", func() {
 
 				// shim outputs arguments
 				Expect(session.Err).To(gbytes.Say("cf api https://api.run.pivotal.io --skip-ssl-validation"))
-				Expect(session.Err).To(gbytes.Say("cf auth awesome@example.com hunter2"))
+				Expect(session.Err).To(gbytes.Say("cf auth\n"))
 				Expect(session.Err).To(gbytes.Say("cf target -o org -s space"))
 				Expect(session.Err).To(gbytes.Say("cf push -f %s -p .",
-					filepath.Join(tmpDir, "project/manifest.yml"),
+					pushedManifest,
 				))
 				Expect(session.Err).To(gbytes.Say(filepath.Join(tmpDir, "another-project")))
 
@@ -390,10 +485,10 @@ var _ = Describe("Out", func() {
 
 				// shim outputs arguments
 				Expect(session.Err).To(gbytes.Say("cf api https://api.run.pivotal.io --skip-ssl-validation"))
-				Expect(session.Err).To(gbytes.Say("cf auth awesome@example.com hunter2"))
+				Expect(session.Err).To(gbytes.Say("cf auth\n"))
 				Expect(session.Err).To(gbytes.Say("cf target -o org -s space"))
 				Expect(session.Err).To(gbytes.Say("cf push -f %s --no-start",
-					filepath.Join(tmpDir, "project/manifest.yml"),
+					pushedManifest,
 				))
 				Expect(session.Err).To(gbytes.Say(filepath.Join(tmpDir, "another-project")))
 
@@ -0,0 +1,238 @@
+package out
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	LintWarn  = "warn"
+	LintError = "error"
+	LintFix   = "fix"
+)
+
+type LintIssue struct {
+	Line    int
+	Path    string
+	Message string
+	// Fixable issues are rewritten by FixDeprecated.
+	Fixable bool
+}
+
+func (issue LintIssue) String() string {
+	return fmt.Sprintf("line %d: %s %s", issue.Line, issue.Path, issue.Message)
+}
+
+// routeKeys are the attributes that routes replaced.
+var routeKeys = []string{"host", "hosts", "domain", "domains", "no-hostname"}
+
+// Lint reports deprecated attributes, which newer cf CLIs reject or ignore,
+// and settings that are risky for the way the app is deployed.
+func (manifest *Manifest) Lint(zeroDowntime bool) []LintIssue {
+	var issues []LintIssue
+
+	if inherit := mappingKey(manifest.root(), "inherit"); inherit != nil {
+		issues = append(issues, LintIssue{
+			Line:    inherit.Line,
+			Path:    "inherit",
+			Message: "is not supported by cf CLI v7 or later; use additional_manifests or ops_files instead",
+		})
+	}
+
+	for i, app := range manifest.apps() {
+		path := fmt.Sprintf("applications[%d]", i)
+
+		for _, key := range routeKeys {
+			if keyNode := mappingKey(app, key); keyNode != nil {
+				issues = append(issues, LintIssue{
+					Line:    keyNode.Line,
+					Path:    path + "." + key,
+					Message: "is deprecated, use routes",
+					Fixable: canFixRoutes(app),
+				})
+			}
+		}
+
+		if keyNode := mappingKey(app, "buildpack"); keyNode != nil {
+			issues = append(issues, LintIssue{
+				Line:    keyNode.Line,
+				Path:    path + ".buildpack",
+				Message: "is deprecated, use buildpacks",
+				Fixable: true,
+			})
+		}
+
+		if zeroDowntime {
+			const risk = "so the app is down whenever its only instance restarts, despite the zero downtime deploy"
+			if keyNode, valueNode := webKey(app, "instances"); keyNode == nil {
+				issues = append(issues, LintIssue{Line: app.Line, Path: path + ".instances", Message: "is not set and defaults to 1, " + risk})
+			} else if instances, _ := intValue(decodeNode(valueNode)); instances == 1 {
+				issues = append(issues, LintIssue{Line: keyNode.Line, Path: path + ".instances", Message: "is 1, " + risk})
+			}
+		}
+
+		if keyNode, _ := webKey(app, "health-check-type"); keyNode == nil {
+			issues = append(issues, LintIssue{
+				Line:    app.Line,
+				Path:    path,
+				Message: "has no health-check-type; set it to port, http or process so a broken app isn't reported as running",
+			})
+		}
+	}
+
+	return issues
+}
+
+// webKey returns the key and value of an attribute of the app's web process:
+// its entry in processes when that sets the attribute, otherwise the app's.
+func webKey(app *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for _, node := range []*yaml.Node{processNode(app, "web"), app} {
+		if keyNode := mappingKey(node, key); keyNode != nil {
+			return keyNode, mappingValue(node, key)
+		}
+	}
+	return nil, nil
+}
+
+// FixDeprecated rewrites deprecated attributes the modern way: host, hosts,
+// domain, domains and no-hostname become routes, and buildpack becomes
+// buildpacks. Apps that use host without a domain are left alone, since
+// their routes depend on the platform's default domain.
+func (manifest *Manifest) FixDeprecated() {
+	for _, app := range manifest.apps() {
+		if canFixRoutes(app) {
+			fixRoutes(app)
+		}
+
+		if buildpack := mappingValue(app, "buildpack"); buildpack != nil {
+			if mappingValue(app, "buildpacks") == nil {
+				renameKey(app, "buildpack", "buildpacks", &yaml.Node{
+					Kind:    yaml.SequenceNode,
+					Tag:     "!!seq",
+					Content: []*yaml.Node{buildpack},
+				})
+			} else {
+				removeKey(app, "buildpack")
+			}
+		}
+	}
+}
+
+func canFixRoutes(app *yaml.Node) bool {
+	return len(scalarValues(app, "domain", "domains")) > 0
+}
+
+func fixRoutes(app *yaml.Node) {
+	hosts := scalarValues(app, "host", "hosts")
+	if noHostname := mappingValue(app, "no-hostname"); noHostname != nil && noHostname.Value == "true" {
+		hosts = []string{""}
+	} else if len(hosts) == 0 {
+		// without a host cf used the app's name
+		if name := mappingValue(app, "name"); name != nil {
+			hosts = []string{name.Value}
+		}
+	}
+
+	routes := mappingValue(app, "routes")
+	if routes == nil || routes.Kind != yaml.SequenceNode {
+		routes = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+	for _, domain := range scalarValues(app, "domain", "domains") {
+		for _, host := range hosts {
+			route := domain
+			if host != "" {
+				route = host + "." + domain
+			}
+
+			routeNode := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setMappingValue(routeNode, "route", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: route})
+			routes.Content = append(routes.Content, routeNode)
+		}
+	}
+
+	// routes takes the place of the first deprecated key
+	replaced := mappingValue(app, "routes") != nil
+	for _, key := range routeKeys {
+		if mappingKey(app, key) == nil {
+			continue
+		}
+		if !replaced {
+			renameKey(app, key, "routes", routes)
+			replaced = true
+		} else {
+			removeKey(app, key)
+		}
+	}
+}
+
+// scalarValues collects the values of the given keys, whether they hold a
+// single value or a list.
+func scalarValues(node *yaml.Node, keys ...string) []string {
+	var values []string
+	for _, key := range keys {
+		value := mappingValue(node, key)
+		switch {
+		case value == nil:
+		case value.Kind == yaml.ScalarNode && value.Value != "":
+			values = append(values, value.Value)
+		case value.Kind == yaml.SequenceNode:
+			for _, item := range value.Content {
+				values = append(values, item.Value)
+			}
+		}
+	}
+	return values
+}
+
+func mappingKey(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+func renameKey(node *yaml.Node, key string, newKey string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i].Value = newKey
+			node.Content[i+1] = value
+			return
+		}
+	}
+}
+
+func removeKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+func decodeNode(node *yaml.Node) interface{} {
+	if node == nil {
+		return nil
+	}
+
+	var value interface{}
+	_ = node.Decode(&value)
+	return value
+}
+
+// lintReport formats issues one per line.
+func lintReport(issues []LintIssue) string {
+	lines := make([]string, len(issues))
+	for i, issue := range issues {
+		lines[i] = issue.String()
+	}
+	return strings.Join(lines, "\n  ")
+}
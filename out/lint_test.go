@@ -0,0 +1,86 @@
+package out_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource/out"
+)
+
+var _ = Describe("Lint", func() {
+	var manifest out.Manifest
+
+	BeforeEach(func() {
+		var err error
+		manifest, err = out.NewManifest("assets/deprecatedManifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("reports deprecated attributes and risky settings", func() {
+		Expect(manifest.Lint(true)).To(Equal([]out.LintIssue{
+			{Line: 1, Path: "inherit", Message: "is not supported by cf CLI v7 or later; use additional_manifests or ops_files instead"},
+			{Line: 6, Path: "applications[0].host", Message: "is deprecated, use routes", Fixable: true},
+			{Line: 7, Path: "applications[0].domains", Message: "is deprecated, use routes", Fixable: true},
+			{Line: 5, Path: "applications[0].buildpack", Message: "is deprecated, use buildpacks", Fixable: true},
+			{Line: 4, Path: "applications[0].instances", Message: "is 1, so the app is down whenever its only instance restarts, despite the zero downtime deploy"},
+			{Line: 13, Path: "applications[1].domain", Message: "is deprecated, use routes", Fixable: true},
+			{Line: 12, Path: "applications[1].no-hostname", Message: "is deprecated, use routes", Fixable: true},
+			{Line: 11, Path: "applications[1].instances", Message: "is not set and defaults to 1, so the app is down whenever its only instance restarts, despite the zero downtime deploy"},
+			{Line: 11, Path: "applications[1]", Message: "has no health-check-type; set it to port, http or process so a broken app isn't reported as running"},
+			{Line: 17, Path: "applications[2].host", Message: "is deprecated, use routes"},
+			{Line: 16, Path: "applications[2].instances", Message: "is not set and defaults to 1, so the app is down whenever its only instance restarts, despite the zero downtime deploy"},
+		}))
+	})
+
+	It("reads instances and health-check-type from the web process", func() {
+		file, err := ioutil.TempFile("", "lint_test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString("applications:\n- name: web\n  processes:\n  - type: web\n    instances: 2\n    health-check-type: http\n  - type: worker\n    instances: 1\n")
+		Expect(err).NotTo(HaveOccurred())
+		file.Close()
+
+		manifest, err := out.NewManifest(file.Name())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Lint(true)).To(BeEmpty())
+	})
+
+	It("only warns about a single instance for zero downtime deploys", func() {
+		for _, issue := range manifest.Lint(false) {
+			Expect(issue.Path).NotTo(MatchRegexp(`\.instances$`))
+		}
+	})
+
+	It("rewrites deprecated attributes to routes and buildpacks", func() {
+		manifest.FixDeprecated()
+
+		tempPath, err := manifest.SaveTemp("assets/deprecatedManifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(tempPath)
+
+		contents, err := ioutil.ReadFile(tempPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal(`inherit: base-manifest.yml
+applications:
+  - name: web
+    instances: 1
+    buildpacks:
+      - go_buildpack
+    routes:
+      - route: web.example.com
+      - route: web.example.org
+    health-check-type: http
+  - name: worker
+    routes:
+      - route: worker.example.net
+      - route: worker.example.com
+  - name: default-domain
+    host: legacy
+    health-check-type: process
+`))
+	})
+})
@@ -0,0 +1,279 @@
+package out
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+const (
+	LockWait = "wait"
+	LockFail = "fail"
+
+	DefaultLockTimeout = 10 * time.Minute
+	DefaultLockTTL     = time.Hour
+
+	// releaseTimeout bounds releasing the locks, which goes ahead even when
+	// the build was aborted
+	releaseTimeout = time.Minute
+
+	lockPrefix = "cf-resource.concourse-ci.org/lock-"
+)
+
+// annotation names are at most 63 characters, alphanumeric at either end
+var lockName = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]{0,56}[A-Za-z0-9])?$`)
+
+// deployLock is the value of a lock annotation.
+type deployLock struct {
+	App     string    `json:"app"`
+	Owner   string    `json:"owner"`
+	ID      string    `json:"id"`
+	Expires time.Time `json:"expires"`
+}
+
+// LockError is returned when an app is locked by another deploy.
+type LockError struct {
+	App     string
+	Owner   string
+	Expires time.Time
+}
+
+func (e *LockError) Error() string {
+	return fmt.Sprintf("%s is being deployed by %s, whose lock expires at %s", e.App, e.Owner, e.Expires.UTC().Format(time.RFC3339))
+}
+
+// lockKey is the space annotation that holds the lock for app. The lock is
+// kept on the space rather than the app, since a zero downtime deploy
+// renames and deletes the app.
+func lockKey(app string) string {
+	if lockName.MatchString(app) {
+		return lockPrefix + app
+	}
+
+	sum := sha256.Sum256([]byte(app))
+	return lockPrefix + hex.EncodeToString(sum[:8])
+}
+
+// lock takes the deploy lock for each app, waiting for other deploys with
+// lock: wait or failing with lock: fail. The returned function releases the
+// locks that were taken, even when taking the rest failed.
+func (command *Command) lock(apps []string, mode string, timeout time.Duration, ttl time.Duration) (func(), error) {
+	release := func() {}
+
+	id, err := lockID()
+	if err != nil {
+		return release, err
+	}
+	owner := lockOwner()
+
+	// always lock in the same order, so two deploys can't each hold what
+	// the other is waiting for
+	sorted := append([]string(nil), apps...)
+	sort.Strings(sorted)
+
+	var held []string
+	release = func() {
+		// an aborted build still releases its locks, or they block every
+		// other put until they expire
+		paas, cancel := command.paas.Detached(releaseTimeout)
+		defer cancel()
+		releasing := NewCommand(paas, command.log)
+
+		for _, app := range held {
+			if err := releasing.unlock(app, id); err != nil {
+				fmt.Fprintf(command.log, "warning: could not release the lock on %s, it expires on its own: %s\n", app, err)
+			}
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for _, app := range sorted {
+		ours := deployLock{App: app, Owner: owner, ID: id}
+		if err := command.acquire(&ours, mode, deadline, ttl); err != nil {
+			return release, err
+		}
+		held = append(held, app)
+	}
+
+	return release, nil
+}
+
+func (command *Command) acquire(ours *deployLock, mode string, deadline time.Time, ttl time.Duration) error {
+	key := lockKey(ours.App)
+	waiting := false
+
+	for {
+		current, err := command.readLock(key)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if current == nil || current.ID == ours.ID || !now.Before(current.Expires) {
+			ours.Expires = now.Add(ttl)
+			if err := command.writeLock(key, ours); err != nil {
+				return err
+			}
+
+			// annotations can't be compared and swapped, so read the lock
+			// back in case another deploy wrote it at the same time
+			current, err = command.readLock(key)
+			if err != nil {
+				return err
+			}
+			if current != nil && current.ID == ours.ID {
+				fmt.Fprintf(command.log, "Locked %s until %s\n", ours.App, ours.Expires.UTC().Format(time.RFC3339))
+				return nil
+			}
+			if current == nil {
+				continue
+			}
+		}
+
+		lockErr := &LockError{App: ours.App, Owner: current.Owner, Expires: current.Expires}
+		if mode != LockWait || !now.Before(deadline) {
+			return lockErr
+		}
+
+		if !waiting {
+			fmt.Fprintf(command.log, "Waiting for the lock on %s: %s\n", ours.App, lockErr)
+			waiting = true
+		}
+
+		// no need to wait past the other deploy's expiry, or the deadline
+		sleepUntil(current.Expires, deadline)
+	}
+}
+
+// unlock removes the lock, unless another deploy has taken it over since
+// it expired.
+func (command *Command) unlock(app string, id string) error {
+	key := lockKey(app)
+
+	current, err := command.readLock(key)
+	if err != nil || current == nil || current.ID != id {
+		return err
+	}
+
+	if err := command.paas.SetSpaceAnnotation(key, ""); err != nil {
+		return err
+	}
+	fmt.Fprintf(command.log, "Released the lock on %s\n", app)
+	return nil
+}
+
+func (command *Command) readLock(key string) (*deployLock, error) {
+	value, err := command.paas.SpaceAnnotation(key)
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	var current deployLock
+	if err := json.Unmarshal([]byte(value), &current); err != nil {
+		return nil, fmt.Errorf("invalid lock annotation %s: %s", key, err)
+	}
+	return &current, nil
+}
+
+func (command *Command) writeLock(key string, lock *deployLock) error {
+	value, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	return command.paas.SetSpaceAnnotation(key, string(value))
+}
+
+// lockOwner describes this deploy to the deploys that wait for it.
+func lockOwner() string {
+	b := currentBuild()
+
+	var owner string
+	switch {
+	case b.Job != "":
+		owner = b.Pipeline + "/" + b.Job + " #" + b.Name
+	case b.ID != "":
+		owner = "build " + b.ID
+	default:
+		hostname, _ := os.Hostname()
+		return fmt.Sprintf("%s (pid %d)", hostname, os.Getpid())
+	}
+
+	if b.URL != "" {
+		owner += " (" + b.URL + ")"
+	}
+	return owner
+}
+
+func lockID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+type v3Space struct {
+	Metadata struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+// SpaceAnnotation returns the value of an annotation on the targeted space,
+// or "" when it isn't set.
+func (cf *CloudFoundry) SpaceAnnotation(key string) (string, error) {
+	guid, err := cf.spaceGUID()
+	if err != nil {
+		return "", err
+	}
+
+	var space v3Space
+	if err := cf.curl("/v3/spaces/"+guid, &space); err != nil {
+		return "", err
+	}
+	return space.Metadata.Annotations[key], nil
+}
+
+// SetSpaceAnnotation sets an annotation on the targeted space, or removes
+// it when value is "".
+func (cf *CloudFoundry) SetSpaceAnnotation(key string, value string) error {
+	guid, err := cf.spaceGUID()
+	if err != nil {
+		return err
+	}
+
+	var annotation interface{}
+	if value != "" {
+		annotation = value
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{key: annotation},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return cf.curl("/v3/spaces/"+guid, nil, "-X", "PATCH", "-d", string(body))
+}
+
+func (cf *CloudFoundry) spaceGUID() (string, error) {
+	if cf.space == "" {
+		return "", fmt.Errorf("no space targeted")
+	}
+
+	result, err := cf.runner.Run(cli.Quietly(cf.ctx), "space", cf.space, "--guid")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
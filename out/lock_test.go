@@ -0,0 +1,272 @@
+package out_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+const lockKey = "cf-resource.concourse-ci.org/lock-"
+
+func otherLock(app string, expires time.Time) string {
+	value, _ := json.Marshal(map[string]interface{}{
+		"app":     app,
+		"owner":   "other-pipeline/deploy #7",
+		"id":      "other",
+		"expires": expires,
+	})
+	return string(value)
+}
+
+var _ = Describe("Deploy lock", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		annotations  map[string]string
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		command = out.NewCommand(cloudFoundry, GinkgoWriter)
+
+		annotations = map[string]string{}
+		cloudFoundry.SpaceAnnotationStub = func(key string) (string, error) {
+			return annotations[key], nil
+		}
+		cloudFoundry.SetSpaceAnnotationStub = func(key string, value string) error {
+			if value == "" {
+				delete(annotations, key)
+			} else {
+				annotations[key] = value
+			}
+			return nil
+		}
+
+		cloudFoundry.DetachedReturns(cloudFoundry, func() {})
+
+		request = newRequest()
+		request.Params.Lock = out.LockFail
+	})
+
+	It("holds a lock on every pushed app during the push", func() {
+		cloudFoundry.PushAppStub = func(_ string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+			Expect(annotations).To(HaveKey(lockKey + "app1"))
+			Expect(annotations).To(HaveKey(lockKey + "app2"))
+			return out.PushResult{}, nil
+		}
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(1))
+		Expect(annotations).To(BeEmpty())
+
+		durations := response.Metadata[len(response.Metadata)-1]
+		Expect(durations.Value).To(ContainSubstring("lock "))
+	})
+
+	It("releases the locks even when the build is aborted during the push", func() {
+		aborted := false
+		detached := &outfakes.FakePAAS{}
+		detached.SpaceAnnotationStub = cloudFoundry.SpaceAnnotationStub
+		detached.SetSpaceAnnotationStub = cloudFoundry.SetSpaceAnnotationStub
+		cloudFoundry.DetachedReturns(detached, func() {})
+
+		spaceAnnotation, setSpaceAnnotation := cloudFoundry.SpaceAnnotationStub, cloudFoundry.SetSpaceAnnotationStub
+		cloudFoundry.SpaceAnnotationStub = func(key string) (string, error) {
+			if aborted {
+				return "", context.Canceled
+			}
+			return spaceAnnotation(key)
+		}
+		cloudFoundry.SetSpaceAnnotationStub = func(key string, value string) error {
+			if aborted {
+				return context.Canceled
+			}
+			return setSpaceAnnotation(key, value)
+		}
+		cloudFoundry.PushAppStub = func(_ string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+			aborted = true
+			return out.PushResult{}, context.Canceled
+		}
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(annotations).To(BeEmpty())
+		Expect(detached.SetSpaceAnnotationCallCount()).To(Equal(2))
+	})
+
+	It("fails without pushing when another deploy holds the lock", func() {
+		held := otherLock("app2", time.Now().Add(time.Hour))
+		annotations[lockKey+"app2"] = held
+
+		_, err := command.Run(request)
+		Expect(err).To(BeAssignableToTypeOf(&out.LockError{}))
+		Expect(err.Error()).To(MatchRegexp("^app2 is being deployed by other-pipeline/deploy #7, whose lock expires at "))
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+
+		// the lock on app1 is released and the other deploy's is untouched
+		Expect(annotations).To(Equal(map[string]string{lockKey + "app2": held}))
+	})
+
+	It("waits for another deploy's lock with lock: wait", func() {
+		request.Params.Lock = out.LockWait
+		annotations[lockKey+"app1"] = otherLock("app1", time.Now().Add(200*time.Millisecond))
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(1))
+		Expect(annotations).To(BeEmpty())
+	})
+
+	It("gives up waiting after lock_timeout", func() {
+		request.Params.Lock = out.LockWait
+		request.Params.LockTimeout = "100ms"
+		annotations[lockKey+"app1"] = otherLock("app1", time.Now().Add(time.Hour))
+
+		_, err := command.Run(request)
+		Expect(err).To(BeAssignableToTypeOf(&out.LockError{}))
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+	})
+
+	It("takes over an expired lock", func() {
+		annotations[lockKey+"app1"] = otherLock("app1", time.Now().Add(-time.Minute))
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(1))
+		Expect(annotations).To(BeEmpty())
+	})
+
+	It("releases the lock when the deploy is rolled back", func() {
+		request.Params.CurrentAppName = "app1"
+		cloudFoundry.PushAppReturns(out.PushResult{Strategy: "zdt", RolledBack: true}, errors.New("app crashed"))
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("app crashed"))
+		Expect(annotations).To(BeEmpty())
+	})
+
+	It("doesn't remove a lock another deploy took over", func() {
+		taken := otherLock("app1", time.Now().Add(time.Hour))
+		cloudFoundry.PushAppStub = func(_ string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+			annotations[lockKey+"app1"] = taken
+			return out.PushResult{}, nil
+		}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(annotations).To(Equal(map[string]string{lockKey + "app1": taken}))
+	})
+
+	It("uses a hash for app names that can't be annotation keys", func() {
+		request.Params.ManifestPath = "assets/varsManifest.yml"
+		request.Params.Vars = map[string]interface{}{
+			"app_name":  "my app",
+			"instances": 2,
+			"name":      "world",
+			"service":   "db",
+			"db":        map[string]interface{}{"password": "s3cret"},
+		}
+		cloudFoundry.PushAppStub = func(_ string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+			Expect(annotations).To(HaveLen(1))
+			for key := range annotations {
+				Expect(key).To(MatchRegexp("^" + lockKey + "[0-9a-f]{16}$"))
+			}
+			return out.PushResult{}, nil
+		}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("doesn't lock unless asked to", func() {
+		request.Params.Lock = ""
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.SpaceAnnotationCallCount()).To(Equal(0))
+		Expect(cloudFoundry.SetSpaceAnnotationCallCount()).To(Equal(0))
+	})
+
+	It("rejects an unknown lock mode", func() {
+		request.Params.Lock = "sometimes"
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(`unknown lock "sometimes", expected "wait" or "fail"`))
+	})
+})
+
+var _ = Describe("Space annotations", func() {
+	var (
+		runner       *clifakes.FakeRunner
+		cloudFoundry *out.CloudFoundry
+	)
+
+	BeforeEach(func() {
+		runner = &clifakes.FakeRunner{}
+		cloudFoundry = out.NewCloudFoundry(context.Background(), runner)
+
+		runner.RunStub = func(ctx context.Context, args ...string) (cli.Result, error) {
+			switch args[0] {
+			case "space":
+				return cli.Result{Stdout: "space-guid\n"}, nil
+			case "curl":
+				return cli.Result{Stdout: `{"metadata":{"annotations":{"a":"b"}}}`}, nil
+			}
+			return cli.Result{}, nil
+		}
+
+		Expect(cloudFoundry.Target("org", "my-space")).To(BeNil())
+	})
+
+	It("reads annotations on the targeted space", func() {
+		value, err := cloudFoundry.SpaceAnnotation("a")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal("b"))
+
+		Expect(commands(runner)).To(Equal([]string{
+			"cf target -o org -s my-space",
+			"cf space my-space --guid",
+			"cf curl /v3/spaces/space-guid",
+		}))
+	})
+
+	It("still runs commands on a detached CloudFoundry once the build is aborted", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		aborted := out.NewCloudFoundry(ctx, runner)
+		Expect(aborted.Target("org", "my-space")).To(BeNil())
+		cancel()
+
+		runner.RunStub = func(ctx context.Context, args ...string) (cli.Result, error) {
+			return cli.Result{}, ctx.Err()
+		}
+		Expect(aborted.SetSpaceAnnotation("a", "")).To(MatchError(context.Canceled))
+
+		detached, stop := aborted.Detached(time.Minute)
+		defer stop()
+		Expect(detached.SetSpaceAnnotation("a", "")).To(BeNil())
+	})
+
+	It("sets and removes annotations", func() {
+		Expect(cloudFoundry.SetSpaceAnnotation("a", "c")).To(BeNil())
+		Expect(cloudFoundry.SetSpaceAnnotation("a", "")).To(BeNil())
+
+		Expect(commands(runner)).To(Equal([]string{
+			"cf target -o org -s my-space",
+			"cf space my-space --guid",
+			`cf curl /v3/spaces/space-guid -X PATCH -d {"metadata":{"annotations":{"a":"c"}}}`,
+			"cf space my-space --guid",
+			`cf curl /v3/spaces/space-guid -X PATCH -d {"metadata":{"annotations":{"a":null}}}`,
+		}))
+	})
+})
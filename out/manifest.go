@@ -1,12 +1,23 @@
 package out
 
 import (
-	"gopkg.in/yaml.v2"
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
+// Manifest keeps the parsed YAML nodes rather than plain maps, so that
+// comments, key order and anchors survive being written back out.
 type Manifest struct {
-	data map[interface{}]interface{}
+	document *yaml.Node
 }
 
 func NewManifest(manifestPath string) (Manifest, error) {
@@ -15,50 +26,539 @@ func NewManifest(manifestPath string) (Manifest, error) {
 		return Manifest{}, err
 	}
 
-	var manifest Manifest
-	err = yaml.Unmarshal(yamlData, &manifest.data)
+	var document yaml.Node
+	err = yaml.Unmarshal(yamlData, &document)
 	if err != nil {
 		return Manifest{}, err
 	}
 
+	manifest := Manifest{document: &document}
+	if root := manifest.root(); root != nil && root.Kind != yaml.MappingNode {
+		return Manifest{}, fmt.Errorf("%s: expected a YAML mapping at the top level", manifestPath)
+	}
+
 	return manifest, nil
 }
 
-func (manifest *Manifest) EnvironmentVariables() []map[interface{}]interface{} {
-	apps, hasApps := manifest.data["applications"].([]interface{})
-	if !hasApps {
-		return []map[interface{}]interface{}{}
+// root is the top level mapping, or nil for an empty manifest.
+func (manifest *Manifest) root() *yaml.Node {
+	if manifest.document == nil || len(manifest.document.Content) == 0 {
+		return nil
 	}
-	appEnvVars := make([]map[interface{}]interface{}, len(apps))
-	for appIdx := range apps {
-		app, isApp := apps[appIdx].(map[interface{}]interface{})
-		if !isApp {
-			continue
+	return manifest.document.Content[0]
+}
+
+// apps returns the mapping node of each entry in `applications`.
+func (manifest *Manifest) apps() []*yaml.Node {
+	applications := mappingValue(manifest.root(), "applications")
+	if applications == nil || applications.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	var apps []*yaml.Node
+	for _, app := range applications.Content {
+		if app.Kind == yaml.MappingNode {
+			apps = append(apps, app)
 		}
-		envVars, hasEnvVars := app["env"].(map[interface{}]interface{})
-		if !hasEnvVars {
-			envVars = make(map[interface{}]interface{})
-			app["env"] = envVars
+	}
+	return apps
+}
+
+func (manifest *Manifest) EnvironmentVariables() []map[string]interface{} {
+	apps := manifest.apps()
+
+	appEnvVars := make([]map[string]interface{}, len(apps))
+	for appIdx, app := range apps {
+		appEnvVars[appIdx] = map[string]interface{}{}
+		if env := mappingValue(app, "env"); env != nil {
+			_ = env.Decode(&appEnvVars[appIdx])
 		}
-		appEnvVars[appIdx] = envVars
 	}
 	return appEnvVars
 }
 
 func (manifest *Manifest) AddEnvironmentVariable(name, value string) {
-	appEnvVars := manifest.EnvironmentVariables()
-	for appIdx := range appEnvVars {
-		if appEnvVars[appIdx] != nil {
-			appEnvVars[appIdx][name] = value
+	for _, app := range manifest.apps() {
+		addEnvironmentVariable(app, name, value)
+	}
+}
+
+// AddAppEnvironmentVariable sets the variable on the named app only. It
+// returns false when the manifest has no such app.
+func (manifest *Manifest) AddAppEnvironmentVariable(appName, name, value string) bool {
+	for _, app := range manifest.apps() {
+		if appNode := mappingValue(app, "name"); appNode != nil && appNode.Value == appName {
+			addEnvironmentVariable(app, name, value)
+			return true
 		}
 	}
+	return false
+}
+
+func addEnvironmentVariable(app *yaml.Node, name, value string) {
+	env := mappingValue(app, "env")
+	if env == nil || env.Kind != yaml.MappingNode {
+		env = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(app, "env", env)
+	}
+
+	setMappingValue(env, name, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
 }
 
 func (manifest *Manifest) Save(manifestPath string) error {
-	data, err := yaml.Marshal(manifest.data)
+	var buffer bytes.Buffer
+
+	if manifest.root() != nil {
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(manifest.document); err != nil {
+			return err
+		}
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(manifestPath, buffer.Bytes(), 0644)
+}
+
+// SaveTemp writes the manifest to a new temporary file, leaving the one it
+// was read from untouched, and returns its path. Relative app paths are made
+// absolute since cf push resolves them against the manifest's directory.
+func (manifest *Manifest) SaveTemp(originalPath string) (string, error) {
+	if err := manifest.resolvePaths(originalPath); err != nil {
+		return "", err
+	}
+
+	tempFile, err := ioutil.TempFile("", "cf-resource-manifest-*.yml")
+	if err != nil {
+		return "", err
+	}
+	tempFile.Close()
+
+	if err := manifest.Save(tempFile.Name()); err != nil {
+		os.Remove(tempFile.Name())
+		return "", err
+	}
+
+	return tempFile.Name(), nil
+}
+
+// resolvePaths makes relative app paths absolute, resolving them against the
+// directory of the manifest at manifestPath.
+func (manifest *Manifest) resolvePaths(manifestPath string) error {
+	dir, err := filepath.Abs(filepath.Dir(manifestPath))
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(manifestPath, data, 0644)
+	for _, app := range manifest.apps() {
+		path := mappingValue(app, "path")
+		if path != nil && path.Kind == yaml.ScalarNode && path.Value != "" && !filepath.IsAbs(path.Value) {
+			path.Value = filepath.Join(dir, path.Value)
+		}
+	}
+	return nil
+}
+
+// Merge merges the manifest read from manifestPath over this one. Maps are
+// merged key by key and apps by name; any other value, lists included, is
+// replaced. Relative app paths are resolved against each manifest's own
+// directory.
+func (manifest *Manifest) Merge(manifestPath string) error {
+	other, err := NewManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	if other.root() == nil {
+		return nil
+	}
+
+	if err := other.resolvePaths(manifestPath); err != nil {
+		return err
+	}
+
+	if manifest.root() == nil {
+		manifest.document = other.document
+		return nil
+	}
+
+	mergeNodes(manifest.root(), other.root())
+	return nil
+}
+
+func mergeNodes(base *yaml.Node, overlay *yaml.Node) {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i].Value, overlay.Content[i+1]
+		existing := mappingValue(base, key)
+
+		switch {
+		case existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNodes(existing, value)
+		case existing != nil && key == "applications" && existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode:
+			mergeApps(existing, value)
+		default:
+			setMappingValue(base, key, value)
+		}
+	}
+}
+
+func mergeApps(base *yaml.Node, overlay *yaml.Node) {
+	for _, app := range overlay.Content {
+		name := mappingValue(app, "name")
+
+		merged := false
+		for _, existing := range base.Content {
+			existingName := mappingValue(existing, "name")
+			if name != nil && existingName != nil && existingName.Value == name.Value {
+				mergeNodes(existing, app)
+				merged = true
+				break
+			}
+		}
+
+		if !merged {
+			base.Content = append(base.Content, app)
+		}
+	}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
+// ManifestApp is a read-only view of one entry in `applications`.
+type ManifestApp struct {
+	Name            string
+	Instances       *int
+	Memory          string
+	DiskQuota       string
+	Buildpacks      []string
+	Stack           string
+	Env             map[string]string
+	Routes          []string
+	Services        []string
+	NoRoute         bool
+	HealthCheckType string
+	Processes       []ManifestProcess
+}
+
+// ManifestProcess is a read-only view of one entry in an app's `processes`.
+type ManifestProcess struct {
+	Type            string
+	Instances       *int
+	Memory          string
+	HealthCheckType string
+}
+
+func (manifest *Manifest) Applications() []ManifestApp {
+	manifestApps := []ManifestApp{}
+	for _, appNode := range manifest.apps() {
+		var app map[string]interface{}
+		if err := appNode.Decode(&app); err != nil {
+			continue
+		}
+
+		manifestApp := ManifestApp{
+			Name:            stringValue(app["name"]),
+			Memory:          stringValue(app["memory"]),
+			DiskQuota:       stringValue(app["disk_quota"]),
+			Stack:           stringValue(app["stack"]),
+			HealthCheckType: stringValue(app["health-check-type"]),
+			Env:             map[string]string{},
+		}
+
+		if instances, ok := intValue(app["instances"]); ok {
+			manifestApp.Instances = &instances
+		}
+
+		if noRoute, ok := app["no-route"].(bool); ok {
+			manifestApp.NoRoute = noRoute
+		}
+
+		if buildpack := stringValue(app["buildpack"]); buildpack != "" {
+			manifestApp.Buildpacks = []string{buildpack}
+		}
+		for _, buildpack := range listValue(app["buildpacks"]) {
+			manifestApp.Buildpacks = append(manifestApp.Buildpacks, stringValue(buildpack))
+		}
+
+		if env, ok := app["env"].(map[string]interface{}); ok {
+			for key, value := range env {
+				manifestApp.Env[key] = envString(value)
+			}
+		}
+
+		for _, rawRoute := range listValue(app["routes"]) {
+			if route, ok := rawRoute.(map[string]interface{}); ok {
+				manifestApp.Routes = append(manifestApp.Routes, stringValue(route["route"]))
+			}
+		}
+
+		for _, rawService := range listValue(app["services"]) {
+			if service, ok := rawService.(map[string]interface{}); ok {
+				rawService = service["name"]
+			}
+			manifestApp.Services = append(manifestApp.Services, stringValue(rawService))
+		}
+
+		for _, rawProcess := range listValue(app["processes"]) {
+			process, ok := rawProcess.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			manifestProcess := ManifestProcess{
+				Type:            stringValue(process["type"]),
+				Memory:          stringValue(process["memory"]),
+				HealthCheckType: stringValue(process["health-check-type"]),
+			}
+			if instances, ok := intValue(process["instances"]); ok {
+				manifestProcess.Instances = &instances
+			}
+			manifestApp.Processes = append(manifestApp.Processes, manifestProcess)
+		}
+
+		manifestApps = append(manifestApps, manifestApp)
+	}
+
+	return manifestApps
+}
+
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// intValue also accepts whole floats, which is how numbers in JSON params
+// arrive.
+func intValue(value interface{}) (int, bool) {
+	switch typed := value.(type) {
+	case int:
+		return typed, true
+	case float64:
+		if typed == float64(int(typed)) {
+			return int(typed), true
+		}
+	}
+	return 0, false
+}
+
+func listValue(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+var variablePattern = regexp.MustCompile(`\(\(([-/\.\w]+)\)\)`)
+
+// Interpolate replaces ((variables)) in the manifest the same way `cf push`
+// does with --var and --vars-file, vars taking precedence over vars files.
+// It fails on variables that can't be resolved, listing every one of them.
+func (manifest *Manifest) Interpolate(vars map[string]interface{}, varsFiles []string) error {
+	values, err := readVars(vars, varsFiles)
+	if err != nil {
+		return err
+	}
+
+	var unresolved []string
+	if err := interpolate(manifest.root(), values, &unresolved); err != nil {
+		return err
+	}
+
+	if len(unresolved) > 0 {
+		return fmt.Errorf("unresolved variables in manifest: %s; set them in vars or vars_files", strings.Join(unresolved, ", "))
+	}
+	return nil
+}
+
+// readVars merges the vars_files, in order, and then vars into the values
+// that variables are interpolated with.
+func readVars(vars map[string]interface{}, varsFiles []string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	for _, varsFile := range varsFiles {
+		yamlData, err := ioutil.ReadFile(varsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		var fileValues map[string]interface{}
+		if err := yaml.Unmarshal(yamlData, &fileValues); err != nil {
+			return nil, fmt.Errorf("reading vars file %s: %s", varsFile, err)
+		}
+
+		for name, value := range fileValues {
+			values[name] = value
+		}
+	}
+
+	for name, value := range vars {
+		values[name] = yamlValue(value)
+	}
+
+	return values, nil
+}
+
+// interpolateString replaces the variables in a template, such as a
+// templated app name, adding the ones it can't resolve to unresolved.
+func interpolateString(template string, values map[string]interface{}, unresolved *[]string) (string, error) {
+	node := yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: template}
+	if err := interpolate(&node, values, unresolved); err != nil {
+		return "", err
+	}
+	return node.Value, nil
+}
+
+// yamlValue turns json.Numbers, which YAML would write out as strings, back
+// into ints and floats.
+func yamlValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case json.Number:
+		if i, err := typed.Int64(); err == nil {
+			return i
+		}
+		if f, err := typed.Float64(); err == nil {
+			return f
+		}
+		return typed.String()
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			converted[key] = yamlValue(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(typed))
+		for i, item := range typed {
+			converted[i] = yamlValue(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// interpolate replaces the variables in node and its children, adding the
+// ones it can't resolve to unresolved.
+func interpolate(node *yaml.Node, values map[string]interface{}, unresolved *[]string) error {
+	if node == nil {
+		return nil
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		for _, child := range node.Content {
+			if err := interpolate(child, values, unresolved); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// a value that is only a variable takes the variable's type
+	if match := variablePattern.FindStringSubmatch(node.Value); match != nil && match[0] == node.Value {
+		value, found := lookupVariable(values, match[1])
+		if !found {
+			addUnresolved(unresolved, match[0])
+			return nil
+		}
+
+		var replacement yaml.Node
+		if err := replacement.Encode(value); err != nil {
+			return err
+		}
+		replacement.HeadComment = node.HeadComment
+		replacement.LineComment = node.LineComment
+		replacement.Line = node.Line
+		replacement.Column = node.Column
+		*node = replacement
+		return nil
+	}
+
+	node.Value = variablePattern.ReplaceAllStringFunc(node.Value, func(variable string) string {
+		name := variablePattern.FindStringSubmatch(variable)[1]
+		if value, found := lookupVariable(values, name); found {
+			return stringValue(value)
+		}
+		addUnresolved(unresolved, variable)
+		return variable
+	})
+	return nil
+}
+
+func addUnresolved(unresolved *[]string, variable string) {
+	for _, existing := range *unresolved {
+		if existing == variable {
+			return
+		}
+	}
+	*unresolved = append(*unresolved, variable)
+}
+
+// lookupVariable resolves a possibly dotted variable name such as
+// ((db.password)).
+func lookupVariable(values map[string]interface{}, name string) (interface{}, bool) {
+	var current interface{} = values
+
+	for _, part := range strings.Split(name, ".") {
+		typed, isMap := current.(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+
+		value, found := typed[part]
+		if !found {
+			return nil, false
+		}
+		current = value
+	}
+
+	return current, true
+}
+
+var sizePattern = regexp.MustCompile(`(?i)^\s*(\d+)\s*(M|MB|G|GB|T|TB)\s*$`)
+
+// megabytes converts a manifest size such as "512M" or "1G" into megabytes.
+func megabytes(size string) (int, error) {
+	match := sizePattern.FindStringSubmatch(size)
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %q, use a number followed by M, MB, G, GB, T or TB", size)
+	}
+
+	value, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToUpper(match[2]) {
+	case "G", "GB":
+		value *= 1024
+	case "T", "TB":
+		value *= 1024 * 1024
+	}
+
+	return value, nil
 }
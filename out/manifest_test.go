@@ -1,12 +1,16 @@
 package out_test
 
 import (
+	"encoding/json"
+
 	"github.com/concourse/cf-resource/out"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
 var _ = Describe("Manifest", func() {
@@ -53,6 +57,70 @@ var _ = Describe("Manifest", func() {
 		})
 	})
 
+	Context("saving a copy to push", func() {
+		var (
+			dir          string
+			manifestPath string
+			original     string
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "manifest_test")
+			Expect(err).NotTo(HaveOccurred())
+
+			original = `# deployed by concourse
+applications:
+- name: app1 # the web app
+  path: ../build/app.jar
+  env:
+    # keep in sync with the worker
+    QUEUE: jobs
+`
+			manifestPath = filepath.Join(dir, "manifest.yml")
+			err = ioutil.WriteFile(manifestPath, []byte(original), 0644)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("leaves the original untouched and keeps its comments", func() {
+			manifest, err := out.NewManifest(manifestPath)
+			Expect(err).NotTo(HaveOccurred())
+			manifest.AddEnvironmentVariable("MANIFEST_TEST_A", "manifest_test_a")
+
+			tempPath, err := manifest.SaveTemp(manifestPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer os.Remove(tempPath)
+
+			contents, err := ioutil.ReadFile(manifestPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(original))
+
+			contents, err = ioutil.ReadFile(tempPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("# deployed by concourse"))
+			Expect(string(contents)).To(ContainSubstring("- name: app1 # the web app"))
+			Expect(string(contents)).To(ContainSubstring("# keep in sync with the worker"))
+			Expect(string(contents)).To(ContainSubstring("MANIFEST_TEST_A: manifest_test_a"))
+		})
+
+		It("makes relative app paths absolute", func() {
+			manifest, err := out.NewManifest(manifestPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			tempPath, err := manifest.SaveTemp(manifestPath)
+			Expect(err).NotTo(HaveOccurred())
+			defer os.Remove(tempPath)
+
+			contents, err := ioutil.ReadFile(tempPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("path: " + filepath.Join(filepath.Dir(dir), "build", "app.jar")))
+		})
+	})
+
 	Context("invalid manifest path", func() {
 		It("returns an error", func() {
 			_, err := out.NewManifest("invalid path")
@@ -62,7 +130,98 @@ var _ = Describe("Manifest", func() {
 
 	Context("invalid manifest YAML", func() {
 		It("returns an error", func() {
-			_, err := out.NewManifest("invalidManifest.yml")
+			_, err := out.NewManifest("assets/invalidManifest.yml")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("Manifest applications", func() {
+	It("reads each application", func() {
+		manifest, err := out.NewManifest("assets/manifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+
+		apps := manifest.Applications()
+		Expect(apps).To(HaveLen(2))
+		Expect(apps[0].Name).To(Equal("app1"))
+		Expect(apps[0].Env).To(Equal(map[string]string{
+			"MANIFEST_A": "manifest_a",
+			"MANIFEST_B": "manifest_b",
+		}))
+		Expect(apps[0].Instances).To(BeNil())
+		Expect(apps[1].Name).To(Equal("app2"))
+	})
+
+	Describe("interpolating variables", func() {
+		var manifest out.Manifest
+
+		BeforeEach(func() {
+			var err error
+			manifest, err = out.NewManifest("assets/varsManifest.yml")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("uses vars and vars files, with vars taking precedence", func() {
+			err := manifest.Interpolate(
+				map[string]interface{}{"app_name": "from-vars", "instances": float64(3), "name": "world"},
+				[]string{"assets/vars.yml"},
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			app := manifest.Applications()[0]
+			Expect(app.Name).To(Equal("from-vars"))
+			Expect(*app.Instances).To(Equal(3))
+			Expect(app.Env["GREETING"]).To(Equal("hello world"))
+			Expect(app.Env["DB_PASSWORD"]).To(Equal("s3cret"))
+			Expect(app.Routes).To(Equal([]string{"from-vars.example.com"}))
+			Expect(app.Services).To(Equal([]string{"database"}))
+		})
+
+		It("keeps the type of JSON numbers", func() {
+			err := manifest.Interpolate(
+				map[string]interface{}{"instances": json.Number("3"), "name": "world"},
+				[]string{"assets/vars.yml"},
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(*manifest.Applications()[0].Instances).To(Equal(3))
+		})
+
+		It("round trips nested vars", func() {
+			err := manifest.Interpolate(
+				map[string]interface{}{
+					"instances": json.Number("3"),
+					"name":      "world",
+					"db":        map[string]interface{}{"password": true},
+					"service":   []interface{}{"a", json.Number("1")},
+				},
+				[]string{"assets/vars.yml"},
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			tempPath, err := manifest.SaveTemp("assets/varsManifest.yml")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.Remove(tempPath)
+
+			contents, err := ioutil.ReadFile(tempPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			var saved struct {
+				Applications []map[string]interface{}
+			}
+			Expect(yaml.Unmarshal(contents, &saved)).To(BeNil())
+			Expect(saved.Applications[0]["instances"]).To(Equal(3))
+			Expect(saved.Applications[0]["env"]).To(HaveKeyWithValue("DB_PASSWORD", true))
+			Expect(saved.Applications[0]["services"]).To(Equal([]interface{}{[]interface{}{"a", 1}}))
+		})
+
+		It("fails on variables it can't resolve, naming all of them", func() {
+			err := manifest.Interpolate(map[string]interface{}{"name": "world"}, nil)
+			Expect(err).To(MatchError("unresolved variables in manifest: ((app_name)), ((instances)), ((db.password)), ((service)); set them in vars or vars_files"))
+		})
+
+		It("fails when a vars file can't be read", func() {
+			err := manifest.Interpolate(nil, []string{"assets/missing.yml"})
 			Expect(err).To(HaveOccurred())
 		})
 	})
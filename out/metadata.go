@@ -0,0 +1,89 @@
+package out
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/concourse/cf-resource"
+)
+
+type phase struct {
+	name     string
+	duration time.Duration
+}
+
+// phases records how long each step of a put took.
+type phases struct {
+	started time.Time
+	phases  []phase
+}
+
+func newPhases() *phases {
+	return &phases{started: time.Now()}
+}
+
+func (p *phases) time(name string, f func() error) error {
+	started := time.Now()
+	err := f()
+	p.phases = append(p.phases, phase{name, time.Since(started)})
+	return err
+}
+
+func (p *phases) String() string {
+	formatted := make([]string, 0, len(p.phases)+1)
+	for _, phase := range p.phases {
+		formatted = append(formatted, fmt.Sprintf("%s %s", phase.name, roundDuration(phase.duration)))
+	}
+	formatted = append(formatted, fmt.Sprintf("total %s", roundDuration(time.Since(p.started))))
+
+	return strings.Join(formatted, ", ")
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(100 * time.Millisecond)
+}
+
+// appMetadata describes a deployed app for the Concourse UI. Env vars are
+// deliberately left out, their values are often secrets.
+func appMetadata(app App, count *InstanceCount) []resource.MetadataPair {
+	instances := strconv.Itoa(app.Instances)
+	if count != nil {
+		instances = count.String()
+	}
+
+	urls := make([]string, len(app.Routes))
+	for i, route := range app.Routes {
+		urls[i] = route
+		// tcp routes are host:port, anything else is served over http(s)
+		if !strings.Contains(route, ":") {
+			urls[i] = "https://" + route
+		}
+	}
+
+	buildpacks := app.DetectedBuildpacks
+	if len(buildpacks) == 0 {
+		buildpacks = app.Buildpacks
+	}
+
+	pairs := []resource.MetadataPair{
+		{Name: "app", Value: app.Name},
+		{Name: "guid", Value: app.GUID},
+		{Name: "urls", Value: strings.Join(urls, ", ")},
+		{Name: "droplet", Value: app.DropletGUID},
+		{Name: "buildpacks", Value: strings.Join(buildpacks, ", ")},
+		{Name: "stack", Value: app.Stack},
+		{Name: "instances", Value: instances},
+		{Name: "memory", Value: fmt.Sprintf("%dM", app.MemoryMB)},
+	}
+
+	// leave out what the app doesn't have, e.g. a droplet before staging
+	filtered := pairs[:0]
+	for _, pair := range pairs {
+		if pair.Value != "" {
+			filtered = append(filtered, pair)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,120 @@
+package out_test
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Response metadata", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		request      out.Request
+		command      *out.Command
+		log          *gbytes.Buffer
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+
+		cloudFoundry.PushAppReturns(out.PushResult{Strategy: "zdt"}, nil)
+		cloudFoundry.GetAppReturns(out.App{
+			Name:               "app1",
+			GUID:               "app-guid",
+			Instances:          2,
+			MemoryMB:           512,
+			Buildpacks:         []string{"go_buildpack"},
+			DetectedBuildpacks: []string{"go_buildpack"},
+			Stack:              "cflinuxfs3",
+			DropletGUID:        "droplet-guid",
+			Env:                map[string]string{"SECRET": "hunter2-env"},
+			Routes:             []string{"app1.example.com", "tcp.example.com:1024"},
+		}, nil)
+
+		request = newRequest()
+		request.Params.CurrentAppName = "app1"
+	})
+
+	It("describes the deployed app", func() {
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.GetAppArgsForCall(0)).To(Equal("app1"))
+
+		Expect(response.Metadata[:12]).To(Equal([]resource.MetadataPair{
+			{Name: "organization", Value: "secret"},
+			{Name: "space", Value: "volcano-base"},
+			{Name: "app", Value: "app1"},
+			{Name: "guid", Value: "app-guid"},
+			{Name: "urls", Value: "https://app1.example.com, tcp.example.com:1024"},
+			{Name: "droplet", Value: "droplet-guid"},
+			{Name: "buildpacks", Value: "go_buildpack"},
+			{Name: "stack", Value: "cflinuxfs3"},
+			{Name: "instances", Value: "2"},
+			{Name: "memory", Value: "512M"},
+			{Name: "strategy", Value: "zdt"},
+			{Name: "rolled_back", Value: "false"},
+		}))
+
+		durations := response.Metadata[12]
+		Expect(durations.Name).To(Equal("durations"))
+		Expect(durations.Value).To(MatchRegexp(`^login \S+, target \S+, push \S+, total \S+$`))
+	})
+
+	It("describes every app of a multi-app manifest", func() {
+		request.Params.CurrentAppName = ""
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.GetAppCallCount()).To(Equal(2))
+		Expect(cloudFoundry.GetAppArgsForCall(1)).To(Equal("app2"))
+
+		apps := 0
+		for _, pair := range response.Metadata {
+			if pair.Name == "app" {
+				apps++
+			}
+		}
+		Expect(apps).To(Equal(2))
+	})
+
+	It("never contains secrets", func() {
+		request.Params.DockerPassword = "docker-secret"
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, pair := range response.Metadata {
+			Expect(pair.Value).NotTo(ContainSubstring("hunter2"))
+			Expect(pair.Value).NotTo(ContainSubstring("docker-secret"))
+		}
+	})
+
+	It("doesn't fail the put when the app can't be read afterwards", func() {
+		cloudFoundry.GetAppReturns(out.App{}, errors.New("api down"))
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log).To(gbytes.Say("warning: could not read app1 for the metadata: api down"))
+		Expect(response.Metadata[2]).To(Equal(resource.MetadataPair{Name: "strategy", Value: "zdt"}))
+	})
+
+	It("says when a failed deploy was rolled back", func() {
+		cloudFoundry.PushAppReturns(out.PushResult{Strategy: "zdt", RolledBack: true}, errors.New("crashed"))
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("crashed"))
+		Expect(log).To(gbytes.Say("The zdt deploy failed and was rolled back; app1 is still running the previous version."))
+		Expect(strings.Contains(string(log.Contents()), "Durations: login")).To(BeTrue())
+	})
+})
@@ -1,6 +1,16 @@
 package out
 
-import "github.com/concourse/cf-resource"
+import (
+	"encoding/json"
+
+	"github.com/concourse/cf-resource"
+)
+
+const (
+	StrategyPlain        = "plain"
+	StrategyZeroDowntime = "zdt"
+	StrategyRolling      = "rolling"
+)
 
 type Request struct {
 	Source resource.Source `json:"source"`
@@ -9,18 +19,139 @@ type Request struct {
 
 type Params struct {
 	ManifestPath         string                 `json:"manifest"`
+	AdditionalManifests  []string               `json:"additional_manifests"`
+	OpsFiles             []string               `json:"ops_files"`
 	Path                 string                 `json:"path"`
 	CurrentAppName       string                 `json:"current_app_name"`
 	Vars                 map[string]interface{} `json:"vars"`
 	VarsFiles            []string               `json:"vars_files"`
-	EnvironmentVariables map[string]string      `json:"environment_variables"`
+	EnvironmentVariables EnvironmentVariables   `json:"environment_variables"`
 	DockerUsername       string                 `json:"docker_username"`
 	DockerPassword       string                 `json:"docker_password"`
 	ShowAppLog           bool                   `json:"show_app_log"`
 	NoStart              bool                   `json:"no_start"`
+	Strategy             string                 `json:"strategy"`
+	DryRun               bool                   `json:"dry_run"`
+	WaitForRunning       bool                   `json:"wait_for_running"`
+	RunningTimeout       string                 `json:"running_timeout"`
+	StabilityWindow      string                 `json:"stability_window"`
+	Lint                 string                 `json:"lint"`
+	OverrideFreeze       bool                   `json:"override_freeze"`
+	OverrideFreezeReason string                 `json:"override_freeze_reason"`
+	Lock                 string                 `json:"lock"`
+	LockTimeout          string                 `json:"lock_timeout"`
+	LockTTL              string                 `json:"lock_ttl"`
+	Labels               map[string]string      `json:"labels"`
+	Annotations          map[string]string      `json:"annotations"`
+	Force                bool                   `json:"force"`
+	Tasks                Tasks                  `json:"tasks"`
+	Services             []Service              `json:"services"`
+	Routes               Routes                 `json:"routes"`
+	Scale                Scaling                `json:"scale"`
+	Action               string                 `json:"action"`
+	Review               Review                 `json:"review"`
+	Cleanup              Cleanup                `json:"cleanup"`
+}
+
+// Secrets returns the values from the request that must never be logged:
+// credentials, environment variable values, the string values of vars and
+// the parameters and credentials of services. The values in vars_files are
+// read by VarsFileSecrets.
+func (request Request) Secrets() []string {
+	secrets := []string{
+		request.Source.Password,
+		request.Source.ClientSecret,
+		request.Params.DockerPassword,
+	}
+
+	envs := []map[string]interface{}{request.Params.EnvironmentVariables.Global}
+	for _, appEnv := range request.Params.EnvironmentVariables.Apps {
+		envs = append(envs, appEnv)
+	}
+	for _, env := range envs {
+		// values that can't be formatted can't be set either
+		values, _ := envStrings(env)
+		for _, value := range values {
+			secrets = append(secrets, value)
+		}
+	}
+
+	secrets = append(secrets, stringLeaves(request.Params.Vars)...)
+
+	for _, service := range request.Params.Services {
+		secrets = append(secrets, jsonStrings(service.Parameters)...)
+		secrets = append(secrets, jsonStrings(service.Credentials)...)
+	}
+
+	return secrets
+}
+
+// jsonStrings returns the strings and numbers anywhere in a decoded JSON
+// value.
+func jsonStrings(value interface{}) []string {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		var values []string
+		for _, v := range value {
+			values = append(values, jsonStrings(v)...)
+		}
+		return values
+	case []interface{}:
+		var values []string
+		for _, v := range value {
+			values = append(values, jsonStrings(v)...)
+		}
+		return values
+	case string:
+		return []string{value}
+	case json.Number:
+		return []string{value.String()}
+	default:
+		return nil
+	}
+}
+
+// VarsFileSecrets returns the string values in vars_files, which are
+// interpolated into the manifest and so would show up in CF_TRACE. The
+// paths have to be resolved first.
+func (params Params) VarsFileSecrets() ([]string, error) {
+	values, err := readVars(nil, params.VarsFiles)
+	if err != nil {
+		return nil, err
+	}
+	return stringLeaves(values), nil
+}
+
+// stringLeaves returns the strings anywhere in a decoded JSON or YAML value,
+// leaving out numbers and booleans, which are too common to mask.
+func stringLeaves(value interface{}) []string {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		var values []string
+		for _, v := range value {
+			values = append(values, stringLeaves(v)...)
+		}
+		return values
+	case []interface{}:
+		var values []string
+		for _, v := range value {
+			values = append(values, stringLeaves(v)...)
+		}
+		return values
+	case string:
+		return []string{value}
+	default:
+		return nil
+	}
 }
 
 type Response struct {
 	Version  resource.Version        `json:"version"`
 	Metadata []resource.MetadataPair `json:"metadata"`
 }
+
+// PushResult describes how PushApp deployed the app.
+type PushResult struct {
+	Strategy   string
+	RolledBack bool
+}
@@ -0,0 +1,261 @@
+package out
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Op is one operation from a BOSH-style ops file, which holds a list of
+// them, e.g.
+//
+//	type: replace
+//	path: /applications/name=web/instances
+//	value: 4
+//
+// Path segments are map keys, list indexes, `-` for the end of a list, or
+// `key=value` for the list item with that key. A segment ending in `?`, and
+// every segment after it, is optional: replace creates what is missing and
+// remove ignores it.
+type Op struct {
+	Type  string    `yaml:"type"`
+	Path  string    `yaml:"path"`
+	Value yaml.Node `yaml:"value"`
+}
+
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	append   bool
+	matchKey string
+	optional bool
+}
+
+// ReadOps reads the operations in an ops file.
+func ReadOps(opsFile string) ([]Op, error) {
+	yamlData, err := ioutil.ReadFile(opsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []Op
+	if err := yaml.Unmarshal(yamlData, &ops); err != nil {
+		return nil, fmt.Errorf("reading ops file %s: %s", opsFile, err)
+	}
+	return ops, nil
+}
+
+// ApplyOps applies the operations to the manifest in order.
+func (manifest *Manifest) ApplyOps(ops []Op) error {
+	if manifest.root() == nil {
+		manifest.document = &yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	for _, op := range ops {
+		segments, err := parsePath(op.Path)
+		if err != nil {
+			return err
+		}
+
+		switch op.Type {
+		case "replace":
+			err = replace(manifest.root(), segments, &op.Value)
+		case "remove":
+			err = remove(manifest.root(), segments)
+		default:
+			err = fmt.Errorf("unknown type %q, expected replace or remove", op.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("ops %s: %s", op.Path, err)
+		}
+	}
+
+	return nil
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "/") || path == "/" {
+		return nil, fmt.Errorf("invalid path %q, expected something like /applications/name=web/instances", path)
+	}
+
+	var segments []pathSegment
+	optional := false
+	for _, token := range strings.Split(path[1:], "/") {
+		if strings.HasSuffix(token, "?") {
+			token = strings.TrimSuffix(token, "?")
+			optional = true
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+		segment := pathSegment{key: token, optional: optional}
+		if token == "-" {
+			segment.append = true
+		} else if index, err := strconv.Atoi(token); err == nil {
+			segment.index, segment.isIndex = index, true
+		} else if parts := strings.SplitN(token, "=", 2); len(parts) == 2 {
+			segment.matchKey, segment.key = parts[0], parts[1]
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, nil
+}
+
+func (segment pathSegment) String() string {
+	if segment.matchKey != "" {
+		return segment.matchKey + "=" + segment.key
+	}
+	return segment.key
+}
+
+// find returns the index of the child that segment refers to in node's
+// Content, or -1. For a mapping that is the index of the value.
+func (segment pathSegment) find(node *yaml.Node) (int, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if segment.matchKey != "" {
+			return -1, fmt.Errorf("expected a map key, not %s", segment)
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment.key {
+				return i + 1, nil
+			}
+		}
+		return -1, nil
+	case yaml.SequenceNode:
+		switch {
+		case segment.isIndex:
+			index := segment.index
+			if index < 0 {
+				index += len(node.Content)
+			}
+			if index < 0 || index >= len(node.Content) {
+				return -1, fmt.Errorf("index %d is out of range, the list has %d items", segment.index, len(node.Content))
+			}
+			return index, nil
+		case segment.matchKey != "":
+			for i, item := range node.Content {
+				if value := mappingValue(item, segment.matchKey); value != nil && value.Value == segment.key {
+					return i, nil
+				}
+			}
+			return -1, nil
+		case segment.append:
+			return -1, nil
+		default:
+			return -1, fmt.Errorf("expected a list index or key=value, not %s", segment)
+		}
+	default:
+		return -1, fmt.Errorf("can't look up %s in a scalar", segment)
+	}
+}
+
+// child returns the node segment refers to. A missing optional node is
+// created when create is set, and nil otherwise; next is the segment that
+// comes after it, and decides what is created.
+func (segment pathSegment) child(node *yaml.Node, next pathSegment, create bool) (*yaml.Node, error) {
+	i, err := segment.find(node)
+	if err != nil {
+		return nil, err
+	}
+	if i >= 0 {
+		return node.Content[i], nil
+	}
+	if !segment.optional {
+		return nil, fmt.Errorf("%s not found", segment)
+	}
+	if !create {
+		return nil, nil
+	}
+
+	created := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if next.isIndex || next.append || next.matchKey != "" {
+		created = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
+
+	switch {
+	case node.Kind == yaml.MappingNode:
+		setMappingValue(node, segment.key, created)
+	case segment.matchKey != "":
+		created = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(created, segment.matchKey, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: segment.key})
+		node.Content = append(node.Content, created)
+	default:
+		node.Content = append(node.Content, created)
+	}
+	return created, nil
+}
+
+func parent(root *yaml.Node, segments []pathSegment, create bool) (*yaml.Node, error) {
+	node := root
+	for i, segment := range segments[:len(segments)-1] {
+		var err error
+		node, err = segment.child(node, segments[i+1], create)
+		if node == nil || err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func replace(root *yaml.Node, segments []pathSegment, value *yaml.Node) error {
+	if value.Kind == 0 {
+		return fmt.Errorf("replace needs a value")
+	}
+
+	node, err := parent(root, segments, true)
+	if err != nil {
+		return err
+	}
+
+	last := segments[len(segments)-1]
+	i, err := last.find(node)
+	if err != nil {
+		return err
+	}
+
+	replacement := *value
+	switch {
+	case i >= 0:
+		node.Content[i] = &replacement
+	case node.Kind == yaml.MappingNode && last.optional:
+		setMappingValue(node, last.key, &replacement)
+	case node.Kind == yaml.SequenceNode && (last.append || last.optional):
+		node.Content = append(node.Content, &replacement)
+	default:
+		return fmt.Errorf("%s not found", last)
+	}
+	return nil
+}
+
+func remove(root *yaml.Node, segments []pathSegment) error {
+	node, err := parent(root, segments, false)
+	if node == nil || err != nil {
+		return err
+	}
+
+	last := segments[len(segments)-1]
+	i, err := last.find(node)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case i < 0 && last.optional:
+		return nil
+	case i < 0:
+		return fmt.Errorf("%s not found", last)
+	case node.Kind == yaml.MappingNode:
+		node.Content = append(node.Content[:i-1], node.Content[i+1:]...)
+	default:
+		node.Content = append(node.Content[:i], node.Content[i+1:]...)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package out_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"github.com/concourse/cf-resource/out"
+)
+
+func ops(document string) []out.Op {
+	var ops []out.Op
+	Expect(yaml.Unmarshal([]byte(document), &ops)).To(BeNil())
+	return ops
+}
+
+var _ = Describe("ApplyOps", func() {
+	var manifest out.Manifest
+
+	BeforeEach(func() {
+		var err error
+		manifest, err = out.NewManifest("assets/manifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("applies the operations in an ops file", func() {
+		ops, err := out.ReadOps("assets/ops.yml")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = manifest.ApplyOps(ops)
+		Expect(err).NotTo(HaveOccurred())
+
+		apps := manifest.Applications()
+		Expect(apps).To(HaveLen(1))
+		Expect(*apps[0].Instances).To(Equal(4))
+		Expect(apps[0].Routes).To(Equal([]string{"app1.staging.example.com"}))
+		Expect(apps[0].Env).To(Equal(map[string]string{"MANIFEST_A": "manifest_a"}))
+	})
+
+	It("replaces by list index", func() {
+		err := manifest.ApplyOps(ops(`
+- type: replace
+  path: /applications/1/name
+  value: renamed
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Applications()[1].Name).To(Equal("renamed"))
+	})
+
+	It("ignores optional paths that aren't there when removing", func() {
+		err := manifest.ApplyOps(ops(`
+- type: remove
+  path: /applications/name=missing?/env
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Applications()).To(HaveLen(2))
+	})
+
+	It("fails on paths that aren't there unless they are optional", func() {
+		err := manifest.ApplyOps(ops(`
+- type: replace
+  path: /applications/name=app2/memory
+  value: 1G
+`))
+		Expect(err).To(MatchError("ops /applications/name=app2/memory: memory not found"))
+	})
+
+	It("fails on unknown operation types", func() {
+		err := manifest.ApplyOps(ops(`
+- type: append
+  path: /applications/-
+  value: {name: app3}
+`))
+		Expect(err).To(MatchError(`ops /applications/-: unknown type "append", expected replace or remove`))
+	})
+})
+
+var _ = Describe("Merge", func() {
+	It("merges apps by name and resolves their paths against the overlay's directory", func() {
+		manifest, err := out.NewManifest("assets/manifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = manifest.Merge("assets/overlayManifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+
+		apps := manifest.Applications()
+		Expect(apps).To(HaveLen(3))
+		Expect(apps[0].Memory).To(Equal("2G"))
+		Expect(apps[0].Env).To(Equal(map[string]string{
+			"MANIFEST_A": "manifest_a",
+			"MANIFEST_B": "manifest_b",
+			"OVERLAY_A":  "overlay_a",
+		}))
+		Expect(apps[2].Name).To(Equal("app3"))
+
+		assets, err := filepath.Abs("assets")
+		Expect(err).NotTo(HaveOccurred())
+
+		tempPath, err := manifest.SaveTemp("assets/manifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(tempPath)
+
+		contents, err := ioutil.ReadFile(tempPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		var saved struct {
+			Applications []map[string]interface{}
+		}
+		Expect(yaml.Unmarshal(contents, &saved)).To(BeNil())
+		Expect(saved.Applications[0]["path"]).To(Equal(filepath.Join(assets, "overlay-build")))
+	})
+})
@@ -8,6 +8,9 @@ import (
 	"github.com/onsi/gomega/gexec"
 
 	"testing"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
 )
 
 func TestOut(t *testing.T) {
@@ -31,3 +34,20 @@ var _ = BeforeSuite(func() {
 var _ = AfterSuite(func() {
 	gexec.CleanupBuildArtifacts()
 })
+
+// newRequest returns a put of assets/manifest.yml to a test space; specs
+// override what they test.
+func newRequest() out.Request {
+	return out.Request{
+		Source: resource.Source{
+			API:          "https://api.run.pivotal.io",
+			Username:     "awesome@example.com",
+			Password:     "hunter2",
+			Organization: "secret",
+			Space:        "volcano-base",
+		},
+		Params: out.Params{
+			ManifestPath: "assets/manifest.yml",
+		},
+	}
+}
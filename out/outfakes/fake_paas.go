@@ -2,131 +2,1799 @@
 package outfakes
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
 )
 
 type FakePAAS struct {
-	LoginStub        func(api string, username string, password string, clientID string, clientSecret string, insecure bool) error
+	AppAnnotationsStub        func(string) (map[string]string, error)
+	appAnnotationsMutex       sync.RWMutex
+	appAnnotationsArgsForCall []struct {
+		arg1 string
+	}
+	appAnnotationsReturns struct {
+		result1 map[string]string
+		result2 error
+	}
+	appAnnotationsReturnsOnCall map[int]struct {
+		result1 map[string]string
+		result2 error
+	}
+	CreateServiceStub        func(out.Service) error
+	createServiceMutex       sync.RWMutex
+	createServiceArgsForCall []struct {
+		arg1 out.Service
+	}
+	createServiceReturns struct {
+		result1 error
+	}
+	createServiceReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteAppStub        func(string) error
+	deleteAppMutex       sync.RWMutex
+	deleteAppArgsForCall []struct {
+		arg1 string
+	}
+	deleteAppReturns struct {
+		result1 error
+	}
+	deleteAppReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteRouteStub        func(out.Route) error
+	deleteRouteMutex       sync.RWMutex
+	deleteRouteArgsForCall []struct {
+		arg1 out.Route
+	}
+	deleteRouteReturns struct {
+		result1 error
+	}
+	deleteRouteReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteServiceStub        func(string) error
+	deleteServiceMutex       sync.RWMutex
+	deleteServiceArgsForCall []struct {
+		arg1 string
+	}
+	deleteServiceReturns struct {
+		result1 error
+	}
+	deleteServiceReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DetachedStub        func(time.Duration) (out.PAAS, context.CancelFunc)
+	detachedMutex       sync.RWMutex
+	detachedArgsForCall []struct {
+		arg1 time.Duration
+	}
+	detachedReturns struct {
+		result1 out.PAAS
+		result2 context.CancelFunc
+	}
+	detachedReturnsOnCall map[int]struct {
+		result1 out.PAAS
+		result2 context.CancelFunc
+	}
+	GetAppStub        func(string) (out.App, error)
+	getAppMutex       sync.RWMutex
+	getAppArgsForCall []struct {
+		arg1 string
+	}
+	getAppReturns struct {
+		result1 out.App
+		result2 error
+	}
+	getAppReturnsOnCall map[int]struct {
+		result1 out.App
+		result2 error
+	}
+	InstanceStatesStub        func(string) ([]string, error)
+	instanceStatesMutex       sync.RWMutex
+	instanceStatesArgsForCall []struct {
+		arg1 string
+	}
+	instanceStatesReturns struct {
+		result1 []string
+		result2 error
+	}
+	instanceStatesReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	LoginStub        func(string, string, string, string, string, bool) error
 	loginMutex       sync.RWMutex
 	loginArgsForCall []struct {
-		api          string
-		username     string
-		password     string
-		clientID     string
-		clientSecret string
-		insecure     bool
+		arg1 string
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 bool
+	}
+	loginReturns struct {
+		result1 error
+	}
+	loginReturnsOnCall map[int]struct {
+		result1 error
+	}
+	MapRouteStub        func(string, out.Route) error
+	mapRouteMutex       sync.RWMutex
+	mapRouteArgsForCall []struct {
+		arg1 string
+		arg2 out.Route
+	}
+	mapRouteReturns struct {
+		result1 error
+	}
+	mapRouteReturnsOnCall map[int]struct {
+		result1 error
+	}
+	PushAppStub        func(string, string, string, string, bool, bool, string, func() error) (out.PushResult, error)
+	pushAppMutex       sync.RWMutex
+	pushAppArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 bool
+		arg6 bool
+		arg7 string
+		arg8 func() error
+	}
+	pushAppReturns struct {
+		result1 out.PushResult
+		result2 error
+	}
+	pushAppReturnsOnCall map[int]struct {
+		result1 out.PushResult
+		result2 error
+	}
+	RestageAppStub        func(string, bool) error
+	restageAppMutex       sync.RWMutex
+	restageAppArgsForCall []struct {
+		arg1 string
+		arg2 bool
+	}
+	restageAppReturns struct {
+		result1 error
+	}
+	restageAppReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RestartAppStub        func(string, bool) error
+	restartAppMutex       sync.RWMutex
+	restartAppArgsForCall []struct {
+		arg1 string
+		arg2 bool
+	}
+	restartAppReturns struct {
+		result1 error
+	}
+	restartAppReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RouteInOtherSpaceStub        func(string) (bool, error)
+	routeInOtherSpaceMutex       sync.RWMutex
+	routeInOtherSpaceArgsForCall []struct {
+		arg1 string
+	}
+	routeInOtherSpaceReturns struct {
+		result1 bool
+		result2 error
+	}
+	routeInOtherSpaceReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
+	RunTaskStub        func(string, out.Task) (out.TaskRun, error)
+	runTaskMutex       sync.RWMutex
+	runTaskArgsForCall []struct {
+		arg1 string
+		arg2 out.Task
+	}
+	runTaskReturns struct {
+		result1 out.TaskRun
+		result2 error
+	}
+	runTaskReturnsOnCall map[int]struct {
+		result1 out.TaskRun
+		result2 error
+	}
+	ServiceInstanceStub        func(string) (out.ServiceInstance, error)
+	serviceInstanceMutex       sync.RWMutex
+	serviceInstanceArgsForCall []struct {
+		arg1 string
+	}
+	serviceInstanceReturns struct {
+		result1 out.ServiceInstance
+		result2 error
+	}
+	serviceInstanceReturnsOnCall map[int]struct {
+		result1 out.ServiceInstance
+		result2 error
+	}
+	SetAppMetadataStub        func(string, map[string]string, map[string]string) error
+	setAppMetadataMutex       sync.RWMutex
+	setAppMetadataArgsForCall []struct {
+		arg1 string
+		arg2 map[string]string
+		arg3 map[string]string
+	}
+	setAppMetadataReturns struct {
+		result1 error
+	}
+	setAppMetadataReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SetSpaceAnnotationStub        func(string, string) error
+	setSpaceAnnotationMutex       sync.RWMutex
+	setSpaceAnnotationArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	setSpaceAnnotationReturns struct {
+		result1 error
+	}
+	setSpaceAnnotationReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SpaceAnnotationStub        func(string) (string, error)
+	spaceAnnotationMutex       sync.RWMutex
+	spaceAnnotationArgsForCall []struct {
+		arg1 string
+	}
+	spaceAnnotationReturns struct {
+		result1 string
+		result2 error
+	}
+	spaceAnnotationReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	SpaceAppsStub        func() ([]out.SpaceApp, error)
+	spaceAppsMutex       sync.RWMutex
+	spaceAppsArgsForCall []struct {
+	}
+	spaceAppsReturns struct {
+		result1 []out.SpaceApp
+		result2 error
+	}
+	spaceAppsReturnsOnCall map[int]struct {
+		result1 []out.SpaceApp
+		result2 error
+	}
+	StartAppStub        func(string) error
+	startAppMutex       sync.RWMutex
+	startAppArgsForCall []struct {
+		arg1 string
+	}
+	startAppReturns struct {
+		result1 error
+	}
+	startAppReturnsOnCall map[int]struct {
+		result1 error
+	}
+	StopAppStub        func(string) error
+	stopAppMutex       sync.RWMutex
+	stopAppArgsForCall []struct {
+		arg1 string
+	}
+	stopAppReturns struct {
+		result1 error
+	}
+	stopAppReturnsOnCall map[int]struct {
+		result1 error
+	}
+	TargetStub        func(string, string) error
+	targetMutex       sync.RWMutex
+	targetArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	targetReturns struct {
+		result1 error
+	}
+	targetReturnsOnCall map[int]struct {
+		result1 error
+	}
+	TaskLogsStub        func(string, string) ([]string, error)
+	taskLogsMutex       sync.RWMutex
+	taskLogsArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	taskLogsReturns struct {
+		result1 []string
+		result2 error
+	}
+	taskLogsReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	TaskStateStub        func(string, int) (out.TaskState, error)
+	taskStateMutex       sync.RWMutex
+	taskStateArgsForCall []struct {
+		arg1 string
+		arg2 int
+	}
+	taskStateReturns struct {
+		result1 out.TaskState
+		result2 error
+	}
+	taskStateReturnsOnCall map[int]struct {
+		result1 out.TaskState
+		result2 error
+	}
+	TerminateTaskStub        func(string, int) error
+	terminateTaskMutex       sync.RWMutex
+	terminateTaskArgsForCall []struct {
+		arg1 string
+		arg2 int
+	}
+	terminateTaskReturns struct {
+		result1 error
+	}
+	terminateTaskReturnsOnCall map[int]struct {
+		result1 error
+	}
+	UnmapRouteStub        func(string, out.Route) error
+	unmapRouteMutex       sync.RWMutex
+	unmapRouteArgsForCall []struct {
+		arg1 string
+		arg2 out.Route
+	}
+	unmapRouteReturns struct {
+		result1 error
+	}
+	unmapRouteReturnsOnCall map[int]struct {
+		result1 error
+	}
+	UnmappedRoutesStub        func() ([]out.SpaceRoute, error)
+	unmappedRoutesMutex       sync.RWMutex
+	unmappedRoutesArgsForCall []struct {
+	}
+	unmappedRoutesReturns struct {
+		result1 []out.SpaceRoute
+		result2 error
+	}
+	unmappedRoutesReturnsOnCall map[int]struct {
+		result1 []out.SpaceRoute
+		result2 error
+	}
+	UpdateServiceStub        func(out.Service) error
+	updateServiceMutex       sync.RWMutex
+	updateServiceArgsForCall []struct {
+		arg1 out.Service
+	}
+	updateServiceReturns struct {
+		result1 error
+	}
+	updateServiceReturnsOnCall map[int]struct {
+		result1 error
+	}
+	VersionStub        func() (cli.Version, error)
+	versionMutex       sync.RWMutex
+	versionArgsForCall []struct {
+	}
+	versionReturns struct {
+		result1 cli.Version
+		result2 error
+	}
+	versionReturnsOnCall map[int]struct {
+		result1 cli.Version
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakePAAS) AppAnnotations(arg1 string) (map[string]string, error) {
+	fake.appAnnotationsMutex.Lock()
+	ret, specificReturn := fake.appAnnotationsReturnsOnCall[len(fake.appAnnotationsArgsForCall)]
+	fake.appAnnotationsArgsForCall = append(fake.appAnnotationsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.AppAnnotationsStub
+	fakeReturns := fake.appAnnotationsReturns
+	fake.recordInvocation("AppAnnotations", []interface{}{arg1})
+	fake.appAnnotationsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) AppAnnotationsCallCount() int {
+	fake.appAnnotationsMutex.RLock()
+	defer fake.appAnnotationsMutex.RUnlock()
+	return len(fake.appAnnotationsArgsForCall)
+}
+
+func (fake *FakePAAS) AppAnnotationsCalls(stub func(string) (map[string]string, error)) {
+	fake.appAnnotationsMutex.Lock()
+	defer fake.appAnnotationsMutex.Unlock()
+	fake.AppAnnotationsStub = stub
+}
+
+func (fake *FakePAAS) AppAnnotationsArgsForCall(i int) string {
+	fake.appAnnotationsMutex.RLock()
+	defer fake.appAnnotationsMutex.RUnlock()
+	argsForCall := fake.appAnnotationsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) AppAnnotationsReturns(result1 map[string]string, result2 error) {
+	fake.appAnnotationsMutex.Lock()
+	defer fake.appAnnotationsMutex.Unlock()
+	fake.AppAnnotationsStub = nil
+	fake.appAnnotationsReturns = struct {
+		result1 map[string]string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) AppAnnotationsReturnsOnCall(i int, result1 map[string]string, result2 error) {
+	fake.appAnnotationsMutex.Lock()
+	defer fake.appAnnotationsMutex.Unlock()
+	fake.AppAnnotationsStub = nil
+	if fake.appAnnotationsReturnsOnCall == nil {
+		fake.appAnnotationsReturnsOnCall = make(map[int]struct {
+			result1 map[string]string
+			result2 error
+		})
+	}
+	fake.appAnnotationsReturnsOnCall[i] = struct {
+		result1 map[string]string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) CreateService(arg1 out.Service) error {
+	fake.createServiceMutex.Lock()
+	ret, specificReturn := fake.createServiceReturnsOnCall[len(fake.createServiceArgsForCall)]
+	fake.createServiceArgsForCall = append(fake.createServiceArgsForCall, struct {
+		arg1 out.Service
+	}{arg1})
+	stub := fake.CreateServiceStub
+	fakeReturns := fake.createServiceReturns
+	fake.recordInvocation("CreateService", []interface{}{arg1})
+	fake.createServiceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) CreateServiceCallCount() int {
+	fake.createServiceMutex.RLock()
+	defer fake.createServiceMutex.RUnlock()
+	return len(fake.createServiceArgsForCall)
+}
+
+func (fake *FakePAAS) CreateServiceCalls(stub func(out.Service) error) {
+	fake.createServiceMutex.Lock()
+	defer fake.createServiceMutex.Unlock()
+	fake.CreateServiceStub = stub
+}
+
+func (fake *FakePAAS) CreateServiceArgsForCall(i int) out.Service {
+	fake.createServiceMutex.RLock()
+	defer fake.createServiceMutex.RUnlock()
+	argsForCall := fake.createServiceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) CreateServiceReturns(result1 error) {
+	fake.createServiceMutex.Lock()
+	defer fake.createServiceMutex.Unlock()
+	fake.CreateServiceStub = nil
+	fake.createServiceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) CreateServiceReturnsOnCall(i int, result1 error) {
+	fake.createServiceMutex.Lock()
+	defer fake.createServiceMutex.Unlock()
+	fake.CreateServiceStub = nil
+	if fake.createServiceReturnsOnCall == nil {
+		fake.createServiceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.createServiceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) DeleteApp(arg1 string) error {
+	fake.deleteAppMutex.Lock()
+	ret, specificReturn := fake.deleteAppReturnsOnCall[len(fake.deleteAppArgsForCall)]
+	fake.deleteAppArgsForCall = append(fake.deleteAppArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.DeleteAppStub
+	fakeReturns := fake.deleteAppReturns
+	fake.recordInvocation("DeleteApp", []interface{}{arg1})
+	fake.deleteAppMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) DeleteAppCallCount() int {
+	fake.deleteAppMutex.RLock()
+	defer fake.deleteAppMutex.RUnlock()
+	return len(fake.deleteAppArgsForCall)
+}
+
+func (fake *FakePAAS) DeleteAppCalls(stub func(string) error) {
+	fake.deleteAppMutex.Lock()
+	defer fake.deleteAppMutex.Unlock()
+	fake.DeleteAppStub = stub
+}
+
+func (fake *FakePAAS) DeleteAppArgsForCall(i int) string {
+	fake.deleteAppMutex.RLock()
+	defer fake.deleteAppMutex.RUnlock()
+	argsForCall := fake.deleteAppArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) DeleteAppReturns(result1 error) {
+	fake.deleteAppMutex.Lock()
+	defer fake.deleteAppMutex.Unlock()
+	fake.DeleteAppStub = nil
+	fake.deleteAppReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) DeleteAppReturnsOnCall(i int, result1 error) {
+	fake.deleteAppMutex.Lock()
+	defer fake.deleteAppMutex.Unlock()
+	fake.DeleteAppStub = nil
+	if fake.deleteAppReturnsOnCall == nil {
+		fake.deleteAppReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteAppReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) DeleteRoute(arg1 out.Route) error {
+	fake.deleteRouteMutex.Lock()
+	ret, specificReturn := fake.deleteRouteReturnsOnCall[len(fake.deleteRouteArgsForCall)]
+	fake.deleteRouteArgsForCall = append(fake.deleteRouteArgsForCall, struct {
+		arg1 out.Route
+	}{arg1})
+	stub := fake.DeleteRouteStub
+	fakeReturns := fake.deleteRouteReturns
+	fake.recordInvocation("DeleteRoute", []interface{}{arg1})
+	fake.deleteRouteMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) DeleteRouteCallCount() int {
+	fake.deleteRouteMutex.RLock()
+	defer fake.deleteRouteMutex.RUnlock()
+	return len(fake.deleteRouteArgsForCall)
+}
+
+func (fake *FakePAAS) DeleteRouteCalls(stub func(out.Route) error) {
+	fake.deleteRouteMutex.Lock()
+	defer fake.deleteRouteMutex.Unlock()
+	fake.DeleteRouteStub = stub
+}
+
+func (fake *FakePAAS) DeleteRouteArgsForCall(i int) out.Route {
+	fake.deleteRouteMutex.RLock()
+	defer fake.deleteRouteMutex.RUnlock()
+	argsForCall := fake.deleteRouteArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) DeleteRouteReturns(result1 error) {
+	fake.deleteRouteMutex.Lock()
+	defer fake.deleteRouteMutex.Unlock()
+	fake.DeleteRouteStub = nil
+	fake.deleteRouteReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) DeleteRouteReturnsOnCall(i int, result1 error) {
+	fake.deleteRouteMutex.Lock()
+	defer fake.deleteRouteMutex.Unlock()
+	fake.DeleteRouteStub = nil
+	if fake.deleteRouteReturnsOnCall == nil {
+		fake.deleteRouteReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteRouteReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) DeleteService(arg1 string) error {
+	fake.deleteServiceMutex.Lock()
+	ret, specificReturn := fake.deleteServiceReturnsOnCall[len(fake.deleteServiceArgsForCall)]
+	fake.deleteServiceArgsForCall = append(fake.deleteServiceArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.DeleteServiceStub
+	fakeReturns := fake.deleteServiceReturns
+	fake.recordInvocation("DeleteService", []interface{}{arg1})
+	fake.deleteServiceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) DeleteServiceCallCount() int {
+	fake.deleteServiceMutex.RLock()
+	defer fake.deleteServiceMutex.RUnlock()
+	return len(fake.deleteServiceArgsForCall)
+}
+
+func (fake *FakePAAS) DeleteServiceCalls(stub func(string) error) {
+	fake.deleteServiceMutex.Lock()
+	defer fake.deleteServiceMutex.Unlock()
+	fake.DeleteServiceStub = stub
+}
+
+func (fake *FakePAAS) DeleteServiceArgsForCall(i int) string {
+	fake.deleteServiceMutex.RLock()
+	defer fake.deleteServiceMutex.RUnlock()
+	argsForCall := fake.deleteServiceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) DeleteServiceReturns(result1 error) {
+	fake.deleteServiceMutex.Lock()
+	defer fake.deleteServiceMutex.Unlock()
+	fake.DeleteServiceStub = nil
+	fake.deleteServiceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) DeleteServiceReturnsOnCall(i int, result1 error) {
+	fake.deleteServiceMutex.Lock()
+	defer fake.deleteServiceMutex.Unlock()
+	fake.DeleteServiceStub = nil
+	if fake.deleteServiceReturnsOnCall == nil {
+		fake.deleteServiceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteServiceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) Detached(arg1 time.Duration) (out.PAAS, context.CancelFunc) {
+	fake.detachedMutex.Lock()
+	ret, specificReturn := fake.detachedReturnsOnCall[len(fake.detachedArgsForCall)]
+	fake.detachedArgsForCall = append(fake.detachedArgsForCall, struct {
+		arg1 time.Duration
+	}{arg1})
+	stub := fake.DetachedStub
+	fakeReturns := fake.detachedReturns
+	fake.recordInvocation("Detached", []interface{}{arg1})
+	fake.detachedMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) DetachedCallCount() int {
+	fake.detachedMutex.RLock()
+	defer fake.detachedMutex.RUnlock()
+	return len(fake.detachedArgsForCall)
+}
+
+func (fake *FakePAAS) DetachedCalls(stub func(time.Duration) (out.PAAS, context.CancelFunc)) {
+	fake.detachedMutex.Lock()
+	defer fake.detachedMutex.Unlock()
+	fake.DetachedStub = stub
+}
+
+func (fake *FakePAAS) DetachedArgsForCall(i int) time.Duration {
+	fake.detachedMutex.RLock()
+	defer fake.detachedMutex.RUnlock()
+	argsForCall := fake.detachedArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) DetachedReturns(result1 out.PAAS, result2 context.CancelFunc) {
+	fake.detachedMutex.Lock()
+	defer fake.detachedMutex.Unlock()
+	fake.DetachedStub = nil
+	fake.detachedReturns = struct {
+		result1 out.PAAS
+		result2 context.CancelFunc
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) DetachedReturnsOnCall(i int, result1 out.PAAS, result2 context.CancelFunc) {
+	fake.detachedMutex.Lock()
+	defer fake.detachedMutex.Unlock()
+	fake.DetachedStub = nil
+	if fake.detachedReturnsOnCall == nil {
+		fake.detachedReturnsOnCall = make(map[int]struct {
+			result1 out.PAAS
+			result2 context.CancelFunc
+		})
+	}
+	fake.detachedReturnsOnCall[i] = struct {
+		result1 out.PAAS
+		result2 context.CancelFunc
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) GetApp(arg1 string) (out.App, error) {
+	fake.getAppMutex.Lock()
+	ret, specificReturn := fake.getAppReturnsOnCall[len(fake.getAppArgsForCall)]
+	fake.getAppArgsForCall = append(fake.getAppArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.GetAppStub
+	fakeReturns := fake.getAppReturns
+	fake.recordInvocation("GetApp", []interface{}{arg1})
+	fake.getAppMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) GetAppCallCount() int {
+	fake.getAppMutex.RLock()
+	defer fake.getAppMutex.RUnlock()
+	return len(fake.getAppArgsForCall)
+}
+
+func (fake *FakePAAS) GetAppCalls(stub func(string) (out.App, error)) {
+	fake.getAppMutex.Lock()
+	defer fake.getAppMutex.Unlock()
+	fake.GetAppStub = stub
+}
+
+func (fake *FakePAAS) GetAppArgsForCall(i int) string {
+	fake.getAppMutex.RLock()
+	defer fake.getAppMutex.RUnlock()
+	argsForCall := fake.getAppArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) GetAppReturns(result1 out.App, result2 error) {
+	fake.getAppMutex.Lock()
+	defer fake.getAppMutex.Unlock()
+	fake.GetAppStub = nil
+	fake.getAppReturns = struct {
+		result1 out.App
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) GetAppReturnsOnCall(i int, result1 out.App, result2 error) {
+	fake.getAppMutex.Lock()
+	defer fake.getAppMutex.Unlock()
+	fake.GetAppStub = nil
+	if fake.getAppReturnsOnCall == nil {
+		fake.getAppReturnsOnCall = make(map[int]struct {
+			result1 out.App
+			result2 error
+		})
+	}
+	fake.getAppReturnsOnCall[i] = struct {
+		result1 out.App
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) InstanceStates(arg1 string) ([]string, error) {
+	fake.instanceStatesMutex.Lock()
+	ret, specificReturn := fake.instanceStatesReturnsOnCall[len(fake.instanceStatesArgsForCall)]
+	fake.instanceStatesArgsForCall = append(fake.instanceStatesArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.InstanceStatesStub
+	fakeReturns := fake.instanceStatesReturns
+	fake.recordInvocation("InstanceStates", []interface{}{arg1})
+	fake.instanceStatesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) InstanceStatesCallCount() int {
+	fake.instanceStatesMutex.RLock()
+	defer fake.instanceStatesMutex.RUnlock()
+	return len(fake.instanceStatesArgsForCall)
+}
+
+func (fake *FakePAAS) InstanceStatesCalls(stub func(string) ([]string, error)) {
+	fake.instanceStatesMutex.Lock()
+	defer fake.instanceStatesMutex.Unlock()
+	fake.InstanceStatesStub = stub
+}
+
+func (fake *FakePAAS) InstanceStatesArgsForCall(i int) string {
+	fake.instanceStatesMutex.RLock()
+	defer fake.instanceStatesMutex.RUnlock()
+	argsForCall := fake.instanceStatesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) InstanceStatesReturns(result1 []string, result2 error) {
+	fake.instanceStatesMutex.Lock()
+	defer fake.instanceStatesMutex.Unlock()
+	fake.InstanceStatesStub = nil
+	fake.instanceStatesReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) InstanceStatesReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.instanceStatesMutex.Lock()
+	defer fake.instanceStatesMutex.Unlock()
+	fake.InstanceStatesStub = nil
+	if fake.instanceStatesReturnsOnCall == nil {
+		fake.instanceStatesReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.instanceStatesReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) Login(arg1 string, arg2 string, arg3 string, arg4 string, arg5 string, arg6 bool) error {
+	fake.loginMutex.Lock()
+	ret, specificReturn := fake.loginReturnsOnCall[len(fake.loginArgsForCall)]
+	fake.loginArgsForCall = append(fake.loginArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 string
+		arg6 bool
+	}{arg1, arg2, arg3, arg4, arg5, arg6})
+	stub := fake.LoginStub
+	fakeReturns := fake.loginReturns
+	fake.recordInvocation("Login", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6})
+	fake.loginMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5, arg6)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) LoginCallCount() int {
+	fake.loginMutex.RLock()
+	defer fake.loginMutex.RUnlock()
+	return len(fake.loginArgsForCall)
+}
+
+func (fake *FakePAAS) LoginCalls(stub func(string, string, string, string, string, bool) error) {
+	fake.loginMutex.Lock()
+	defer fake.loginMutex.Unlock()
+	fake.LoginStub = stub
+}
+
+func (fake *FakePAAS) LoginArgsForCall(i int) (string, string, string, string, string, bool) {
+	fake.loginMutex.RLock()
+	defer fake.loginMutex.RUnlock()
+	argsForCall := fake.loginArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6
+}
+
+func (fake *FakePAAS) LoginReturns(result1 error) {
+	fake.loginMutex.Lock()
+	defer fake.loginMutex.Unlock()
+	fake.LoginStub = nil
+	fake.loginReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) LoginReturnsOnCall(i int, result1 error) {
+	fake.loginMutex.Lock()
+	defer fake.loginMutex.Unlock()
+	fake.LoginStub = nil
+	if fake.loginReturnsOnCall == nil {
+		fake.loginReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.loginReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) MapRoute(arg1 string, arg2 out.Route) error {
+	fake.mapRouteMutex.Lock()
+	ret, specificReturn := fake.mapRouteReturnsOnCall[len(fake.mapRouteArgsForCall)]
+	fake.mapRouteArgsForCall = append(fake.mapRouteArgsForCall, struct {
+		arg1 string
+		arg2 out.Route
+	}{arg1, arg2})
+	stub := fake.MapRouteStub
+	fakeReturns := fake.mapRouteReturns
+	fake.recordInvocation("MapRoute", []interface{}{arg1, arg2})
+	fake.mapRouteMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) MapRouteCallCount() int {
+	fake.mapRouteMutex.RLock()
+	defer fake.mapRouteMutex.RUnlock()
+	return len(fake.mapRouteArgsForCall)
+}
+
+func (fake *FakePAAS) MapRouteCalls(stub func(string, out.Route) error) {
+	fake.mapRouteMutex.Lock()
+	defer fake.mapRouteMutex.Unlock()
+	fake.MapRouteStub = stub
+}
+
+func (fake *FakePAAS) MapRouteArgsForCall(i int) (string, out.Route) {
+	fake.mapRouteMutex.RLock()
+	defer fake.mapRouteMutex.RUnlock()
+	argsForCall := fake.mapRouteArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePAAS) MapRouteReturns(result1 error) {
+	fake.mapRouteMutex.Lock()
+	defer fake.mapRouteMutex.Unlock()
+	fake.MapRouteStub = nil
+	fake.mapRouteReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) MapRouteReturnsOnCall(i int, result1 error) {
+	fake.mapRouteMutex.Lock()
+	defer fake.mapRouteMutex.Unlock()
+	fake.MapRouteStub = nil
+	if fake.mapRouteReturnsOnCall == nil {
+		fake.mapRouteReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.mapRouteReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) PushApp(arg1 string, arg2 string, arg3 string, arg4 string, arg5 bool, arg6 bool, arg7 string, arg8 func() error) (out.PushResult, error) {
+	fake.pushAppMutex.Lock()
+	ret, specificReturn := fake.pushAppReturnsOnCall[len(fake.pushAppArgsForCall)]
+	fake.pushAppArgsForCall = append(fake.pushAppArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+		arg4 string
+		arg5 bool
+		arg6 bool
+		arg7 string
+		arg8 func() error
+	}{arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8})
+	stub := fake.PushAppStub
+	fakeReturns := fake.pushAppReturns
+	fake.recordInvocation("PushApp", []interface{}{arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8})
+	fake.pushAppMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) PushAppCallCount() int {
+	fake.pushAppMutex.RLock()
+	defer fake.pushAppMutex.RUnlock()
+	return len(fake.pushAppArgsForCall)
+}
+
+func (fake *FakePAAS) PushAppCalls(stub func(string, string, string, string, bool, bool, string, func() error) (out.PushResult, error)) {
+	fake.pushAppMutex.Lock()
+	defer fake.pushAppMutex.Unlock()
+	fake.PushAppStub = stub
+}
+
+func (fake *FakePAAS) PushAppArgsForCall(i int) (string, string, string, string, bool, bool, string, func() error) {
+	fake.pushAppMutex.RLock()
+	defer fake.pushAppMutex.RUnlock()
+	argsForCall := fake.pushAppArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5, argsForCall.arg6, argsForCall.arg7, argsForCall.arg8
+}
+
+func (fake *FakePAAS) PushAppReturns(result1 out.PushResult, result2 error) {
+	fake.pushAppMutex.Lock()
+	defer fake.pushAppMutex.Unlock()
+	fake.PushAppStub = nil
+	fake.pushAppReturns = struct {
+		result1 out.PushResult
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) PushAppReturnsOnCall(i int, result1 out.PushResult, result2 error) {
+	fake.pushAppMutex.Lock()
+	defer fake.pushAppMutex.Unlock()
+	fake.PushAppStub = nil
+	if fake.pushAppReturnsOnCall == nil {
+		fake.pushAppReturnsOnCall = make(map[int]struct {
+			result1 out.PushResult
+			result2 error
+		})
+	}
+	fake.pushAppReturnsOnCall[i] = struct {
+		result1 out.PushResult
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) RestageApp(arg1 string, arg2 bool) error {
+	fake.restageAppMutex.Lock()
+	ret, specificReturn := fake.restageAppReturnsOnCall[len(fake.restageAppArgsForCall)]
+	fake.restageAppArgsForCall = append(fake.restageAppArgsForCall, struct {
+		arg1 string
+		arg2 bool
+	}{arg1, arg2})
+	stub := fake.RestageAppStub
+	fakeReturns := fake.restageAppReturns
+	fake.recordInvocation("RestageApp", []interface{}{arg1, arg2})
+	fake.restageAppMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) RestageAppCallCount() int {
+	fake.restageAppMutex.RLock()
+	defer fake.restageAppMutex.RUnlock()
+	return len(fake.restageAppArgsForCall)
+}
+
+func (fake *FakePAAS) RestageAppCalls(stub func(string, bool) error) {
+	fake.restageAppMutex.Lock()
+	defer fake.restageAppMutex.Unlock()
+	fake.RestageAppStub = stub
+}
+
+func (fake *FakePAAS) RestageAppArgsForCall(i int) (string, bool) {
+	fake.restageAppMutex.RLock()
+	defer fake.restageAppMutex.RUnlock()
+	argsForCall := fake.restageAppArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePAAS) RestageAppReturns(result1 error) {
+	fake.restageAppMutex.Lock()
+	defer fake.restageAppMutex.Unlock()
+	fake.RestageAppStub = nil
+	fake.restageAppReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) RestageAppReturnsOnCall(i int, result1 error) {
+	fake.restageAppMutex.Lock()
+	defer fake.restageAppMutex.Unlock()
+	fake.RestageAppStub = nil
+	if fake.restageAppReturnsOnCall == nil {
+		fake.restageAppReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.restageAppReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) RestartApp(arg1 string, arg2 bool) error {
+	fake.restartAppMutex.Lock()
+	ret, specificReturn := fake.restartAppReturnsOnCall[len(fake.restartAppArgsForCall)]
+	fake.restartAppArgsForCall = append(fake.restartAppArgsForCall, struct {
+		arg1 string
+		arg2 bool
+	}{arg1, arg2})
+	stub := fake.RestartAppStub
+	fakeReturns := fake.restartAppReturns
+	fake.recordInvocation("RestartApp", []interface{}{arg1, arg2})
+	fake.restartAppMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) RestartAppCallCount() int {
+	fake.restartAppMutex.RLock()
+	defer fake.restartAppMutex.RUnlock()
+	return len(fake.restartAppArgsForCall)
+}
+
+func (fake *FakePAAS) RestartAppCalls(stub func(string, bool) error) {
+	fake.restartAppMutex.Lock()
+	defer fake.restartAppMutex.Unlock()
+	fake.RestartAppStub = stub
+}
+
+func (fake *FakePAAS) RestartAppArgsForCall(i int) (string, bool) {
+	fake.restartAppMutex.RLock()
+	defer fake.restartAppMutex.RUnlock()
+	argsForCall := fake.restartAppArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePAAS) RestartAppReturns(result1 error) {
+	fake.restartAppMutex.Lock()
+	defer fake.restartAppMutex.Unlock()
+	fake.RestartAppStub = nil
+	fake.restartAppReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) RestartAppReturnsOnCall(i int, result1 error) {
+	fake.restartAppMutex.Lock()
+	defer fake.restartAppMutex.Unlock()
+	fake.RestartAppStub = nil
+	if fake.restartAppReturnsOnCall == nil {
+		fake.restartAppReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.restartAppReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) RouteInOtherSpace(arg1 string) (bool, error) {
+	fake.routeInOtherSpaceMutex.Lock()
+	ret, specificReturn := fake.routeInOtherSpaceReturnsOnCall[len(fake.routeInOtherSpaceArgsForCall)]
+	fake.routeInOtherSpaceArgsForCall = append(fake.routeInOtherSpaceArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.RouteInOtherSpaceStub
+	fakeReturns := fake.routeInOtherSpaceReturns
+	fake.recordInvocation("RouteInOtherSpace", []interface{}{arg1})
+	fake.routeInOtherSpaceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) RouteInOtherSpaceCallCount() int {
+	fake.routeInOtherSpaceMutex.RLock()
+	defer fake.routeInOtherSpaceMutex.RUnlock()
+	return len(fake.routeInOtherSpaceArgsForCall)
+}
+
+func (fake *FakePAAS) RouteInOtherSpaceCalls(stub func(string) (bool, error)) {
+	fake.routeInOtherSpaceMutex.Lock()
+	defer fake.routeInOtherSpaceMutex.Unlock()
+	fake.RouteInOtherSpaceStub = stub
+}
+
+func (fake *FakePAAS) RouteInOtherSpaceArgsForCall(i int) string {
+	fake.routeInOtherSpaceMutex.RLock()
+	defer fake.routeInOtherSpaceMutex.RUnlock()
+	argsForCall := fake.routeInOtherSpaceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) RouteInOtherSpaceReturns(result1 bool, result2 error) {
+	fake.routeInOtherSpaceMutex.Lock()
+	defer fake.routeInOtherSpaceMutex.Unlock()
+	fake.RouteInOtherSpaceStub = nil
+	fake.routeInOtherSpaceReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) RouteInOtherSpaceReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.routeInOtherSpaceMutex.Lock()
+	defer fake.routeInOtherSpaceMutex.Unlock()
+	fake.RouteInOtherSpaceStub = nil
+	if fake.routeInOtherSpaceReturnsOnCall == nil {
+		fake.routeInOtherSpaceReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.routeInOtherSpaceReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) RunTask(arg1 string, arg2 out.Task) (out.TaskRun, error) {
+	fake.runTaskMutex.Lock()
+	ret, specificReturn := fake.runTaskReturnsOnCall[len(fake.runTaskArgsForCall)]
+	fake.runTaskArgsForCall = append(fake.runTaskArgsForCall, struct {
+		arg1 string
+		arg2 out.Task
+	}{arg1, arg2})
+	stub := fake.RunTaskStub
+	fakeReturns := fake.runTaskReturns
+	fake.recordInvocation("RunTask", []interface{}{arg1, arg2})
+	fake.runTaskMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) RunTaskCallCount() int {
+	fake.runTaskMutex.RLock()
+	defer fake.runTaskMutex.RUnlock()
+	return len(fake.runTaskArgsForCall)
+}
+
+func (fake *FakePAAS) RunTaskCalls(stub func(string, out.Task) (out.TaskRun, error)) {
+	fake.runTaskMutex.Lock()
+	defer fake.runTaskMutex.Unlock()
+	fake.RunTaskStub = stub
+}
+
+func (fake *FakePAAS) RunTaskArgsForCall(i int) (string, out.Task) {
+	fake.runTaskMutex.RLock()
+	defer fake.runTaskMutex.RUnlock()
+	argsForCall := fake.runTaskArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePAAS) RunTaskReturns(result1 out.TaskRun, result2 error) {
+	fake.runTaskMutex.Lock()
+	defer fake.runTaskMutex.Unlock()
+	fake.RunTaskStub = nil
+	fake.runTaskReturns = struct {
+		result1 out.TaskRun
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) RunTaskReturnsOnCall(i int, result1 out.TaskRun, result2 error) {
+	fake.runTaskMutex.Lock()
+	defer fake.runTaskMutex.Unlock()
+	fake.RunTaskStub = nil
+	if fake.runTaskReturnsOnCall == nil {
+		fake.runTaskReturnsOnCall = make(map[int]struct {
+			result1 out.TaskRun
+			result2 error
+		})
+	}
+	fake.runTaskReturnsOnCall[i] = struct {
+		result1 out.TaskRun
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) ServiceInstance(arg1 string) (out.ServiceInstance, error) {
+	fake.serviceInstanceMutex.Lock()
+	ret, specificReturn := fake.serviceInstanceReturnsOnCall[len(fake.serviceInstanceArgsForCall)]
+	fake.serviceInstanceArgsForCall = append(fake.serviceInstanceArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.ServiceInstanceStub
+	fakeReturns := fake.serviceInstanceReturns
+	fake.recordInvocation("ServiceInstance", []interface{}{arg1})
+	fake.serviceInstanceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) ServiceInstanceCallCount() int {
+	fake.serviceInstanceMutex.RLock()
+	defer fake.serviceInstanceMutex.RUnlock()
+	return len(fake.serviceInstanceArgsForCall)
+}
+
+func (fake *FakePAAS) ServiceInstanceCalls(stub func(string) (out.ServiceInstance, error)) {
+	fake.serviceInstanceMutex.Lock()
+	defer fake.serviceInstanceMutex.Unlock()
+	fake.ServiceInstanceStub = stub
+}
+
+func (fake *FakePAAS) ServiceInstanceArgsForCall(i int) string {
+	fake.serviceInstanceMutex.RLock()
+	defer fake.serviceInstanceMutex.RUnlock()
+	argsForCall := fake.serviceInstanceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) ServiceInstanceReturns(result1 out.ServiceInstance, result2 error) {
+	fake.serviceInstanceMutex.Lock()
+	defer fake.serviceInstanceMutex.Unlock()
+	fake.ServiceInstanceStub = nil
+	fake.serviceInstanceReturns = struct {
+		result1 out.ServiceInstance
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) ServiceInstanceReturnsOnCall(i int, result1 out.ServiceInstance, result2 error) {
+	fake.serviceInstanceMutex.Lock()
+	defer fake.serviceInstanceMutex.Unlock()
+	fake.ServiceInstanceStub = nil
+	if fake.serviceInstanceReturnsOnCall == nil {
+		fake.serviceInstanceReturnsOnCall = make(map[int]struct {
+			result1 out.ServiceInstance
+			result2 error
+		})
+	}
+	fake.serviceInstanceReturnsOnCall[i] = struct {
+		result1 out.ServiceInstance
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) SetAppMetadata(arg1 string, arg2 map[string]string, arg3 map[string]string) error {
+	fake.setAppMetadataMutex.Lock()
+	ret, specificReturn := fake.setAppMetadataReturnsOnCall[len(fake.setAppMetadataArgsForCall)]
+	fake.setAppMetadataArgsForCall = append(fake.setAppMetadataArgsForCall, struct {
+		arg1 string
+		arg2 map[string]string
+		arg3 map[string]string
+	}{arg1, arg2, arg3})
+	stub := fake.SetAppMetadataStub
+	fakeReturns := fake.setAppMetadataReturns
+	fake.recordInvocation("SetAppMetadata", []interface{}{arg1, arg2, arg3})
+	fake.setAppMetadataMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
-	loginReturns struct {
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) SetAppMetadataCallCount() int {
+	fake.setAppMetadataMutex.RLock()
+	defer fake.setAppMetadataMutex.RUnlock()
+	return len(fake.setAppMetadataArgsForCall)
+}
+
+func (fake *FakePAAS) SetAppMetadataCalls(stub func(string, map[string]string, map[string]string) error) {
+	fake.setAppMetadataMutex.Lock()
+	defer fake.setAppMetadataMutex.Unlock()
+	fake.SetAppMetadataStub = stub
+}
+
+func (fake *FakePAAS) SetAppMetadataArgsForCall(i int) (string, map[string]string, map[string]string) {
+	fake.setAppMetadataMutex.RLock()
+	defer fake.setAppMetadataMutex.RUnlock()
+	argsForCall := fake.setAppMetadataArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakePAAS) SetAppMetadataReturns(result1 error) {
+	fake.setAppMetadataMutex.Lock()
+	defer fake.setAppMetadataMutex.Unlock()
+	fake.SetAppMetadataStub = nil
+	fake.setAppMetadataReturns = struct {
 		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) SetAppMetadataReturnsOnCall(i int, result1 error) {
+	fake.setAppMetadataMutex.Lock()
+	defer fake.setAppMetadataMutex.Unlock()
+	fake.SetAppMetadataStub = nil
+	if fake.setAppMetadataReturnsOnCall == nil {
+		fake.setAppMetadataReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
 	}
-	loginReturnsOnCall map[int]struct {
+	fake.setAppMetadataReturnsOnCall[i] = struct {
 		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) SetSpaceAnnotation(arg1 string, arg2 string) error {
+	fake.setSpaceAnnotationMutex.Lock()
+	ret, specificReturn := fake.setSpaceAnnotationReturnsOnCall[len(fake.setSpaceAnnotationArgsForCall)]
+	fake.setSpaceAnnotationArgsForCall = append(fake.setSpaceAnnotationArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.SetSpaceAnnotationStub
+	fakeReturns := fake.setSpaceAnnotationReturns
+	fake.recordInvocation("SetSpaceAnnotation", []interface{}{arg1, arg2})
+	fake.setSpaceAnnotationMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
-	TargetStub        func(organization string, space string) error
-	targetMutex       sync.RWMutex
-	targetArgsForCall []struct {
-		organization string
-		space        string
+	if specificReturn {
+		return ret.result1
 	}
-	targetReturns struct {
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) SetSpaceAnnotationCallCount() int {
+	fake.setSpaceAnnotationMutex.RLock()
+	defer fake.setSpaceAnnotationMutex.RUnlock()
+	return len(fake.setSpaceAnnotationArgsForCall)
+}
+
+func (fake *FakePAAS) SetSpaceAnnotationCalls(stub func(string, string) error) {
+	fake.setSpaceAnnotationMutex.Lock()
+	defer fake.setSpaceAnnotationMutex.Unlock()
+	fake.SetSpaceAnnotationStub = stub
+}
+
+func (fake *FakePAAS) SetSpaceAnnotationArgsForCall(i int) (string, string) {
+	fake.setSpaceAnnotationMutex.RLock()
+	defer fake.setSpaceAnnotationMutex.RUnlock()
+	argsForCall := fake.setSpaceAnnotationArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePAAS) SetSpaceAnnotationReturns(result1 error) {
+	fake.setSpaceAnnotationMutex.Lock()
+	defer fake.setSpaceAnnotationMutex.Unlock()
+	fake.SetSpaceAnnotationStub = nil
+	fake.setSpaceAnnotationReturns = struct {
 		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) SetSpaceAnnotationReturnsOnCall(i int, result1 error) {
+	fake.setSpaceAnnotationMutex.Lock()
+	defer fake.setSpaceAnnotationMutex.Unlock()
+	fake.SetSpaceAnnotationStub = nil
+	if fake.setSpaceAnnotationReturnsOnCall == nil {
+		fake.setSpaceAnnotationReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
 	}
-	targetReturnsOnCall map[int]struct {
+	fake.setSpaceAnnotationReturnsOnCall[i] = struct {
 		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) SpaceAnnotation(arg1 string) (string, error) {
+	fake.spaceAnnotationMutex.Lock()
+	ret, specificReturn := fake.spaceAnnotationReturnsOnCall[len(fake.spaceAnnotationArgsForCall)]
+	fake.spaceAnnotationArgsForCall = append(fake.spaceAnnotationArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.SpaceAnnotationStub
+	fakeReturns := fake.spaceAnnotationReturns
+	fake.recordInvocation("SpaceAnnotation", []interface{}{arg1})
+	fake.spaceAnnotationMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
 	}
-	PushAppStub        func(manifest string, path string, currentAppName string, vars map[string]interface{}, varsFiles []string, dockerUser string, showLogs bool, noStart bool) error
-	pushAppMutex       sync.RWMutex
-	pushAppArgsForCall []struct {
-		manifest       string
-		path           string
-		currentAppName string
-		vars           map[string]interface{}
-		varsFiles      []string
-		dockerUser     string
-		showLogs       bool
-		noStart        bool
+	if specificReturn {
+		return ret.result1, ret.result2
 	}
-	pushAppReturns struct {
-		result1 error
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) SpaceAnnotationCallCount() int {
+	fake.spaceAnnotationMutex.RLock()
+	defer fake.spaceAnnotationMutex.RUnlock()
+	return len(fake.spaceAnnotationArgsForCall)
+}
+
+func (fake *FakePAAS) SpaceAnnotationCalls(stub func(string) (string, error)) {
+	fake.spaceAnnotationMutex.Lock()
+	defer fake.spaceAnnotationMutex.Unlock()
+	fake.SpaceAnnotationStub = stub
+}
+
+func (fake *FakePAAS) SpaceAnnotationArgsForCall(i int) string {
+	fake.spaceAnnotationMutex.RLock()
+	defer fake.spaceAnnotationMutex.RUnlock()
+	argsForCall := fake.spaceAnnotationArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) SpaceAnnotationReturns(result1 string, result2 error) {
+	fake.spaceAnnotationMutex.Lock()
+	defer fake.spaceAnnotationMutex.Unlock()
+	fake.SpaceAnnotationStub = nil
+	fake.spaceAnnotationReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) SpaceAnnotationReturnsOnCall(i int, result1 string, result2 error) {
+	fake.spaceAnnotationMutex.Lock()
+	defer fake.spaceAnnotationMutex.Unlock()
+	fake.SpaceAnnotationStub = nil
+	if fake.spaceAnnotationReturnsOnCall == nil {
+		fake.spaceAnnotationReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
 	}
-	pushAppReturnsOnCall map[int]struct {
+	fake.spaceAnnotationReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) SpaceApps() ([]out.SpaceApp, error) {
+	fake.spaceAppsMutex.Lock()
+	ret, specificReturn := fake.spaceAppsReturnsOnCall[len(fake.spaceAppsArgsForCall)]
+	fake.spaceAppsArgsForCall = append(fake.spaceAppsArgsForCall, struct {
+	}{})
+	stub := fake.SpaceAppsStub
+	fakeReturns := fake.spaceAppsReturns
+	fake.recordInvocation("SpaceApps", []interface{}{})
+	fake.spaceAppsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) SpaceAppsCallCount() int {
+	fake.spaceAppsMutex.RLock()
+	defer fake.spaceAppsMutex.RUnlock()
+	return len(fake.spaceAppsArgsForCall)
+}
+
+func (fake *FakePAAS) SpaceAppsCalls(stub func() ([]out.SpaceApp, error)) {
+	fake.spaceAppsMutex.Lock()
+	defer fake.spaceAppsMutex.Unlock()
+	fake.SpaceAppsStub = stub
+}
+
+func (fake *FakePAAS) SpaceAppsReturns(result1 []out.SpaceApp, result2 error) {
+	fake.spaceAppsMutex.Lock()
+	defer fake.spaceAppsMutex.Unlock()
+	fake.SpaceAppsStub = nil
+	fake.spaceAppsReturns = struct {
+		result1 []out.SpaceApp
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) SpaceAppsReturnsOnCall(i int, result1 []out.SpaceApp, result2 error) {
+	fake.spaceAppsMutex.Lock()
+	defer fake.spaceAppsMutex.Unlock()
+	fake.SpaceAppsStub = nil
+	if fake.spaceAppsReturnsOnCall == nil {
+		fake.spaceAppsReturnsOnCall = make(map[int]struct {
+			result1 []out.SpaceApp
+			result2 error
+		})
+	}
+	fake.spaceAppsReturnsOnCall[i] = struct {
+		result1 []out.SpaceApp
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) StartApp(arg1 string) error {
+	fake.startAppMutex.Lock()
+	ret, specificReturn := fake.startAppReturnsOnCall[len(fake.startAppArgsForCall)]
+	fake.startAppArgsForCall = append(fake.startAppArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.StartAppStub
+	fakeReturns := fake.startAppReturns
+	fake.recordInvocation("StartApp", []interface{}{arg1})
+	fake.startAppMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) StartAppCallCount() int {
+	fake.startAppMutex.RLock()
+	defer fake.startAppMutex.RUnlock()
+	return len(fake.startAppArgsForCall)
+}
+
+func (fake *FakePAAS) StartAppCalls(stub func(string) error) {
+	fake.startAppMutex.Lock()
+	defer fake.startAppMutex.Unlock()
+	fake.StartAppStub = stub
+}
+
+func (fake *FakePAAS) StartAppArgsForCall(i int) string {
+	fake.startAppMutex.RLock()
+	defer fake.startAppMutex.RUnlock()
+	argsForCall := fake.startAppArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) StartAppReturns(result1 error) {
+	fake.startAppMutex.Lock()
+	defer fake.startAppMutex.Unlock()
+	fake.StartAppStub = nil
+	fake.startAppReturns = struct {
 		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) StartAppReturnsOnCall(i int, result1 error) {
+	fake.startAppMutex.Lock()
+	defer fake.startAppMutex.Unlock()
+	fake.StartAppStub = nil
+	if fake.startAppReturnsOnCall == nil {
+		fake.startAppReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
 	}
-	invocations      map[string][][]interface{}
-	invocationsMutex sync.RWMutex
+	fake.startAppReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
 }
 
-func (fake *FakePAAS) Login(api string, username string, password string, clientID string, clientSecret string, insecure bool) error {
-	fake.loginMutex.Lock()
-	ret, specificReturn := fake.loginReturnsOnCall[len(fake.loginArgsForCall)]
-	fake.loginArgsForCall = append(fake.loginArgsForCall, struct {
-		api          string
-		username     string
-		password     string
-		clientID     string
-		clientSecret string
-		insecure     bool
-	}{api, username, password, clientID, clientSecret, insecure})
-	fake.recordInvocation("Login", []interface{}{api, username, password, clientID, clientSecret, insecure})
-	fake.loginMutex.Unlock()
-	if fake.LoginStub != nil {
-		return fake.LoginStub(api, username, password, clientID, clientSecret, insecure)
+func (fake *FakePAAS) StopApp(arg1 string) error {
+	fake.stopAppMutex.Lock()
+	ret, specificReturn := fake.stopAppReturnsOnCall[len(fake.stopAppArgsForCall)]
+	fake.stopAppArgsForCall = append(fake.stopAppArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.StopAppStub
+	fakeReturns := fake.stopAppReturns
+	fake.recordInvocation("StopApp", []interface{}{arg1})
+	fake.stopAppMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.loginReturns.result1
+	return fakeReturns.result1
 }
 
-func (fake *FakePAAS) LoginCallCount() int {
-	fake.loginMutex.RLock()
-	defer fake.loginMutex.RUnlock()
-	return len(fake.loginArgsForCall)
+func (fake *FakePAAS) StopAppCallCount() int {
+	fake.stopAppMutex.RLock()
+	defer fake.stopAppMutex.RUnlock()
+	return len(fake.stopAppArgsForCall)
 }
 
-func (fake *FakePAAS) LoginArgsForCall(i int) (string, string, string, string, string, bool) {
-	fake.loginMutex.RLock()
-	defer fake.loginMutex.RUnlock()
-	return fake.loginArgsForCall[i].api, fake.loginArgsForCall[i].username, fake.loginArgsForCall[i].password, fake.loginArgsForCall[i].clientID, fake.loginArgsForCall[i].clientSecret, fake.loginArgsForCall[i].insecure
+func (fake *FakePAAS) StopAppCalls(stub func(string) error) {
+	fake.stopAppMutex.Lock()
+	defer fake.stopAppMutex.Unlock()
+	fake.StopAppStub = stub
 }
 
-func (fake *FakePAAS) LoginReturns(result1 error) {
-	fake.LoginStub = nil
-	fake.loginReturns = struct {
+func (fake *FakePAAS) StopAppArgsForCall(i int) string {
+	fake.stopAppMutex.RLock()
+	defer fake.stopAppMutex.RUnlock()
+	argsForCall := fake.stopAppArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) StopAppReturns(result1 error) {
+	fake.stopAppMutex.Lock()
+	defer fake.stopAppMutex.Unlock()
+	fake.StopAppStub = nil
+	fake.stopAppReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakePAAS) LoginReturnsOnCall(i int, result1 error) {
-	fake.LoginStub = nil
-	if fake.loginReturnsOnCall == nil {
-		fake.loginReturnsOnCall = make(map[int]struct {
+func (fake *FakePAAS) StopAppReturnsOnCall(i int, result1 error) {
+	fake.stopAppMutex.Lock()
+	defer fake.stopAppMutex.Unlock()
+	fake.StopAppStub = nil
+	if fake.stopAppReturnsOnCall == nil {
+		fake.stopAppReturnsOnCall = make(map[int]struct {
 			result1 error
 		})
 	}
-	fake.loginReturnsOnCall[i] = struct {
+	fake.stopAppReturnsOnCall[i] = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakePAAS) Target(organization string, space string) error {
+func (fake *FakePAAS) Target(arg1 string, arg2 string) error {
 	fake.targetMutex.Lock()
 	ret, specificReturn := fake.targetReturnsOnCall[len(fake.targetArgsForCall)]
 	fake.targetArgsForCall = append(fake.targetArgsForCall, struct {
-		organization string
-		space        string
-	}{organization, space})
-	fake.recordInvocation("Target", []interface{}{organization, space})
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.TargetStub
+	fakeReturns := fake.targetReturns
+	fake.recordInvocation("Target", []interface{}{arg1, arg2})
 	fake.targetMutex.Unlock()
-	if fake.TargetStub != nil {
-		return fake.TargetStub(organization, space)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.targetReturns.result1
+	return fakeReturns.result1
 }
 
 func (fake *FakePAAS) TargetCallCount() int {
@@ -135,13 +1803,22 @@ func (fake *FakePAAS) TargetCallCount() int {
 	return len(fake.targetArgsForCall)
 }
 
+func (fake *FakePAAS) TargetCalls(stub func(string, string) error) {
+	fake.targetMutex.Lock()
+	defer fake.targetMutex.Unlock()
+	fake.TargetStub = stub
+}
+
 func (fake *FakePAAS) TargetArgsForCall(i int) (string, string) {
 	fake.targetMutex.RLock()
 	defer fake.targetMutex.RUnlock()
-	return fake.targetArgsForCall[i].organization, fake.targetArgsForCall[i].space
+	argsForCall := fake.targetArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakePAAS) TargetReturns(result1 error) {
+	fake.targetMutex.Lock()
+	defer fake.targetMutex.Unlock()
 	fake.TargetStub = nil
 	fake.targetReturns = struct {
 		result1 error
@@ -149,6 +1826,8 @@ func (fake *FakePAAS) TargetReturns(result1 error) {
 }
 
 func (fake *FakePAAS) TargetReturnsOnCall(i int, result1 error) {
+	fake.targetMutex.Lock()
+	defer fake.targetMutex.Unlock()
 	fake.TargetStub = nil
 	if fake.targetReturnsOnCall == nil {
 		fake.targetReturnsOnCall = make(map[int]struct {
@@ -160,75 +1839,496 @@ func (fake *FakePAAS) TargetReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakePAAS) PushApp(manifest string, path string, currentAppName string, vars map[string]interface{}, varsFiles []string, dockerUser string, showLogs bool, noStart bool) error {
-	var varsFilesCopy []string
-	if varsFiles != nil {
-		varsFilesCopy = make([]string, len(varsFiles))
-		copy(varsFilesCopy, varsFiles)
+func (fake *FakePAAS) TaskLogs(arg1 string, arg2 string) ([]string, error) {
+	fake.taskLogsMutex.Lock()
+	ret, specificReturn := fake.taskLogsReturnsOnCall[len(fake.taskLogsArgsForCall)]
+	fake.taskLogsArgsForCall = append(fake.taskLogsArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.TaskLogsStub
+	fakeReturns := fake.taskLogsReturns
+	fake.recordInvocation("TaskLogs", []interface{}{arg1, arg2})
+	fake.taskLogsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
-	fake.pushAppMutex.Lock()
-	ret, specificReturn := fake.pushAppReturnsOnCall[len(fake.pushAppArgsForCall)]
-	fake.pushAppArgsForCall = append(fake.pushAppArgsForCall, struct {
-		manifest       string
-		path           string
-		currentAppName string
-		vars           map[string]interface{}
-		varsFiles      []string
-		dockerUser     string
-		showLogs       bool
-		noStart        bool
-	}{manifest, path, currentAppName, vars, varsFilesCopy, dockerUser, showLogs, noStart})
-	fake.recordInvocation("PushApp", []interface{}{manifest, path, currentAppName, vars, varsFilesCopy, dockerUser, showLogs, noStart})
-	fake.pushAppMutex.Unlock()
-	if fake.PushAppStub != nil {
-		return fake.PushAppStub(manifest, path, currentAppName, vars, varsFiles, dockerUser, showLogs, noStart)
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) TaskLogsCallCount() int {
+	fake.taskLogsMutex.RLock()
+	defer fake.taskLogsMutex.RUnlock()
+	return len(fake.taskLogsArgsForCall)
+}
+
+func (fake *FakePAAS) TaskLogsCalls(stub func(string, string) ([]string, error)) {
+	fake.taskLogsMutex.Lock()
+	defer fake.taskLogsMutex.Unlock()
+	fake.TaskLogsStub = stub
+}
+
+func (fake *FakePAAS) TaskLogsArgsForCall(i int) (string, string) {
+	fake.taskLogsMutex.RLock()
+	defer fake.taskLogsMutex.RUnlock()
+	argsForCall := fake.taskLogsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePAAS) TaskLogsReturns(result1 []string, result2 error) {
+	fake.taskLogsMutex.Lock()
+	defer fake.taskLogsMutex.Unlock()
+	fake.TaskLogsStub = nil
+	fake.taskLogsReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) TaskLogsReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.taskLogsMutex.Lock()
+	defer fake.taskLogsMutex.Unlock()
+	fake.TaskLogsStub = nil
+	if fake.taskLogsReturnsOnCall == nil {
+		fake.taskLogsReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.taskLogsReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) TaskState(arg1 string, arg2 int) (out.TaskState, error) {
+	fake.taskStateMutex.Lock()
+	ret, specificReturn := fake.taskStateReturnsOnCall[len(fake.taskStateArgsForCall)]
+	fake.taskStateArgsForCall = append(fake.taskStateArgsForCall, struct {
+		arg1 string
+		arg2 int
+	}{arg1, arg2})
+	stub := fake.TaskStateStub
+	fakeReturns := fake.taskStateReturns
+	fake.recordInvocation("TaskState", []interface{}{arg1, arg2})
+	fake.taskStateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) TaskStateCallCount() int {
+	fake.taskStateMutex.RLock()
+	defer fake.taskStateMutex.RUnlock()
+	return len(fake.taskStateArgsForCall)
+}
+
+func (fake *FakePAAS) TaskStateCalls(stub func(string, int) (out.TaskState, error)) {
+	fake.taskStateMutex.Lock()
+	defer fake.taskStateMutex.Unlock()
+	fake.TaskStateStub = stub
+}
+
+func (fake *FakePAAS) TaskStateArgsForCall(i int) (string, int) {
+	fake.taskStateMutex.RLock()
+	defer fake.taskStateMutex.RUnlock()
+	argsForCall := fake.taskStateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePAAS) TaskStateReturns(result1 out.TaskState, result2 error) {
+	fake.taskStateMutex.Lock()
+	defer fake.taskStateMutex.Unlock()
+	fake.TaskStateStub = nil
+	fake.taskStateReturns = struct {
+		result1 out.TaskState
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) TaskStateReturnsOnCall(i int, result1 out.TaskState, result2 error) {
+	fake.taskStateMutex.Lock()
+	defer fake.taskStateMutex.Unlock()
+	fake.TaskStateStub = nil
+	if fake.taskStateReturnsOnCall == nil {
+		fake.taskStateReturnsOnCall = make(map[int]struct {
+			result1 out.TaskState
+			result2 error
+		})
+	}
+	fake.taskStateReturnsOnCall[i] = struct {
+		result1 out.TaskState
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) TerminateTask(arg1 string, arg2 int) error {
+	fake.terminateTaskMutex.Lock()
+	ret, specificReturn := fake.terminateTaskReturnsOnCall[len(fake.terminateTaskArgsForCall)]
+	fake.terminateTaskArgsForCall = append(fake.terminateTaskArgsForCall, struct {
+		arg1 string
+		arg2 int
+	}{arg1, arg2})
+	stub := fake.TerminateTaskStub
+	fakeReturns := fake.terminateTaskReturns
+	fake.recordInvocation("TerminateTask", []interface{}{arg1, arg2})
+	fake.terminateTaskMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	return fake.pushAppReturns.result1
+	return fakeReturns.result1
 }
 
-func (fake *FakePAAS) PushAppCallCount() int {
-	fake.pushAppMutex.RLock()
-	defer fake.pushAppMutex.RUnlock()
-	return len(fake.pushAppArgsForCall)
+func (fake *FakePAAS) TerminateTaskCallCount() int {
+	fake.terminateTaskMutex.RLock()
+	defer fake.terminateTaskMutex.RUnlock()
+	return len(fake.terminateTaskArgsForCall)
 }
 
-func (fake *FakePAAS) PushAppArgsForCall(i int) (string, string, string, map[string]interface{}, []string, string, bool, bool) {
-	fake.pushAppMutex.RLock()
-	defer fake.pushAppMutex.RUnlock()
-	return fake.pushAppArgsForCall[i].manifest, fake.pushAppArgsForCall[i].path, fake.pushAppArgsForCall[i].currentAppName, fake.pushAppArgsForCall[i].vars, fake.pushAppArgsForCall[i].varsFiles, fake.pushAppArgsForCall[i].dockerUser, fake.pushAppArgsForCall[i].showLogs, fake.pushAppArgsForCall[i].noStart
+func (fake *FakePAAS) TerminateTaskCalls(stub func(string, int) error) {
+	fake.terminateTaskMutex.Lock()
+	defer fake.terminateTaskMutex.Unlock()
+	fake.TerminateTaskStub = stub
 }
 
-func (fake *FakePAAS) PushAppReturns(result1 error) {
-	fake.PushAppStub = nil
-	fake.pushAppReturns = struct {
+func (fake *FakePAAS) TerminateTaskArgsForCall(i int) (string, int) {
+	fake.terminateTaskMutex.RLock()
+	defer fake.terminateTaskMutex.RUnlock()
+	argsForCall := fake.terminateTaskArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePAAS) TerminateTaskReturns(result1 error) {
+	fake.terminateTaskMutex.Lock()
+	defer fake.terminateTaskMutex.Unlock()
+	fake.TerminateTaskStub = nil
+	fake.terminateTaskReturns = struct {
 		result1 error
 	}{result1}
 }
 
-func (fake *FakePAAS) PushAppReturnsOnCall(i int, result1 error) {
-	fake.PushAppStub = nil
-	if fake.pushAppReturnsOnCall == nil {
-		fake.pushAppReturnsOnCall = make(map[int]struct {
+func (fake *FakePAAS) TerminateTaskReturnsOnCall(i int, result1 error) {
+	fake.terminateTaskMutex.Lock()
+	defer fake.terminateTaskMutex.Unlock()
+	fake.TerminateTaskStub = nil
+	if fake.terminateTaskReturnsOnCall == nil {
+		fake.terminateTaskReturnsOnCall = make(map[int]struct {
 			result1 error
 		})
 	}
-	fake.pushAppReturnsOnCall[i] = struct {
+	fake.terminateTaskReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) UnmapRoute(arg1 string, arg2 out.Route) error {
+	fake.unmapRouteMutex.Lock()
+	ret, specificReturn := fake.unmapRouteReturnsOnCall[len(fake.unmapRouteArgsForCall)]
+	fake.unmapRouteArgsForCall = append(fake.unmapRouteArgsForCall, struct {
+		arg1 string
+		arg2 out.Route
+	}{arg1, arg2})
+	stub := fake.UnmapRouteStub
+	fakeReturns := fake.unmapRouteReturns
+	fake.recordInvocation("UnmapRoute", []interface{}{arg1, arg2})
+	fake.unmapRouteMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) UnmapRouteCallCount() int {
+	fake.unmapRouteMutex.RLock()
+	defer fake.unmapRouteMutex.RUnlock()
+	return len(fake.unmapRouteArgsForCall)
+}
+
+func (fake *FakePAAS) UnmapRouteCalls(stub func(string, out.Route) error) {
+	fake.unmapRouteMutex.Lock()
+	defer fake.unmapRouteMutex.Unlock()
+	fake.UnmapRouteStub = stub
+}
+
+func (fake *FakePAAS) UnmapRouteArgsForCall(i int) (string, out.Route) {
+	fake.unmapRouteMutex.RLock()
+	defer fake.unmapRouteMutex.RUnlock()
+	argsForCall := fake.unmapRouteArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakePAAS) UnmapRouteReturns(result1 error) {
+	fake.unmapRouteMutex.Lock()
+	defer fake.unmapRouteMutex.Unlock()
+	fake.UnmapRouteStub = nil
+	fake.unmapRouteReturns = struct {
 		result1 error
 	}{result1}
 }
 
+func (fake *FakePAAS) UnmapRouteReturnsOnCall(i int, result1 error) {
+	fake.unmapRouteMutex.Lock()
+	defer fake.unmapRouteMutex.Unlock()
+	fake.UnmapRouteStub = nil
+	if fake.unmapRouteReturnsOnCall == nil {
+		fake.unmapRouteReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.unmapRouteReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) UnmappedRoutes() ([]out.SpaceRoute, error) {
+	fake.unmappedRoutesMutex.Lock()
+	ret, specificReturn := fake.unmappedRoutesReturnsOnCall[len(fake.unmappedRoutesArgsForCall)]
+	fake.unmappedRoutesArgsForCall = append(fake.unmappedRoutesArgsForCall, struct {
+	}{})
+	stub := fake.UnmappedRoutesStub
+	fakeReturns := fake.unmappedRoutesReturns
+	fake.recordInvocation("UnmappedRoutes", []interface{}{})
+	fake.unmappedRoutesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) UnmappedRoutesCallCount() int {
+	fake.unmappedRoutesMutex.RLock()
+	defer fake.unmappedRoutesMutex.RUnlock()
+	return len(fake.unmappedRoutesArgsForCall)
+}
+
+func (fake *FakePAAS) UnmappedRoutesCalls(stub func() ([]out.SpaceRoute, error)) {
+	fake.unmappedRoutesMutex.Lock()
+	defer fake.unmappedRoutesMutex.Unlock()
+	fake.UnmappedRoutesStub = stub
+}
+
+func (fake *FakePAAS) UnmappedRoutesReturns(result1 []out.SpaceRoute, result2 error) {
+	fake.unmappedRoutesMutex.Lock()
+	defer fake.unmappedRoutesMutex.Unlock()
+	fake.UnmappedRoutesStub = nil
+	fake.unmappedRoutesReturns = struct {
+		result1 []out.SpaceRoute
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) UnmappedRoutesReturnsOnCall(i int, result1 []out.SpaceRoute, result2 error) {
+	fake.unmappedRoutesMutex.Lock()
+	defer fake.unmappedRoutesMutex.Unlock()
+	fake.UnmappedRoutesStub = nil
+	if fake.unmappedRoutesReturnsOnCall == nil {
+		fake.unmappedRoutesReturnsOnCall = make(map[int]struct {
+			result1 []out.SpaceRoute
+			result2 error
+		})
+	}
+	fake.unmappedRoutesReturnsOnCall[i] = struct {
+		result1 []out.SpaceRoute
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) UpdateService(arg1 out.Service) error {
+	fake.updateServiceMutex.Lock()
+	ret, specificReturn := fake.updateServiceReturnsOnCall[len(fake.updateServiceArgsForCall)]
+	fake.updateServiceArgsForCall = append(fake.updateServiceArgsForCall, struct {
+		arg1 out.Service
+	}{arg1})
+	stub := fake.UpdateServiceStub
+	fakeReturns := fake.updateServiceReturns
+	fake.recordInvocation("UpdateService", []interface{}{arg1})
+	fake.updateServiceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakePAAS) UpdateServiceCallCount() int {
+	fake.updateServiceMutex.RLock()
+	defer fake.updateServiceMutex.RUnlock()
+	return len(fake.updateServiceArgsForCall)
+}
+
+func (fake *FakePAAS) UpdateServiceCalls(stub func(out.Service) error) {
+	fake.updateServiceMutex.Lock()
+	defer fake.updateServiceMutex.Unlock()
+	fake.UpdateServiceStub = stub
+}
+
+func (fake *FakePAAS) UpdateServiceArgsForCall(i int) out.Service {
+	fake.updateServiceMutex.RLock()
+	defer fake.updateServiceMutex.RUnlock()
+	argsForCall := fake.updateServiceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakePAAS) UpdateServiceReturns(result1 error) {
+	fake.updateServiceMutex.Lock()
+	defer fake.updateServiceMutex.Unlock()
+	fake.UpdateServiceStub = nil
+	fake.updateServiceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) UpdateServiceReturnsOnCall(i int, result1 error) {
+	fake.updateServiceMutex.Lock()
+	defer fake.updateServiceMutex.Unlock()
+	fake.UpdateServiceStub = nil
+	if fake.updateServiceReturnsOnCall == nil {
+		fake.updateServiceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.updateServiceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePAAS) Version() (cli.Version, error) {
+	fake.versionMutex.Lock()
+	ret, specificReturn := fake.versionReturnsOnCall[len(fake.versionArgsForCall)]
+	fake.versionArgsForCall = append(fake.versionArgsForCall, struct {
+	}{})
+	stub := fake.VersionStub
+	fakeReturns := fake.versionReturns
+	fake.recordInvocation("Version", []interface{}{})
+	fake.versionMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakePAAS) VersionCallCount() int {
+	fake.versionMutex.RLock()
+	defer fake.versionMutex.RUnlock()
+	return len(fake.versionArgsForCall)
+}
+
+func (fake *FakePAAS) VersionCalls(stub func() (cli.Version, error)) {
+	fake.versionMutex.Lock()
+	defer fake.versionMutex.Unlock()
+	fake.VersionStub = stub
+}
+
+func (fake *FakePAAS) VersionReturns(result1 cli.Version, result2 error) {
+	fake.versionMutex.Lock()
+	defer fake.versionMutex.Unlock()
+	fake.VersionStub = nil
+	fake.versionReturns = struct {
+		result1 cli.Version
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakePAAS) VersionReturnsOnCall(i int, result1 cli.Version, result2 error) {
+	fake.versionMutex.Lock()
+	defer fake.versionMutex.Unlock()
+	fake.VersionStub = nil
+	if fake.versionReturnsOnCall == nil {
+		fake.versionReturnsOnCall = make(map[int]struct {
+			result1 cli.Version
+			result2 error
+		})
+	}
+	fake.versionReturnsOnCall[i] = struct {
+		result1 cli.Version
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakePAAS) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.appAnnotationsMutex.RLock()
+	defer fake.appAnnotationsMutex.RUnlock()
+	fake.createServiceMutex.RLock()
+	defer fake.createServiceMutex.RUnlock()
+	fake.deleteAppMutex.RLock()
+	defer fake.deleteAppMutex.RUnlock()
+	fake.deleteRouteMutex.RLock()
+	defer fake.deleteRouteMutex.RUnlock()
+	fake.deleteServiceMutex.RLock()
+	defer fake.deleteServiceMutex.RUnlock()
+	fake.detachedMutex.RLock()
+	defer fake.detachedMutex.RUnlock()
+	fake.getAppMutex.RLock()
+	defer fake.getAppMutex.RUnlock()
+	fake.instanceStatesMutex.RLock()
+	defer fake.instanceStatesMutex.RUnlock()
 	fake.loginMutex.RLock()
 	defer fake.loginMutex.RUnlock()
-	fake.targetMutex.RLock()
-	defer fake.targetMutex.RUnlock()
+	fake.mapRouteMutex.RLock()
+	defer fake.mapRouteMutex.RUnlock()
 	fake.pushAppMutex.RLock()
 	defer fake.pushAppMutex.RUnlock()
+	fake.restageAppMutex.RLock()
+	defer fake.restageAppMutex.RUnlock()
+	fake.restartAppMutex.RLock()
+	defer fake.restartAppMutex.RUnlock()
+	fake.routeInOtherSpaceMutex.RLock()
+	defer fake.routeInOtherSpaceMutex.RUnlock()
+	fake.runTaskMutex.RLock()
+	defer fake.runTaskMutex.RUnlock()
+	fake.serviceInstanceMutex.RLock()
+	defer fake.serviceInstanceMutex.RUnlock()
+	fake.setAppMetadataMutex.RLock()
+	defer fake.setAppMetadataMutex.RUnlock()
+	fake.setSpaceAnnotationMutex.RLock()
+	defer fake.setSpaceAnnotationMutex.RUnlock()
+	fake.spaceAnnotationMutex.RLock()
+	defer fake.spaceAnnotationMutex.RUnlock()
+	fake.spaceAppsMutex.RLock()
+	defer fake.spaceAppsMutex.RUnlock()
+	fake.startAppMutex.RLock()
+	defer fake.startAppMutex.RUnlock()
+	fake.stopAppMutex.RLock()
+	defer fake.stopAppMutex.RUnlock()
+	fake.targetMutex.RLock()
+	defer fake.targetMutex.RUnlock()
+	fake.taskLogsMutex.RLock()
+	defer fake.taskLogsMutex.RUnlock()
+	fake.taskStateMutex.RLock()
+	defer fake.taskStateMutex.RUnlock()
+	fake.terminateTaskMutex.RLock()
+	defer fake.terminateTaskMutex.RUnlock()
+	fake.unmapRouteMutex.RLock()
+	defer fake.unmapRouteMutex.RUnlock()
+	fake.unmappedRoutesMutex.RLock()
+	defer fake.unmappedRoutesMutex.RUnlock()
+	fake.updateServiceMutex.RLock()
+	defer fake.updateServiceMutex.RUnlock()
+	fake.versionMutex.RLock()
+	defer fake.versionMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
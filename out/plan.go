@@ -0,0 +1,294 @@
+package out
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+type AppPlan struct {
+	Name    string
+	Exists  bool
+	Changes []string
+}
+
+type Plan struct {
+	Apps  []AppPlan
+	Steps []string
+}
+
+// diffApp lists how pushing manifestApp would change the deployed app. When
+// replace is true the app is pushed from scratch, as a zero downtime deploy
+// does, so anything not in the manifest is lost; otherwise cf push leaves
+// env vars, routes and services that aren't in the manifest alone.
+func diffApp(manifestApp ManifestApp, deployed App, replace bool) []string {
+	var changes []string
+
+	if manifestApp.Instances != nil && *manifestApp.Instances != deployed.Instances {
+		changes = append(changes, fmt.Sprintf("instances: %d -> %d", deployed.Instances, *manifestApp.Instances))
+	} else if manifestApp.Instances == nil && replace && deployed.Instances != 1 {
+		changes = append(changes, fmt.Sprintf("instances: %d -> 1 (default)", deployed.Instances))
+	}
+
+	changes = append(changes, diffSize("memory", manifestApp.Memory, deployed.MemoryMB, replace)...)
+	changes = append(changes, diffSize("disk", manifestApp.DiskQuota, deployed.DiskMB, replace)...)
+
+	if len(manifestApp.Buildpacks) > 0 && strings.Join(manifestApp.Buildpacks, ",") != strings.Join(deployed.Buildpacks, ",") {
+		changes = append(changes, fmt.Sprintf("buildpacks: [%s] -> [%s]",
+			strings.Join(deployed.Buildpacks, ", "),
+			strings.Join(manifestApp.Buildpacks, ", "),
+		))
+	}
+
+	// only keys are shown, values may be secrets
+	for _, key := range sortedKeys(manifestApp.Env) {
+		deployedValue, found := deployed.Env[key]
+		if !found {
+			changes = append(changes, "env: + "+key)
+		} else if deployedValue != manifestApp.Env[key] {
+			changes = append(changes, "env: ~ "+key)
+		}
+	}
+	if replace {
+		for _, key := range sortedKeys(deployed.Env) {
+			if _, found := manifestApp.Env[key]; !found {
+				changes = append(changes, "env: - "+key)
+			}
+		}
+	}
+
+	changes = append(changes, diffList("routes", manifestApp.Routes, deployed.Routes, replace)...)
+	changes = append(changes, diffList("services", manifestApp.Services, deployed.Services, replace)...)
+
+	return changes
+}
+
+func diffSize(name string, size string, deployedMB int, replace bool) []string {
+	if size == "" {
+		if replace {
+			return []string{fmt.Sprintf("%s: %dM -> platform default", name, deployedMB)}
+		}
+		return nil
+	}
+
+	mb, err := megabytes(size)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %dM -> %s (%s)", name, deployedMB, size, err)}
+	}
+
+	if mb == deployedMB {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %dM -> %dM", name, deployedMB, mb)}
+}
+
+func diffList(name string, desired []string, deployed []string, replace bool) []string {
+	var changes []string
+
+	for _, item := range desired {
+		if !contains(deployed, item) {
+			changes = append(changes, fmt.Sprintf("%s: + %s", name, item))
+		}
+	}
+
+	if replace {
+		for _, item := range deployed {
+			if !contains(desired, item) {
+				changes = append(changes, fmt.Sprintf("%s: - %s", name, item))
+			}
+		}
+	}
+
+	return changes
+}
+
+func contains(list []string, item string) bool {
+	for _, candidate := range list {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pushedApps returns the manifest apps that cf push will push with these
+// params.
+func pushedApps(params Params, manifest Manifest) []ManifestApp {
+	var manifestApps []ManifestApp
+	for _, app := range manifest.Applications() {
+		// cf push with an app name only pushes that app from the manifest
+		if params.CurrentAppName == "" || app.Name == params.CurrentAppName {
+			manifestApps = append(manifestApps, app)
+		}
+	}
+
+	// ...or, when the manifest has a single app, pushes it under that name
+	if params.CurrentAppName != "" && len(manifestApps) == 0 && len(manifest.Applications()) == 1 {
+		app := manifest.Applications()[0]
+		app.Name = params.CurrentAppName
+		manifestApps = append(manifestApps, app)
+	}
+
+	return manifestApps
+}
+
+// plan works out what a put with these params would do, reading but never
+// changing the deployed apps.
+func (command *Command) plan(params Params, manifest Manifest) (Plan, error) {
+	manifestApps := pushedApps(params, manifest)
+
+	names := make([]string, len(manifestApps))
+	for i, app := range manifestApps {
+		names[i] = app.Name
+	}
+
+	if !params.pushes() {
+		steps := actionSteps(params.Action, names)
+		if params.Action == ActionDestroy {
+			steps = append(steps, reviewSteps(params.Review, params.Services)...)
+		}
+		return Plan{Steps: steps}, nil
+	}
+
+	plan := Plan{}
+	zdtApp := ""
+
+	for _, manifestApp := range manifestApps {
+		appPlan := AppPlan{Name: manifestApp.Name}
+
+		deployed, err := command.paas.GetApp(manifestApp.Name)
+		if cli.Is(err, cli.AppNotFound) {
+			plan.Apps = append(plan.Apps, appPlan)
+			continue
+		}
+		if err != nil {
+			return Plan{}, err
+		}
+
+		replace := params.Strategy == "" && manifestApp.Name == params.CurrentAppName
+		if replace {
+			zdtApp = manifestApp.Name
+		}
+
+		appPlan.Exists = true
+		appPlan.Changes = diffApp(manifestApp, deployed, replace)
+		plan.Apps = append(plan.Apps, appPlan)
+	}
+
+	switch {
+	case params.Strategy == StrategyRolling:
+		plan.Steps = []string{fmt.Sprintf("push %s with a rolling deployment", strings.Join(names, ", "))}
+	case zdtApp != "":
+		plan.Steps = []string{
+			fmt.Sprintf("rename %s to %s-venerable", zdtApp, zdtApp),
+			fmt.Sprintf("push %s", zdtApp),
+		}
+		plan.Steps = append(plan.Steps, taskSteps(params.Tasks.BeforeSwitch, names)...)
+		plan.Steps = append(plan.Steps, fmt.Sprintf("delete %s-venerable", zdtApp))
+	case params.NoStart:
+		plan.Steps = []string{fmt.Sprintf("push %s without starting", strings.Join(names, ", "))}
+	default:
+		plan.Steps = []string{fmt.Sprintf("push %s", strings.Join(names, ", "))}
+	}
+
+	serviceSteps, err := command.serviceSteps(params.Services)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan.Steps = append(serviceSteps, plan.Steps...)
+
+	if zdtApp == "" || params.Strategy == StrategyRolling {
+		plan.Steps = append(plan.Steps, taskSteps(params.Tasks.BeforeSwitch, names)...)
+	}
+	plan.Steps = append(plan.Steps, routeSteps(params.Routes, names)...)
+	plan.Steps = append(plan.Steps, taskSteps(params.Tasks.AfterPush, names)...)
+
+	return plan, nil
+}
+
+func (command *Command) serviceSteps(services []Service) ([]string, error) {
+	var steps []string
+	for _, service := range services {
+		_, err := command.paas.ServiceInstance(service.Name)
+		switch {
+		case cli.Is(err, cli.ResourceNotFound) && service.Type() == ServiceUserProvided:
+			steps = append(steps, fmt.Sprintf("create user-provided service %s", service.Name))
+		case cli.Is(err, cli.ResourceNotFound):
+			steps = append(steps, fmt.Sprintf("create service %s (%s %s)", service.Name, service.Offering, service.Plan))
+		case err != nil:
+			return nil, err
+		default:
+			steps = append(steps, fmt.Sprintf("update service %s", service.Name))
+		}
+	}
+	return steps, nil
+}
+
+func routeSteps(routes Routes, apps []string) []string {
+	var steps []string
+	for _, route := range routes.Map {
+		app := route.App
+		if app == "" {
+			app = apps[0]
+		}
+		steps = append(steps, fmt.Sprintf("map route %s to %s", route.URL(), app))
+	}
+	for _, route := range routes.Unmap {
+		app := route.App
+		if app == "" {
+			app = apps[0]
+		}
+		steps = append(steps, fmt.Sprintf("unmap route %s from %s", route.URL(), app))
+	}
+	return steps
+}
+
+func taskSteps(tasks []Task, apps []string) []string {
+	steps := make([]string, len(tasks))
+	for i, task := range tasks {
+		app := task.App
+		if app == "" {
+			app = apps[0]
+		}
+		steps[i] = fmt.Sprintf("run task on %s: %s", app, task.Command)
+	}
+	return steps
+}
+
+func (plan Plan) Write(w io.Writer) {
+	fmt.Fprintln(w, "Dry run: nothing will be changed.")
+
+	for _, app := range plan.Apps {
+		fmt.Fprintln(w)
+		if !app.Exists {
+			fmt.Fprintf(w, "%s: will be created\n", app.Name)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s:\n", app.Name)
+		if len(app.Changes) == 0 {
+			fmt.Fprintln(w, "  no changes")
+		}
+		for _, change := range app.Changes {
+			fmt.Fprintf(w, "  %s\n", change)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Planned steps:")
+	for i, step := range plan.Steps {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, step)
+	}
+}
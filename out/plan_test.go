@@ -0,0 +1,143 @@
+package out_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Dry run", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		request      out.Request
+		command      *out.Command
+		log          *gbytes.Buffer
+		deployed     out.App
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+
+		deployed = out.App{
+			Name:       "from-vars-file",
+			GUID:       "app-guid",
+			Instances:  2,
+			MemoryMB:   512,
+			DiskMB:     1024,
+			Buildpacks: []string{"go_buildpack"},
+			Env:        map[string]string{"GREETING": "hello world", "OLD": "value"},
+			Routes:     []string{"from-vars-file.example.com", "legacy.example.com"},
+			Services:   []string{"cache"},
+		}
+		cloudFoundry.GetAppReturns(deployed, nil)
+
+		request = newRequest()
+		request.Params.ManifestPath = "assets/varsManifest.yml"
+		request.Params.Vars = map[string]interface{}{"instances": float64(4), "name": "world"}
+		request.Params.VarsFiles = []string{"assets/vars.yml"}
+		request.Params.EnvironmentVariables = out.EnvironmentVariables{
+			Global: map[string]interface{}{
+				"DB_PASSWORD": "not-this-one",
+				"NEW_KEY":     "new-secret-value",
+			},
+		}
+		request.Params.DryRun = true
+	})
+
+	It("logs in and targets but doesn't push", func() {
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(1))
+		Expect(cloudFoundry.TargetCallCount()).To(Equal(1))
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "dry_run", Value: "true"}))
+	})
+
+	It("compares the interpolated manifest with the deployed app", func() {
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.GetAppArgsForCall(0)).To(Equal("from-vars-file"))
+
+		Expect(log).To(gbytes.Say("Dry run: nothing will be changed."))
+		Expect(log).To(gbytes.Say(`from-vars-file:\n`))
+		Expect(log).To(gbytes.Say(`instances: 2 -> 4\n`))
+		Expect(log).To(gbytes.Say(`memory: 512M -> 1024M\n`))
+		Expect(log).To(gbytes.Say(`env: \+ DB_PASSWORD\n`))
+		Expect(log).To(gbytes.Say(`env: \+ NEW_KEY\n`))
+		Expect(log).To(gbytes.Say(`services: \+ database\n`))
+		Expect(log).To(gbytes.Say("Planned steps:\n  1. push from-vars-file\n"))
+
+		By("leaving out things cf push doesn't remove")
+		Expect(log.Contents()).NotTo(ContainSubstring("OLD"))
+		Expect(log.Contents()).NotTo(ContainSubstring("legacy.example.com"))
+
+		By("never showing env values")
+		Expect(log.Contents()).NotTo(ContainSubstring("new-secret-value"))
+		Expect(log.Contents()).NotTo(ContainSubstring("s3cret"))
+	})
+
+	It("doesn't write to the manifest", func() {
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		manifest, err := out.NewManifest("assets/varsManifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Applications()[0].Env).NotTo(HaveKey("NEW_KEY"))
+	})
+
+	It("plans a zero downtime deploy as a replacement", func() {
+		request.Params.CurrentAppName = "from-vars-file"
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(log).To(gbytes.Say(`env: - OLD\n`))
+		Expect(log).To(gbytes.Say(`routes: - legacy.example.com\n`))
+		Expect(log).To(gbytes.Say(`services: \+ database\n`))
+		Expect(log).To(gbytes.Say(`services: - cache\n`))
+		Expect(log).To(gbytes.Say("Planned steps:\n" +
+			"  1. rename from-vars-file to from-vars-file-venerable\n" +
+			"  2. push from-vars-file\n" +
+			"  3. delete from-vars-file-venerable\n",
+		))
+	})
+
+	It("plans a rolling deployment", func() {
+		request.Params.CurrentAppName = "from-vars-file"
+		request.Params.Strategy = "rolling"
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(log).To(gbytes.Say("  1. push from-vars-file with a rolling deployment\n"))
+	})
+
+	It("says when an app will be created", func() {
+		cloudFoundry.GetAppReturns(out.App{}, cli.Classify([]string{"app"}, "App 'from-vars-file' not found.", errors.New("exit status 1")))
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(log).To(gbytes.Say("from-vars-file: will be created\n"))
+		Expect(log).To(gbytes.Say("  1. push from-vars-file\n"))
+	})
+
+	It("fails when the deployed app can't be read", func() {
+		cloudFoundry.GetAppReturns(out.App{}, errors.New("boom"))
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("boom"))
+	})
+})
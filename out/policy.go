@@ -0,0 +1,141 @@
+package out
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/concourse/cf-resource"
+)
+
+// PolicyError lists every way a put breaks source.policy.
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "policy violations:\n  " + strings.Join(e.Violations, "\n  ")
+}
+
+// loadPolicy reads the policy from its file, if it was given as one.
+func loadPolicy(policy resource.Policy) (resource.Policy, error) {
+	if policy.File == "" {
+		return policy, nil
+	}
+
+	yamlData, err := ioutil.ReadFile(policy.File)
+	if err != nil {
+		return resource.Policy{}, fmt.Errorf("reading policy: %s", err)
+	}
+
+	var loaded resource.Policy
+	if err := yaml.Unmarshal(yamlData, &loaded); err != nil {
+		return resource.Policy{}, fmt.Errorf("reading policy %s: %s", policy.File, err)
+	}
+	return loaded, nil
+}
+
+// checkPolicy checks the interpolated manifest and the request against the
+// policy. Settings the manifest leaves to the platform, such as memory or
+// the stack, break a policy that limits them, since the platform default
+// can't be checked.
+func checkPolicy(policy resource.Policy, request Request, manifest Manifest) error {
+	var violations []string
+
+	if policy.BanSkipCertCheck && request.Source.SkipCertCheck {
+		violations = append(violations, "source.skip_cert_check is not allowed")
+	}
+
+	maxMemoryMB := 0
+	if policy.MaxMemory != "" {
+		var err error
+		maxMemoryMB, err = megabytes(policy.MaxMemory)
+		if err != nil {
+			return fmt.Errorf("invalid policy max_memory: %s", err)
+		}
+	}
+
+	for _, app := range manifest.Applications() {
+		violate := func(format string, args ...interface{}) {
+			violations = append(violations, app.Name+": "+fmt.Sprintf(format, args...))
+		}
+
+		if maxMemoryMB > 0 {
+			if app.Memory == "" {
+				violate("memory must be set, to at most %s", policy.MaxMemory)
+			} else if memoryMB, err := megabytes(app.Memory); err == nil && memoryMB > maxMemoryMB {
+				violate("memory %s is over the maximum of %s", app.Memory, policy.MaxMemory)
+			}
+		}
+
+		if policy.MaxInstances > 0 && app.Instances != nil && *app.Instances > policy.MaxInstances {
+			violate("instances %d is over the maximum of %d", *app.Instances, policy.MaxInstances)
+		}
+
+		// each process type is sized on its own, except that the web process
+		// falls back to the app's memory, which is checked above
+		for _, process := range app.Processes {
+			if maxMemoryMB > 0 {
+				if process.Memory == "" {
+					if process.Type != "web" {
+						violate("%s process memory must be set, to at most %s", process.Type, policy.MaxMemory)
+					}
+				} else if memoryMB, err := megabytes(process.Memory); err == nil && memoryMB > maxMemoryMB {
+					violate("%s process memory %s is over the maximum of %s", process.Type, process.Memory, policy.MaxMemory)
+				}
+			}
+
+			if policy.MaxInstances > 0 && process.Instances != nil && *process.Instances > policy.MaxInstances {
+				violate("%s process instances %d is over the maximum of %d", process.Type, *process.Instances, policy.MaxInstances)
+			}
+		}
+
+		if len(policy.AllowedBuildpacks) > 0 {
+			if len(app.Buildpacks) == 0 {
+				violate("buildpacks must be set to one of %s", strings.Join(policy.AllowedBuildpacks, ", "))
+			}
+			for _, buildpack := range app.Buildpacks {
+				if !contains(policy.AllowedBuildpacks, buildpack) {
+					violate("buildpack %s is not allowed, expected one of %s", buildpack, strings.Join(policy.AllowedBuildpacks, ", "))
+				}
+			}
+		}
+
+		if len(policy.AllowedStacks) > 0 && !contains(policy.AllowedStacks, app.Stack) {
+			if app.Stack == "" {
+				violate("stack must be set to one of %s", strings.Join(policy.AllowedStacks, ", "))
+			} else {
+				violate("stack %s is not allowed, expected one of %s", app.Stack, strings.Join(policy.AllowedStacks, ", "))
+			}
+		}
+
+		for _, key := range policy.RequiredEnv {
+			if _, found := app.Env[key]; !found {
+				violate("env %s is required", key)
+			}
+		}
+
+		if policy.RequireHealthCheck {
+			// the web process's entry in processes takes the place of the
+			// app's; other process types default to a process check
+			healthCheckType := app.HealthCheckType
+			for _, process := range app.Processes {
+				if process.Type == "web" && process.HealthCheckType != "" {
+					healthCheckType = process.HealthCheckType
+				} else if process.Type != "web" && process.HealthCheckType == "none" {
+					violate("%s process health-check-type must be port, http or process", process.Type)
+				}
+			}
+			if healthCheckType == "" || healthCheckType == "none" {
+				violate("health-check-type must be set to port, http or process")
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
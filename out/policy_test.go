@@ -0,0 +1,117 @@
+package out_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Policy", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		command = out.NewCommand(cloudFoundry, GinkgoWriter)
+
+		request = newRequest()
+		request.Source.SkipCertCheck = true
+		request.Source.Policy = resource.Policy{File: "assets/policy.yml"}
+		request.Params.ManifestPath = "assets/policyManifest.yml"
+	})
+
+	It("reports every violation together before logging in", func() {
+		_, err := command.Run(request)
+		Expect(err).To(BeAssignableToTypeOf(&out.PolicyError{}))
+		Expect(err.(*out.PolicyError).Violations).To(Equal([]string{
+			"source.skip_cert_check is not allowed",
+			"web: memory 2G is over the maximum of 1G",
+			"web: instances 4 is over the maximum of 2",
+			"web: buildpack java_buildpack is not allowed, expected one of go_buildpack",
+			"web: stack cflinuxfs3 is not allowed, expected one of cflinuxfs4",
+			"worker: memory must be set, to at most 1G",
+			"worker: stack must be set to one of cflinuxfs4",
+			"worker: env LOG_LEVEL is required",
+			"worker: health-check-type must be set to port, http or process",
+		}))
+
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+
+	It("checks every process type, including those sized by scale", func() {
+		request.Source.SkipCertCheck = false
+		request.Source.Policy = resource.Policy{MaxMemory: "2G", MaxInstances: 4}
+		instances := 500
+		request.Params.Scale = out.Scaling{
+			"web": {Processes: map[string]out.Scale{
+				"worker":    {Instances: &instances, Memory: "64G"},
+				"scheduler": {Instances: &instances},
+			}},
+		}
+
+		_, err := command.Run(request)
+		Expect(err).To(BeAssignableToTypeOf(&out.PolicyError{}))
+		Expect(err.(*out.PolicyError).Violations).To(Equal([]string{
+			"web: scheduler process memory must be set, to at most 2G",
+			"web: scheduler process instances 500 is over the maximum of 4",
+			"web: worker process memory 64G is over the maximum of 2G",
+			"web: worker process instances 500 is over the maximum of 4",
+			"worker: memory must be set, to at most 2G",
+		}))
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+	})
+
+	It("checks environment_variables from the params too", func() {
+		request.Source.SkipCertCheck = false
+		request.Source.Policy = resource.Policy{RequiredEnv: []string{"LOG_LEVEL"}}
+		request.Params.EnvironmentVariables = out.EnvironmentVariables{
+			Apps: map[string]map[string]interface{}{"worker": {"LOG_LEVEL": "debug"}},
+		}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(1))
+	})
+
+	It("reads health-check-type from the web process", func() {
+		file, err := ioutil.TempFile("", "policy_test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString("applications:\n- name: web\n  processes:\n  - type: web\n    health-check-type: http\n  - type: worker\n    health-check-type: none\n")
+		Expect(err).NotTo(HaveOccurred())
+		file.Close()
+
+		request.Source.SkipCertCheck = false
+		request.Source.Policy = resource.Policy{RequireHealthCheck: true}
+		request.Params.ManifestPath = file.Name()
+
+		_, err = command.Run(request)
+		Expect(err).To(BeAssignableToTypeOf(&out.PolicyError{}))
+		Expect(err.(*out.PolicyError).Violations).To(Equal([]string{
+			"web: worker process health-check-type must be port, http or process",
+		}))
+	})
+
+	It("is read inline or from a file", func() {
+		var source resource.Source
+
+		err := json.Unmarshal([]byte(`{"policy": "ci/policy.yml"}`), &source)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source.Policy).To(Equal(resource.Policy{File: "ci/policy.yml"}))
+
+		err = json.Unmarshal([]byte(`{"policy": {"max_instances": 3, "allowed_stacks": ["cflinuxfs4"]}}`), &source)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source.Policy).To(Equal(resource.Policy{MaxInstances: 3, AllowedStacks: []string{"cflinuxfs4"}}))
+	})
+})
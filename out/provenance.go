@@ -0,0 +1,174 @@
+package out
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const provenancePrefix = "concourse-ci.org/"
+
+var (
+	// label values, and the name part of label and annotation keys
+	metadataName = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]{0,61}[A-Za-z0-9])?$`)
+	// the optional prefix of a key, a DNS subdomain
+	metadataPrefix = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+)
+
+const maxAnnotationLength = 5000
+
+// build is the Concourse build running the put, from the BUILD_*
+// environment variables.
+type build struct {
+	Team     string
+	Pipeline string
+	Job      string
+	Name     string
+	ID       string
+	URL      string
+}
+
+func currentBuild() build {
+	b := build{
+		Team:     os.Getenv("BUILD_TEAM_NAME"),
+		Pipeline: os.Getenv("BUILD_PIPELINE_NAME"),
+		Job:      os.Getenv("BUILD_JOB_NAME"),
+		Name:     os.Getenv("BUILD_NAME"),
+		ID:       os.Getenv("BUILD_ID"),
+	}
+
+	if atc := strings.TrimSuffix(os.Getenv("ATC_EXTERNAL_URL"), "/"); atc != "" {
+		switch {
+		case b.Team != "" && b.Pipeline != "" && b.Job != "" && b.Name != "":
+			b.URL = fmt.Sprintf("%s/teams/%s/pipelines/%s/jobs/%s/builds/%s", atc,
+				url.PathEscape(b.Team), url.PathEscape(b.Pipeline), url.PathEscape(b.Job), url.PathEscape(b.Name))
+		case b.ID != "":
+			b.URL = atc + "/builds/" + b.ID
+		}
+	}
+
+	return b
+}
+
+// provenance returns the labels and annotations that record which build
+// deployed an app. Only values that are valid labels become labels; every
+// value is an annotation.
+func (b build) provenance() (map[string]string, map[string]string) {
+	labels := map[string]string{}
+	annotations := map[string]string{}
+
+	for _, field := range []struct{ key, value string }{
+		{"team", b.Team},
+		{"pipeline", b.Pipeline},
+		{"job", b.Job},
+		{"build", b.Name},
+		{"build-id", b.ID},
+	} {
+		if field.value == "" {
+			continue
+		}
+		annotations[provenancePrefix+field.key] = field.value
+		if metadataName.MatchString(field.value) {
+			labels[provenancePrefix+field.key] = field.value
+		}
+	}
+
+	if b.URL != "" {
+		annotations[provenancePrefix+"build-url"] = b.URL
+	}
+
+	return labels, annotations
+}
+
+// appMetadataFor merges params.labels and params.annotations over the
+// build's provenance.
+func appMetadataFor(b build, params Params) (map[string]string, map[string]string) {
+	labels, annotations := b.provenance()
+	for key, value := range params.Labels {
+		labels[key] = value
+	}
+	for key, value := range params.Annotations {
+		annotations[key] = value
+	}
+	return labels, annotations
+}
+
+// checkMetadata fails on labels and annotations the Cloud Controller would
+// reject, before anything is pushed.
+func checkMetadata(params Params) error {
+	var problems []string
+
+	for _, key := range sortedKeys(params.Labels) {
+		if err := checkMetadataKey(key); err != nil {
+			problems = append(problems, fmt.Sprintf("label %q: %s", key, err))
+		} else if value := params.Labels[key]; value != "" && !metadataName.MatchString(value) {
+			problems = append(problems, fmt.Sprintf("label %q: value %q must be at most 63 letters, digits, '-', '_' or '.', starting and ending with a letter or digit", key, value))
+		}
+	}
+
+	for _, key := range sortedKeys(params.Annotations) {
+		if err := checkMetadataKey(key); err != nil {
+			problems = append(problems, fmt.Sprintf("annotation %q: %s", key, err))
+		} else if len(params.Annotations[key]) > maxAnnotationLength {
+			problems = append(problems, fmt.Sprintf("annotation %q: value is over %d characters", key, maxAnnotationLength))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid metadata:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func checkMetadataKey(key string) error {
+	name := key
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		prefix := key[:i]
+		name = key[i+1:]
+		if len(prefix) > 253 || !metadataPrefix.MatchString(prefix) {
+			return fmt.Errorf("prefix %q must be a DNS subdomain", prefix)
+		}
+	}
+
+	if !metadataName.MatchString(name) {
+		return fmt.Errorf("name %q must be at most 63 letters, digits, '-', '_' or '.', starting and ending with a letter or digit", name)
+	}
+	return nil
+}
+
+// SetAppMetadata sets labels and annotations on an app.
+func (cf *CloudFoundry) SetAppMetadata(name string, labels map[string]string, annotations map[string]string) error {
+	guid, err := cf.appGUID(name)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      labels,
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return cf.curl("/v3/apps/"+guid, nil, "-X", "PATCH", "-d", string(body))
+}
+
+// stampApps sets the metadata on the pushed apps. The deploy itself has
+// worked by then, so failures are only warnings.
+func (command *Command) stampApps(apps []string, labels map[string]string, annotations map[string]string) {
+	if len(labels) == 0 && len(annotations) == 0 {
+		return
+	}
+
+	for _, app := range apps {
+		if err := command.paas.SetAppMetadata(app, labels, annotations); err != nil {
+			fmt.Fprintf(command.log, "warning: could not set the labels and annotations on %s: %s\n", app, err)
+		}
+	}
+}
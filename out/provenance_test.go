@@ -0,0 +1,155 @@
+package out_test
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Provenance", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		log          *gbytes.Buffer
+	)
+
+	buildEnv := map[string]string{
+		"BUILD_TEAM_NAME":     "main",
+		"BUILD_PIPELINE_NAME": "web",
+		"BUILD_JOB_NAME":      "deploy to prod",
+		"BUILD_NAME":          "42",
+		"BUILD_ID":            "1234",
+		"ATC_EXTERNAL_URL":    "https://ci.example.com/",
+	}
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+
+		for name, value := range buildEnv {
+			os.Setenv(name, value)
+		}
+
+		request = newRequest()
+	})
+
+	AfterEach(func() {
+		for name := range buildEnv {
+			os.Unsetenv(name)
+		}
+	})
+
+	It("stamps the pushed apps with the build that deployed them", func() {
+		request.Params.Labels = map[string]string{"tier": "frontend", "concourse-ci.org/team": "web-team"}
+		request.Params.Annotations = map[string]string{"example.com/ticket": "CHG-1234"}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.SetAppMetadataCallCount()).To(Equal(2))
+		name, labels, annotations := cloudFoundry.SetAppMetadataArgsForCall(0)
+		Expect(name).To(Equal("app1"))
+		Expect(labels).To(Equal(map[string]string{
+			"concourse-ci.org/team":     "web-team",
+			"concourse-ci.org/pipeline": "web",
+			"concourse-ci.org/build":    "42",
+			"concourse-ci.org/build-id": "1234",
+			"tier":                      "frontend",
+		}))
+		Expect(annotations).To(HaveKey("cf-resource.concourse-ci.org/fingerprint"))
+		Expect(annotations).To(HaveKey("cf-resource.concourse-ci.org/deployed-at"))
+		delete(annotations, "cf-resource.concourse-ci.org/fingerprint")
+		delete(annotations, "cf-resource.concourse-ci.org/deployed-at")
+		Expect(annotations).To(Equal(map[string]string{
+			"concourse-ci.org/team":      "main",
+			"concourse-ci.org/pipeline":  "web",
+			"concourse-ci.org/job":       "deploy to prod",
+			"concourse-ci.org/build":     "42",
+			"concourse-ci.org/build-id":  "1234",
+			"concourse-ci.org/build-url": "https://ci.example.com/teams/main/pipelines/web/jobs/deploy%20to%20prod/builds/42",
+			"example.com/ticket":         "CHG-1234",
+		}))
+
+		name, _, _ = cloudFoundry.SetAppMetadataArgsForCall(1)
+		Expect(name).To(Equal("app2"))
+	})
+
+	It("stamps the new app of a zero downtime deploy", func() {
+		request.Params.CurrentAppName = "app2"
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.SetAppMetadataCallCount()).To(Equal(1))
+		name, _, _ := cloudFoundry.SetAppMetadataArgsForCall(0)
+		Expect(name).To(Equal("app2"))
+	})
+
+	It("doesn't stamp apps when the push fails", func() {
+		cloudFoundry.PushAppReturns(out.PushResult{}, errors.New("push failed"))
+
+		_, err := command.Run(request)
+		Expect(err).To(HaveOccurred())
+		Expect(cloudFoundry.SetAppMetadataCallCount()).To(Equal(0))
+	})
+
+	It("only warns when the apps can't be stamped", func() {
+		cloudFoundry.SetAppMetadataReturns(errors.New("CF-NotAuthorized"))
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log).To(gbytes.Say("warning: could not set the labels and annotations on app1: CF-NotAuthorized"))
+	})
+
+	It("only stamps the fingerprint outside Concourse", func() {
+		for name := range buildEnv {
+			os.Unsetenv(name)
+		}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.SetAppMetadataCallCount()).To(Equal(2))
+		_, labels, annotations := cloudFoundry.SetAppMetadataArgsForCall(0)
+		Expect(labels).To(BeEmpty())
+		Expect(annotations).To(HaveLen(2))
+	})
+
+	It("rejects labels and annotations the Cloud Controller would, before logging in", func() {
+		request.Params.Labels = map[string]string{"-tier": "frontend", "owner": "web team"}
+		request.Params.Annotations = map[string]string{"Example.com/ticket": "CHG-1234"}
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(`invalid metadata:
+  label "-tier": name "-tier" must be at most 63 letters, digits, '-', '_' or '.', starting and ending with a letter or digit
+  label "owner": value "web team" must be at most 63 letters, digits, '-', '_' or '.', starting and ending with a letter or digit
+  annotation "Example.com/ticket": prefix "Example.com" must be a DNS subdomain`))
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+})
+
+var _ = Describe("SetAppMetadata", func() {
+	It("patches the app's metadata", func() {
+		runner := &clifakes.FakeRunner{}
+		fakeAPI(runner, map[string]string{"guid:my-app": "app-guid", "/v3/apps/app-guid": "{}"})
+		cloudFoundry := out.NewCloudFoundry(context.Background(), runner)
+
+		err := cloudFoundry.SetAppMetadata("my-app", map[string]string{"a": "b"}, map[string]string{"c": "d"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(commands(runner)).To(Equal([]string{
+			"cf app my-app --guid",
+			`cf curl /v3/apps/app-guid -X PATCH -d {"metadata":{"annotations":{"c":"d"},"labels":{"a":"b"}}}`,
+		}))
+	})
+})
@@ -0,0 +1,198 @@
+package out
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+// Review deploys the manifest's app as a throwaway review app, e.g. one per
+// pull request, under a templated name and route such as
+// myapp-pr-((pr_number)). The templates are interpolated with vars and
+// vars_files.
+type Review struct {
+	AppName string `json:"app_name"`
+	Host    string `json:"host"`
+	Domain  string `json:"domain"`
+	Path    string `json:"path"`
+}
+
+func (review Review) enabled() bool {
+	return review.AppName != ""
+}
+
+// Route is the review app's only route. The host defaults to the app name.
+func (review Review) Route() Route {
+	host := review.Host
+	if host == "" {
+		host = review.AppName
+	}
+	return Route{Host: host, Domain: review.Domain, Path: review.Path}
+}
+
+// URL is where the review app can be reached.
+func (review Review) URL() string {
+	return "https://" + review.Route().URL()
+}
+
+// resolveReview interpolates the review templates, and the names of the
+// review app's dedicated services, and pushes the app under the review
+// app's name. Every problem is reported at once, before logging in.
+func resolveReview(params Params) (Params, error) {
+	if !params.Review.enabled() {
+		return params, nil
+	}
+
+	values, err := readVars(params.Vars, params.VarsFiles)
+	if err != nil {
+		return Params{}, err
+	}
+
+	var unresolved []string
+	templates := []*string{&params.Review.AppName, &params.Review.Host, &params.Review.Domain, &params.Review.Path}
+	services := make([]Service, len(params.Services))
+	copy(services, params.Services)
+	for i := range services {
+		templates = append(templates, &services[i].Name)
+	}
+	params.Services = services
+
+	for _, template := range templates {
+		if *template, err = interpolateString(*template, values, &unresolved); err != nil {
+			return Params{}, err
+		}
+	}
+	if len(unresolved) > 0 {
+		return Params{}, fmt.Errorf("unresolved variables in review: %s; set them in vars or vars_files", strings.Join(unresolved, ", "))
+	}
+
+	var problems []string
+	if params.CurrentAppName != "" {
+		problems = append(problems, "current_app_name can't be set, review.app_name names the app")
+	}
+	if params.Review.Domain == "" {
+		problems = append(problems, "domain is required")
+	}
+	if params.Review.Path != "" && !strings.HasPrefix(params.Review.Path, "/") {
+		problems = append(problems, fmt.Sprintf("path %q must start with /", params.Review.Path))
+	}
+	if len(problems) > 0 {
+		return Params{}, fmt.Errorf("invalid review:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	params.CurrentAppName = params.Review.AppName
+	return params, nil
+}
+
+// addTo renames the manifest's app to the review app and gives it the review
+// route in place of its own, so a review app never takes a route of the app
+// it was copied from.
+func (review Review) addTo(manifest *Manifest) error {
+	if !review.enabled() {
+		return nil
+	}
+
+	apps := manifest.apps()
+	if len(apps) != 1 {
+		return fmt.Errorf("invalid review: the manifest has %d apps, a review app is pushed from a manifest with a single app", len(apps))
+	}
+	app := apps[0]
+
+	setMappingValue(app, "name", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: review.AppName})
+	for _, key := range append([]string{"random-route", "no-route"}, routeKeys...) {
+		removeKey(app, key)
+	}
+
+	route := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setMappingValue(route, "route", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: review.Route().URL()})
+	setMappingValue(app, "routes", &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{route}})
+	return nil
+}
+
+// reviewMetadata gives the review app's URL.
+func reviewMetadata(review Review) []resource.MetadataPair {
+	if !review.enabled() {
+		return nil
+	}
+	return []resource.MetadataPair{{Name: "review_url", Value: review.URL()}}
+}
+
+// destroyReview deletes the review app's route and dedicated services, once
+// the app itself is deleted, waiting for each broker to finish.
+func (command *Command) destroyReview(review Review, services []Service) error {
+	fmt.Fprintf(command.log, "Deleting route %s...\n", review.Route().URL())
+	if err := command.paas.DeleteRoute(review.Route()); err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		timeout, _ := parseDuration(service.Timeout, DefaultServiceTimeout)
+		deadline := time.Now().Add(timeout)
+
+		fmt.Fprintf(command.log, "Deleting service %s...\n", service.Name)
+		if err := command.paas.DeleteService(service.Name); err != nil {
+			return err
+		}
+		if err := command.waitForServiceDeletion(service.Name, deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForServiceDeletion polls the service instance until it is gone,
+// failing with the broker's message if the delete failed.
+func (command *Command) waitForServiceDeletion(name string, deadline time.Time) error {
+	for {
+		instance, err := command.paas.ServiceInstance(name)
+		if cli.Is(err, cli.ResourceNotFound) {
+			fmt.Fprintf(command.log, "Service %s is deleted.\n", name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if instance.State == "failed" {
+			return &cli.Error{
+				Kind:    cli.ServiceFailed,
+				Command: "delete-service",
+				Line:    fmt.Sprintf("%s of service %s failed: %s", instance.Operation, name, instance.Description),
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			return &cli.Error{
+				Kind:    cli.ServiceFailed,
+				Command: "delete-service",
+				Line:    fmt.Sprintf("delete of service %s is still in progress: %s", name, instance.Description),
+			}
+		}
+
+		sleepUntil(deadline)
+	}
+}
+
+func reviewSteps(review Review, services []Service) []string {
+	steps := []string{fmt.Sprintf("delete route %s", review.Route().URL())}
+	for _, service := range services {
+		steps = append(steps, fmt.Sprintf("delete service %s", service.Name))
+	}
+	return steps
+}
+
+// DeleteRoute deletes the route, unmapping it from any app.
+func (cf *CloudFoundry) DeleteRoute(route Route) error {
+	return cf.run(append(append([]string{"delete-route", route.Domain}, routeFlags(route)...), "-f")...)
+}
+
+// DeleteService deletes the service instance, which brokers may do
+// asynchronously. cf succeeds when it doesn't exist.
+func (cf *CloudFoundry) DeleteService(name string) error {
+	return cf.run("delete-service", name, "-f")
+}
@@ -0,0 +1,220 @@
+package out_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource"
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Review apps", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		log          *gbytes.Buffer
+		dir          string
+		pushed       string
+		events       []string
+	)
+
+	const manifest = `applications:
+- name: myapp
+  memory: 256M
+  routes:
+  - route: myapp.example.com
+  services:
+  - db-pr-((pr_number))
+`
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+		events = nil
+
+		var err error
+		dir, err = ioutil.TempDir("", "review")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "manifest.yml"), []byte(manifest), 0644)).To(BeNil())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "pr.yml"), []byte("pr_number: 42\n"), 0644)).To(BeNil())
+
+		pushed = ""
+		cloudFoundry.PushAppStub = func(manifest string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+			contents, err := ioutil.ReadFile(manifest)
+			pushed = string(contents)
+			return out.PushResult{Strategy: "plain"}, err
+		}
+		cloudFoundry.GetAppStub = func(name string) (out.App, error) {
+			return out.App{Name: name, State: "STARTED"}, nil
+		}
+		services := map[string]bool{}
+		cloudFoundry.ServiceInstanceStub = func(name string) (out.ServiceInstance, error) {
+			if !services[name] {
+				return out.ServiceInstance{}, &cli.Error{Kind: cli.ResourceNotFound}
+			}
+			return out.ServiceInstance{Name: name, Type: "managed", State: "succeeded"}, nil
+		}
+		cloudFoundry.CreateServiceStub = func(service out.Service) error {
+			services[service.Name] = true
+			return nil
+		}
+		cloudFoundry.DeleteAppStub = func(name string) error {
+			events = append(events, "delete app "+name)
+			return nil
+		}
+		cloudFoundry.DeleteRouteStub = func(route out.Route) error {
+			events = append(events, "delete route "+route.URL())
+			return nil
+		}
+		cloudFoundry.DeleteServiceStub = func(name string) error {
+			events = append(events, "delete service "+name)
+			return nil
+		}
+
+		request = newRequest()
+		request.Params.ManifestPath = filepath.Join(dir, "manifest.yml")
+		request.Params.VarsFiles = []string{filepath.Join(dir, "pr.yml")}
+		request.Params.Review = out.Review{
+			AppName: "myapp-pr-((pr_number))",
+			Domain:  "review.example.com",
+		}
+		request.Params.Services = []out.Service{{Name: "db-pr-((pr_number))", Offering: "postgres", Plan: "small"}}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("pushes the app under the templated name, with only the review route", func() {
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pushed).To(Equal(`applications:
+  - name: myapp-pr-42
+    memory: 256M
+    routes:
+      - route: myapp-pr-42.review.example.com
+    services:
+      - db-pr-42
+`))
+		_, _, currentAppName, _, _, _, _, _ := cloudFoundry.PushAppArgsForCall(0)
+		Expect(currentAppName).To(Equal("myapp-pr-42"))
+		Expect(cloudFoundry.CreateServiceArgsForCall(0).Name).To(Equal("db-pr-42"))
+
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "app", Value: "myapp-pr-42"}))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "review_url", Value: "https://myapp-pr-42.review.example.com"}))
+	})
+
+	It("templates the host and path too", func() {
+		request.Params.Review.Host = "pr-((pr_number))"
+		request.Params.Review.Path = "/myapp"
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pushed).To(ContainSubstring("- route: pr-42.review.example.com/myapp\n"))
+	})
+
+	It("destroys the app, its route and its services", func() {
+		request.Params.Action = out.ActionDestroy
+
+		response, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+		Expect(events).To(Equal([]string{
+			"delete app myapp-pr-42",
+			"delete route myapp-pr-42.review.example.com",
+			"delete service db-pr-42",
+		}))
+		Expect(log).To(gbytes.Say("Service db-pr-42 is deleted.\n"))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "state", Value: out.StateDeleted}))
+		Expect(response.Metadata).To(ContainElement(resource.MetadataPair{Name: "review_url", Value: "https://myapp-pr-42.review.example.com"}))
+	})
+
+	It("fails when a service can't be deleted", func() {
+		request.Params.Action = out.ActionDestroy
+		cloudFoundry.ServiceInstanceStub = nil
+		cloudFoundry.ServiceInstanceReturns(out.ServiceInstance{Name: "db-pr-42", Operation: "delete", State: "failed", Description: "Service binding still exists"}, nil)
+
+		_, err := command.Run(request)
+		Expect(cli.Is(err, cli.ServiceFailed)).To(BeTrue())
+		Expect(err).To(MatchError("cf delete-service: service failed: delete of service db-pr-42 failed: Service binding still exists"))
+	})
+
+	It("plans the destroy in a dry run", func() {
+		request.Params.Action = out.ActionDestroy
+		request.Params.DryRun = true
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log).To(gbytes.Say(`1\. destroy myapp-pr-42\n  2\. delete route myapp-pr-42.review.example.com\n  3\. delete service db-pr-42\n`))
+		Expect(events).To(BeEmpty())
+	})
+
+	It("rejects a review that can't be resolved, before logging in", func() {
+		request.Params.VarsFiles = nil
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("unresolved variables in review: ((pr_number)); set them in vars or vars_files"))
+
+		request.Params.Vars = map[string]interface{}{"pr_number": 42}
+		request.Params.CurrentAppName = "myapp"
+		request.Params.Review.Domain = ""
+		request.Params.Review.Path = "docs"
+		_, err = command.Run(request)
+		Expect(err).To(MatchError(`invalid review:
+  current_app_name can't be set, review.app_name names the app
+  domain is required
+  path "docs" must start with /`))
+
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+
+	It("needs a single app manifest", func() {
+		request.Params.ManifestPath = "assets/manifest.yml"
+		request.Params.Services = nil
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("invalid review: the manifest has 2 apps, a review app is pushed from a manifest with a single app"))
+	})
+
+	It("only destroys review apps", func() {
+		request.Params.Review = out.Review{}
+		request.Params.Action = out.ActionDestroy
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("action destroy destroys a review app, so it needs review.app_name"))
+	})
+})
+
+var _ = Describe("CloudFoundry review apps", func() {
+	var (
+		runner       *clifakes.FakeRunner
+		cloudFoundry *out.CloudFoundry
+	)
+
+	BeforeEach(func() {
+		runner = &clifakes.FakeRunner{}
+		cloudFoundry = out.NewCloudFoundry(context.Background(), runner)
+	})
+
+	It("deletes routes and services", func() {
+		Expect(cloudFoundry.DeleteRoute(out.Route{Host: "myapp-pr-42", Domain: "example.com", Path: "/docs"})).To(BeNil())
+		Expect(cloudFoundry.DeleteService("db-pr-42")).To(BeNil())
+
+		Expect(commands(runner)).To(Equal([]string{
+			"cf delete-route example.com --hostname myapp-pr-42 --path /docs -f",
+			"cf delete-service db-pr-42 -f",
+		}))
+	})
+})
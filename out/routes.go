@@ -0,0 +1,263 @@
+package out
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+// Routes are mapped to, or unmapped from, the pushed apps after the push,
+// on top of the manifest's routes.
+type Routes struct {
+	Map   []Route `json:"map"`
+	Unmap []Route `json:"unmap"`
+}
+
+type Route struct {
+	App    string `json:"app"`
+	Host   string `json:"host"`
+	Domain string `json:"domain"`
+	Path   string `json:"path"`
+	Port   int    `json:"port"`
+}
+
+// URL is the route as cf prints it, e.g. www.example.com/docs or
+// tcp.example.com:1024.
+func (route Route) URL() string {
+	url := route.Domain
+	if route.Host != "" {
+		url = route.Host + "." + url
+	}
+	if route.Port != 0 {
+		url += ":" + strconv.Itoa(route.Port)
+	}
+	return url + route.Path
+}
+
+// checkRoutes fails on routes that could never be mapped, before anything
+// is changed. A route may leave out the app when only one app is pushed.
+func checkRoutes(routes Routes, apps []string) error {
+	var problems []string
+
+	check := func(list string, routes []Route) {
+		for i, route := range routes {
+			where := fmt.Sprintf("%s[%d]", list, i)
+			if route.Domain == "" {
+				problems = append(problems, where+": domain is required")
+			}
+			if route.App == "" && len(apps) != 1 {
+				problems = append(problems, fmt.Sprintf("%s: app is required when %d apps are pushed", where, len(apps)))
+			}
+			if route.App != "" && !contains(apps, route.App) {
+				problems = append(problems, fmt.Sprintf("%s: app %q is not pushed by this put", where, route.App))
+			}
+			if route.Path != "" && !strings.HasPrefix(route.Path, "/") {
+				problems = append(problems, fmt.Sprintf("%s: path %q must start with /", where, route.Path))
+			}
+			if route.Port != 0 && (route.Host != "" || route.Path != "") {
+				problems = append(problems, where+": a route with a port can't have a host or path")
+			}
+		}
+	}
+	check("map", routes.Map)
+	check("unmap", routes.Unmap)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid routes:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// checkRouteOwnership fails when any route the put would map, from the
+// manifest or from routes.map, belongs to another space, where cf push or
+// map-route would only fail partway through the deploy.
+func (command *Command) checkRouteOwnership(manifestApps []ManifestApp, routes Routes) error {
+	urls := map[string]bool{}
+	for _, app := range manifestApps {
+		for _, route := range app.Routes {
+			urls[route] = true
+		}
+	}
+	for _, route := range routes.Map {
+		urls[route.URL()] = true
+	}
+
+	var taken []string
+	for url := range urls {
+		elsewhere, err := command.paas.RouteInOtherSpace(url)
+		if err != nil {
+			return err
+		}
+		if elsewhere {
+			taken = append(taken, url)
+		}
+	}
+
+	if len(taken) == 0 {
+		return nil
+	}
+	sort.Strings(taken)
+	return &cli.Error{
+		Kind:    cli.RouteTaken,
+		Command: "map-route",
+		Line:    fmt.Sprintf("owned by another space: %s", strings.Join(taken, ", ")),
+	}
+}
+
+// applyRoutes maps and then unmaps routes by app name, which after a zero
+// downtime deploy is the new app.
+func (command *Command) applyRoutes(routes Routes, apps []string) error {
+	appFor := func(route Route) string {
+		if route.App == "" {
+			return apps[0]
+		}
+		return route.App
+	}
+
+	for _, route := range routes.Map {
+		if err := command.paas.MapRoute(appFor(route), route); err != nil {
+			return err
+		}
+	}
+	for _, route := range routes.Unmap {
+		if err := command.paas.UnmapRoute(appFor(route), route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MapRoute maps the route to the app, creating the route if need be.
+func (cf *CloudFoundry) MapRoute(app string, route Route) error {
+	return cf.run(append([]string{"map-route", app, route.Domain}, routeFlags(route)...)...)
+}
+
+// UnmapRoute unmaps the route from the app, leaving the route itself.
+func (cf *CloudFoundry) UnmapRoute(app string, route Route) error {
+	return cf.run(append([]string{"unmap-route", app, route.Domain}, routeFlags(route)...)...)
+}
+
+func routeFlags(route Route) []string {
+	var flags []string
+	if route.Host != "" {
+		flags = append(flags, "--hostname", route.Host)
+	}
+	if route.Path != "" {
+		flags = append(flags, "--path", route.Path)
+	}
+	if route.Port != 0 {
+		flags = append(flags, "--port", strconv.Itoa(route.Port))
+	}
+	return flags
+}
+
+type v3Domains struct {
+	Resources []struct {
+		GUID string `json:"guid"`
+		Name string `json:"name"`
+	} `json:"resources"`
+}
+
+type v3RouteReservation struct {
+	MatchingRoute bool `json:"matching_route"`
+}
+
+type v3SpaceRoutes struct {
+	Resources []struct {
+		Host string `json:"host"`
+		Path string `json:"path"`
+		Port int    `json:"port"`
+	} `json:"resources"`
+}
+
+// RouteInOtherSpace reports whether the route with this URL exists outside
+// the targeted space. Routes in other spaces may not be visible, so this
+// asks whether the route is reserved at all and then whether the reservation
+// is the targeted space's own.
+func (cf *CloudFoundry) RouteInOtherSpace(routeURL string) (bool, error) {
+	route, domainGUID, err := cf.resolveRoute(routeURL)
+	if err != nil {
+		return false, err
+	}
+
+	query := url.Values{}
+	if route.Host != "" {
+		query.Set("host", route.Host)
+	}
+	if route.Path != "" {
+		query.Set("path", route.Path)
+	}
+	if route.Port != 0 {
+		query.Set("port", strconv.Itoa(route.Port))
+	}
+	var reservation v3RouteReservation
+	if err := cf.curl("/v3/domains/"+domainGUID+"/route_reservations?"+query.Encode(), &reservation); err != nil {
+		return false, err
+	}
+	if !reservation.MatchingRoute {
+		return false, nil
+	}
+
+	spaceGUID, err := cf.spaceGUID()
+	if err != nil {
+		return false, err
+	}
+	query = url.Values{"domain_guids": {domainGUID}, "space_guids": {spaceGUID}}
+	var routes v3SpaceRoutes
+	if err := cf.curl("/v3/routes?"+query.Encode(), &routes); err != nil {
+		return false, err
+	}
+	for _, own := range routes.Resources {
+		if own.Host == route.Host && own.Path == route.Path && own.Port == route.Port {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// resolveRoute splits a route URL into its host, domain, port and path. The
+// domain is the longest suffix of the hostname that Cloud Foundry knows.
+func (cf *CloudFoundry) resolveRoute(routeURL string) (Route, string, error) {
+	var route Route
+
+	hostname := routeURL
+	if i := strings.Index(hostname, "/"); i >= 0 {
+		hostname, route.Path = hostname[:i], hostname[i:]
+	}
+	if i := strings.LastIndex(hostname, ":"); i >= 0 {
+		port, err := strconv.Atoi(hostname[i+1:])
+		if err != nil {
+			return Route{}, "", fmt.Errorf("route %s: invalid port: %s", routeURL, err)
+		}
+		hostname, route.Port = hostname[:i], port
+	}
+
+	candidates := []string{hostname}
+	for i, c := range hostname {
+		if c == '.' {
+			candidates = append(candidates, hostname[i+1:])
+		}
+	}
+
+	var domains v3Domains
+	if err := cf.curl("/v3/domains?"+url.Values{"names": {strings.Join(candidates, ",")}}.Encode(), &domains); err != nil {
+		return Route{}, "", err
+	}
+
+	domainGUID := ""
+	for _, domain := range domains.Resources {
+		if len(domain.Name) > len(route.Domain) {
+			route.Domain, domainGUID = domain.Name, domain.GUID
+		}
+	}
+	if domainGUID == "" {
+		return Route{}, "", &cli.Error{Kind: cli.ResourceNotFound, Command: "curl", Line: fmt.Sprintf("no domain of route %s exists", routeURL)}
+	}
+
+	route.Host = strings.TrimSuffix(strings.TrimSuffix(hostname, route.Domain), ".")
+	return route, domainGUID, nil
+}
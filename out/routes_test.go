@@ -0,0 +1,186 @@
+package out_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Routes", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		log          *gbytes.Buffer
+		events       []string
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+		events = nil
+
+		cloudFoundry.PushAppStub = func(_ string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+			events = append(events, "push")
+			return out.PushResult{Strategy: "zdt"}, nil
+		}
+		cloudFoundry.MapRouteStub = func(app string, route out.Route) error {
+			events = append(events, "map "+route.URL()+" to "+app)
+			return nil
+		}
+		cloudFoundry.UnmapRouteStub = func(app string, route out.Route) error {
+			events = append(events, "unmap "+route.URL()+" from "+app)
+			return nil
+		}
+
+		request = newRequest()
+		request.Params.ManifestPath = "assets/varsManifest.yml"
+		request.Params.Vars = map[string]interface{}{
+			"app_name":  "web",
+			"instances": 2,
+			"name":      "world",
+			"db":        map[string]interface{}{"password": "s3cret"},
+			"service":   "db",
+		}
+		request.Params.CurrentAppName = "web"
+		request.Params.Routes = out.Routes{
+			Map:   []out.Route{{Host: "web-canary", Domain: "example.com"}, {Domain: "example.org", Path: "/web"}},
+			Unmap: []out.Route{{Host: "legacy", Domain: "example.com"}},
+		}
+	})
+
+	It("maps and unmaps the routes by name after the push, so they land on the new app", func() {
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(events).To(Equal([]string{
+			"push",
+			"map web-canary.example.com to web",
+			"map example.org/web to web",
+			"unmap legacy.example.com from web",
+		}))
+	})
+
+	It("checks the manifest's routes and the mapped routes before anything is changed", func() {
+		cloudFoundry.RouteInOtherSpaceStub = func(url string) (bool, error) {
+			return url == "web.example.com" || url == "example.org/web", nil
+		}
+
+		_, err := command.Run(request)
+		Expect(cli.Is(err, cli.RouteTaken)).To(BeTrue())
+		Expect(err).To(MatchError("cf map-route: route taken: owned by another space: example.org/web, web.example.com"))
+
+		var urls []string
+		for i := 0; i < cloudFoundry.RouteInOtherSpaceCallCount(); i++ {
+			urls = append(urls, cloudFoundry.RouteInOtherSpaceArgsForCall(i))
+		}
+		Expect(urls).To(ConsistOf("web.example.com", "web-canary.example.com", "example.org/web"))
+		Expect(events).To(BeEmpty())
+	})
+
+	It("rejects routes that could never be mapped, before logging in", func() {
+		request.Params.ManifestPath = "assets/manifest.yml"
+		request.Params.CurrentAppName = ""
+		request.Params.Routes = out.Routes{
+			Map:   []out.Route{{Host: "web", Path: "docs"}},
+			Unmap: []out.Route{{App: "app3", Host: "tcp", Domain: "tcp.example.com", Port: 1024}},
+		}
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(`invalid routes:
+  map[0]: domain is required
+  map[0]: app is required when 2 apps are pushed
+  map[0]: path "docs" must start with /
+  unmap[0]: app "app3" is not pushed by this put
+  unmap[0]: a route with a port can't have a host or path`))
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+
+	It("adds the routes to the dry run plan", func() {
+		request.Params.DryRun = true
+		cloudFoundry.GetAppReturns(out.App{}, &cli.Error{Kind: cli.AppNotFound})
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log).To(gbytes.Say(`1\. push web\n  2\. map route web-canary.example.com to web\n  3\. map route example.org/web to web\n  4\. unmap route legacy.example.com from web\n`))
+	})
+})
+
+var _ = Describe("CloudFoundry routes", func() {
+	var (
+		runner       *clifakes.FakeRunner
+		cloudFoundry *out.CloudFoundry
+		responses    map[string]string
+	)
+
+	BeforeEach(func() {
+		runner = &clifakes.FakeRunner{}
+		cloudFoundry = out.NewCloudFoundry(context.Background(), runner)
+
+		responses = map[string]string{
+			"/v3/domains?names=www.apps.example.com%2Capps.example.com%2Cexample.com%2Ccom": `{"resources":[{"guid":"example-guid","name":"example.com"},{"guid":"apps-guid","name":"apps.example.com"}]}`,
+			"/v3/domains/apps-guid/route_reservations?host=www&path=%2Fdocs":                `{"matching_route":true}`,
+		}
+		runner.RunStub = func(ctx context.Context, args ...string) (cli.Result, error) {
+			switch args[0] {
+			case "space":
+				return cli.Result{Stdout: "space-guid\n"}, nil
+			case "curl":
+				return cli.Result{Stdout: responses[args[1]]}, nil
+			}
+			return cli.Result{}, nil
+		}
+
+		Expect(cloudFoundry.Target("org", "my-space")).To(BeNil())
+	})
+
+	It("maps and unmaps routes", func() {
+		Expect(cloudFoundry.MapRoute("web", out.Route{Host: "www", Domain: "example.com", Path: "/docs"})).To(BeNil())
+		Expect(cloudFoundry.UnmapRoute("web", out.Route{Domain: "tcp.example.com", Port: 1024})).To(BeNil())
+
+		Expect(commands(runner)[1:]).To(Equal([]string{
+			"cf map-route web example.com --hostname www --path /docs",
+			"cf unmap-route web tcp.example.com --port 1024",
+		}))
+	})
+
+	It("finds a route that is reserved outside the targeted space", func() {
+		responses["/v3/routes?domain_guids=apps-guid&space_guids=space-guid"] = `{"resources":[{"host":"www","path":""}]}`
+
+		elsewhere, err := cloudFoundry.RouteInOtherSpace("www.apps.example.com/docs")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(elsewhere).To(BeTrue())
+	})
+
+	It("accepts a route the targeted space already has", func() {
+		responses["/v3/routes?domain_guids=apps-guid&space_guids=space-guid"] = `{"resources":[{"host":"www","path":"/docs"}]}`
+
+		elsewhere, err := cloudFoundry.RouteInOtherSpace("www.apps.example.com/docs")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(elsewhere).To(BeFalse())
+	})
+
+	It("accepts a route nobody has", func() {
+		responses["/v3/domains/apps-guid/route_reservations?host=www&path=%2Fdocs"] = `{"matching_route":false}`
+
+		elsewhere, err := cloudFoundry.RouteInOtherSpace("www.apps.example.com/docs")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(elsewhere).To(BeFalse())
+		Expect(commands(runner)).NotTo(ContainElement("cf space my-space --guid"))
+	})
+
+	It("fails on a route whose domain doesn't exist", func() {
+		responses["/v3/domains?names=tcp.example.net%2Cexample.net%2Cnet"] = `{"resources":[]}`
+
+		_, err := cloudFoundry.RouteInOtherSpace("tcp.example.net:1024")
+		Expect(cli.Is(err, cli.ResourceNotFound)).To(BeTrue())
+	})
+})
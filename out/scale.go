@@ -0,0 +1,149 @@
+package out
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scale sets the instances, memory and disk of a process on top of the
+// manifest. Under an app name it scales the app's web process, and its
+// processes scale the app's other process types.
+type Scale struct {
+	Instances *int             `json:"instances"`
+	Memory    string           `json:"memory"`
+	Disk      string           `json:"disk"`
+	Processes map[string]Scale `json:"processes"`
+}
+
+// Scaling maps app names, or process types, to how they are scaled. A key
+// that isn't a pushed app scales that process type on every pushed app that
+// has it; every app has a web process.
+type Scaling map[string]Scale
+
+// addTo scales the manifest's apps, so that every kind of push deploys them
+// scaled. currentAppName also names the app of a single app manifest, as cf
+// push does.
+func (scaling Scaling) addTo(manifest *Manifest, currentAppName string) error {
+	appNodes := manifest.apps()
+	findApp := func(name string) *yaml.Node {
+		if len(appNodes) == 1 && name == currentAppName {
+			return appNodes[0]
+		}
+		for _, app := range appNodes {
+			if appName := mappingValue(app, "name"); appName != nil && appName.Value == name {
+				return app
+			}
+		}
+		return nil
+	}
+
+	var problems []string
+	for _, key := range sortedScaleKeys(scaling) {
+		scale := scaling[key]
+		where := "scale." + key
+		problems = append(problems, scale.check(where)...)
+
+		if app := findApp(key); app != nil {
+			scaleProcess(app, "web", scale)
+			for _, processType := range sortedScaleKeys(scale.Processes) {
+				process := scale.Processes[processType]
+				problems = append(problems, process.check(where+".processes."+processType)...)
+				if process.Processes != nil {
+					problems = append(problems, where+".processes."+processType+": processes only go under an app")
+				}
+				scaleProcess(app, processType, process)
+			}
+			continue
+		}
+
+		if scale.Processes != nil {
+			problems = append(problems, fmt.Sprintf("%s: processes only go under an app, and there is no app named %s", where, key))
+		}
+		scaled := false
+		for _, app := range appNodes {
+			if key == "web" || processNode(app, key) != nil {
+				scaleProcess(app, key, scale)
+				scaled = true
+			}
+		}
+		if !scaled {
+			problems = append(problems, fmt.Sprintf("%s: there is no app or process type named %s in the manifest", where, key))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid scale:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func (scale Scale) check(where string) []string {
+	var problems []string
+	if scale.Instances != nil && *scale.Instances < 0 {
+		problems = append(problems, fmt.Sprintf("%s: instances must not be negative", where))
+	}
+	for field, size := range map[string]string{"memory": scale.Memory, "disk": scale.Disk} {
+		if _, err := megabytes(size); size != "" && err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s %q must be a size such as 512M or 1G", where, field, size))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// scaleProcess sets the scale on the app's entry in processes for the type,
+// adding one if need be. The web process is scaled through the app itself
+// unless the manifest lists it under processes.
+func scaleProcess(app *yaml.Node, processType string, scale Scale) {
+	node := processNode(app, processType)
+	if node == nil && processType == "web" {
+		node = app
+	}
+	if node == nil {
+		processes := mappingValue(app, "processes")
+		if processes == nil || processes.Kind != yaml.SequenceNode {
+			processes = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			setMappingValue(app, "processes", processes)
+		}
+		node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(node, "type", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: processType})
+		processes.Content = append(processes.Content, node)
+	}
+
+	if scale.Instances != nil {
+		setMappingValue(node, "instances", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(*scale.Instances)})
+	}
+	if scale.Memory != "" {
+		setMappingValue(node, "memory", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scale.Memory})
+	}
+	if scale.Disk != "" {
+		setMappingValue(node, "disk_quota", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scale.Disk})
+	}
+}
+
+// processNode returns the app's entry in processes for the type, if any.
+func processNode(app *yaml.Node, processType string) *yaml.Node {
+	processes := mappingValue(app, "processes")
+	if processes == nil || processes.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, process := range processes.Content {
+		if t := mappingValue(process, "type"); t != nil && t.Value == processType {
+			return process
+		}
+	}
+	return nil
+}
+
+func sortedScaleKeys(m map[string]Scale) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
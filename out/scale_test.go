@@ -0,0 +1,136 @@
+package out_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Scale", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		dir          string
+		pushed       string
+	)
+
+	const manifest = `applications:
+- name: web
+  instances: 1
+  memory: 256M
+- name: jobs
+  processes:
+  - type: web
+    instances: 1
+  - type: worker
+    instances: 1
+`
+
+	instances := func(n int) *int { return &n }
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		command = out.NewCommand(cloudFoundry, GinkgoWriter)
+
+		var err error
+		dir, err = ioutil.TempDir("", "scale")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "manifest.yml"), []byte(manifest), 0644)).To(BeNil())
+
+		pushed = ""
+		cloudFoundry.PushAppStub = func(manifest string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+			contents, err := ioutil.ReadFile(manifest)
+			pushed = string(contents)
+			return out.PushResult{}, err
+		}
+
+		request = newRequest()
+		request.Params.ManifestPath = filepath.Join(dir, "manifest.yml")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("scales apps, and their process types, on top of the manifest", func() {
+		request.Params.Scale = out.Scaling{
+			"web": {Instances: instances(4), Memory: "1G"},
+			"jobs": {Disk: "2G", Processes: map[string]out.Scale{
+				"worker":    {Instances: instances(3)},
+				"scheduler": {Instances: instances(1), Memory: "128M"},
+			}},
+		}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pushed).To(Equal(`applications:
+  - name: web
+    instances: 4
+    memory: 1G
+  - name: jobs
+    processes:
+      - type: web
+        instances: 1
+        disk_quota: 2G
+      - type: worker
+        instances: 3
+      - type: scheduler
+        instances: 1
+        memory: 128M
+`))
+	})
+
+	It("scales a process type on every app that has it", func() {
+		Expect(ioutil.WriteFile(request.Params.ManifestPath, []byte("applications:\n- name: api\n- name: jobs\n  processes:\n  - type: worker\n"), 0644)).To(BeNil())
+		request.Params.Scale = out.Scaling{
+			"web":    {Instances: instances(2)},
+			"worker": {Instances: instances(5)},
+		}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pushed).To(Equal(`applications:
+  - name: api
+    instances: 2
+  - name: jobs
+    processes:
+      - type: worker
+        instances: 5
+    instances: 2
+`))
+	})
+
+	It("scales a single app manifest pushed under current_app_name", func() {
+		request.Params.ManifestPath = "assets/varsManifest.yml"
+		request.Params.Vars = map[string]interface{}{"app_name": "web", "instances": 1, "name": "world", "db": map[string]interface{}{"password": "s3cret"}, "service": "db"}
+		request.Params.CurrentAppName = "web-blue"
+		request.Params.Scale = out.Scaling{"web-blue": {Instances: instances(3)}}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pushed).To(ContainSubstring("instances: 3\n"))
+	})
+
+	It("rejects scaling that doesn't fit the manifest, before logging in", func() {
+		request.Params.Scale = out.Scaling{
+			"web":    {Instances: instances(-1), Memory: "lots"},
+			"cron":   {Instances: instances(1)},
+			"worker": {Processes: map[string]out.Scale{"x": {}}},
+		}
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(`invalid scale:
+  scale.cron: there is no app or process type named cron in the manifest
+  scale.web: instances must not be negative
+  scale.web: memory "lots" must be a size such as 512M or 1G
+  scale.worker: processes only go under an app, and there is no app named worker`))
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+})
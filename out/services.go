@@ -0,0 +1,310 @@
+package out
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+const DefaultServiceTimeout = 15 * time.Minute
+
+const (
+	ServiceManaged      = "managed"
+	ServiceUserProvided = "user-provided"
+)
+
+// Service is a service instance the put creates, or updates, before the
+// push. One with an offering is a managed service instance; one without is
+// a user-provided service instance.
+type Service struct {
+	Name            string                 `json:"name"`
+	Offering        string                 `json:"offering"`
+	Plan            string                 `json:"plan"`
+	Parameters      map[string]interface{} `json:"parameters"`
+	Tags            []string               `json:"tags"`
+	Credentials     map[string]interface{} `json:"credentials"`
+	SyslogDrainURL  string                 `json:"syslog_drain_url"`
+	RouteServiceURL string                 `json:"route_service_url"`
+	Timeout         string                 `json:"timeout"`
+}
+
+func (service Service) Type() string {
+	if service.Offering == "" {
+		return ServiceUserProvided
+	}
+	return ServiceManaged
+}
+
+// ServiceInstance is the deployed state of a service instance and of its
+// last create or update, which brokers may run asynchronously.
+type ServiceInstance struct {
+	Name        string
+	Type        string
+	Plan        string
+	Operation   string
+	State       string
+	Description string
+}
+
+// checkServices fails on services that could never be created, before
+// anything is changed.
+func checkServices(services []Service) error {
+	var problems []string
+	names := map[string]bool{}
+
+	for i, service := range services {
+		where := fmt.Sprintf("services[%d]", i)
+		if service.Name == "" {
+			problems = append(problems, where+": name is required")
+		} else {
+			where = fmt.Sprintf("service %q", service.Name)
+			if names[service.Name] {
+				problems = append(problems, where+": declared more than once")
+			}
+			names[service.Name] = true
+		}
+
+		if service.Type() == ServiceManaged {
+			if service.Plan == "" {
+				problems = append(problems, where+": plan is required with an offering")
+			}
+			if service.Credentials != nil || service.SyslogDrainURL != "" || service.RouteServiceURL != "" {
+				problems = append(problems, where+": credentials, syslog_drain_url and route_service_url are only for user-provided services, which have no offering")
+			}
+		} else if service.Plan != "" || service.Parameters != nil {
+			problems = append(problems, where+": plan and parameters need an offering")
+		}
+
+		if _, err := parseDuration(service.Timeout, DefaultServiceTimeout); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid timeout: %s", where, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid services:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// provisionServices creates the services that don't exist yet and updates
+// the rest, one at a time, waiting for each to be ready.
+func (command *Command) provisionServices(services []Service) error {
+	for _, service := range services {
+		timeout, _ := parseDuration(service.Timeout, DefaultServiceTimeout)
+		deadline := time.Now().Add(timeout)
+
+		instance, err := command.paas.ServiceInstance(service.Name)
+		switch {
+		case cli.Is(err, cli.ResourceNotFound):
+			fmt.Fprintf(command.log, "Creating service %s...\n", service.Name)
+			err = command.paas.CreateService(service)
+		case err != nil:
+			return err
+		case instance.Type != service.Type():
+			return fmt.Errorf("service %s is %s, but is declared as %s", service.Name, instance.Type, service.Type())
+		default:
+			// a broker refuses to update an instance that is still busy
+			if instance.State == "in progress" {
+				if err := command.waitForService(service.Name, deadline); err != nil {
+					return err
+				}
+			}
+			update := service
+			if instance.Plan == service.Plan {
+				update.Plan = ""
+			}
+			fmt.Fprintf(command.log, "Updating service %s...\n", service.Name)
+			err = command.paas.UpdateService(update)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := command.waitForService(service.Name, deadline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForService polls the service instance until its last operation is no
+// longer in progress, failing with the broker's message if it failed.
+func (command *Command) waitForService(name string, deadline time.Time) error {
+	for {
+		instance, err := command.paas.ServiceInstance(name)
+		if err != nil {
+			return err
+		}
+
+		switch instance.State {
+		case "in progress":
+		case "failed":
+			return &cli.Error{
+				Kind:    cli.ServiceFailed,
+				Command: instance.Operation + "-service",
+				Line:    fmt.Sprintf("%s of service %s failed: %s", instance.Operation, name, instance.Description),
+			}
+		default:
+			fmt.Fprintf(command.log, "Service %s is ready.\n", name)
+			return nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return &cli.Error{
+				Kind:    cli.ServiceFailed,
+				Command: instance.Operation + "-service",
+				Line:    fmt.Sprintf("%s of service %s is still in progress: %s", instance.Operation, name, instance.Description),
+			}
+		}
+
+		sleepUntil(deadline)
+	}
+}
+
+type v3ServiceInstances struct {
+	Resources []struct {
+		Name          string `json:"name"`
+		Type          string `json:"type"`
+		LastOperation struct {
+			Type        string `json:"type"`
+			State       string `json:"state"`
+			Description string `json:"description"`
+		} `json:"last_operation"`
+		Relationships struct {
+			ServicePlan struct {
+				Data struct {
+					GUID string `json:"guid"`
+				} `json:"data"`
+			} `json:"service_plan"`
+		} `json:"relationships"`
+	} `json:"resources"`
+	Included struct {
+		ServicePlans []struct {
+			GUID string `json:"guid"`
+			Name string `json:"name"`
+		} `json:"service_plans"`
+	} `json:"included"`
+}
+
+// ServiceInstance returns the targeted space's service instance with this
+// name.
+func (cf *CloudFoundry) ServiceInstance(name string) (ServiceInstance, error) {
+	spaceGUID, err := cf.spaceGUID()
+	if err != nil {
+		return ServiceInstance{}, err
+	}
+
+	query := url.Values{
+		"names":                {name},
+		"space_guids":          {spaceGUID},
+		"fields[service_plan]": {"guid,name"},
+	}
+	var instances v3ServiceInstances
+	if err := cf.curl("/v3/service_instances?"+query.Encode(), &instances); err != nil {
+		return ServiceInstance{}, err
+	}
+	if len(instances.Resources) == 0 {
+		return ServiceInstance{}, &cli.Error{Kind: cli.ResourceNotFound, Command: "curl", Line: fmt.Sprintf("service %s not found", name)}
+	}
+
+	instance := instances.Resources[0]
+	deployed := ServiceInstance{
+		Name:        instance.Name,
+		Type:        instance.Type,
+		Operation:   instance.LastOperation.Type,
+		State:       instance.LastOperation.State,
+		Description: instance.LastOperation.Description,
+	}
+	for _, plan := range instances.Included.ServicePlans {
+		if plan.GUID == instance.Relationships.ServicePlan.Data.GUID {
+			deployed.Plan = plan.Name
+		}
+	}
+	return deployed, nil
+}
+
+// CreateService creates a service instance, returning once the broker has
+// accepted the request.
+func (cf *CloudFoundry) CreateService(service Service) error {
+	if service.Type() == ServiceUserProvided {
+		return cf.runServiceCommand([]string{"create-user-provided-service", service.Name}, service)
+	}
+	return cf.runServiceCommand([]string{"create-service", service.Offering, service.Plan, service.Name}, service)
+}
+
+// UpdateService updates a service instance's parameters, tags or
+// credentials, and its plan when one is given, returning once the broker has
+// accepted the request.
+func (cf *CloudFoundry) UpdateService(service Service) error {
+	if service.Type() == ServiceUserProvided {
+		return cf.runServiceCommand([]string{"update-user-provided-service", service.Name}, service)
+	}
+
+	args := []string{"update-service", service.Name}
+	if service.Plan != "" {
+		args = append(args, "-p", service.Plan)
+	}
+	return cf.runServiceCommand(args, service)
+}
+
+// runServiceCommand adds the service's options to args and runs them. The
+// parameters or credentials are passed as a JSON file, which keeps them off
+// the process list.
+func (cf *CloudFoundry) runServiceCommand(args []string, service Service) error {
+	command := len(args)
+
+	flag, values := "-c", service.Parameters
+	if service.Type() == ServiceUserProvided {
+		flag, values = "-p", service.Credentials
+	}
+	if values != nil {
+		path, err := writeJSON(values)
+		if err != nil {
+			return fmt.Errorf("service %s: %s", service.Name, err)
+		}
+		defer os.Remove(path)
+		args = append(args, flag, path)
+	}
+
+	if service.SyslogDrainURL != "" {
+		args = append(args, "-l", service.SyslogDrainURL)
+	}
+	if service.RouteServiceURL != "" {
+		args = append(args, "-r", service.RouteServiceURL)
+	}
+	if len(service.Tags) > 0 {
+		args = append(args, "-t", strings.Join(service.Tags, ","))
+	}
+
+	// cf fails an update with nothing to change
+	if strings.HasPrefix(args[0], "update-") && len(args) == command {
+		return nil
+	}
+
+	return cf.run(args...)
+}
+
+func writeJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := ioutil.TempFile("", "service-*.json")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
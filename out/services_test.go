@@ -0,0 +1,247 @@
+package out_test
+
+import (
+	"context"
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Services", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		log          *gbytes.Buffer
+		events       []string
+		instances    map[string]out.ServiceInstance
+	)
+
+	notFound := &cli.Error{Kind: cli.ResourceNotFound, Command: "curl", Line: "service not found"}
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+		events = nil
+
+		instances = map[string]out.ServiceInstance{}
+		cloudFoundry.ServiceInstanceStub = func(name string) (out.ServiceInstance, error) {
+			instance, found := instances[name]
+			if !found {
+				return out.ServiceInstance{}, notFound
+			}
+			return instance, nil
+		}
+		cloudFoundry.CreateServiceStub = func(service out.Service) error {
+			events = append(events, "create "+service.Name)
+			instances[service.Name] = out.ServiceInstance{Name: service.Name, Type: service.Type(), Plan: service.Plan, Operation: "create", State: "succeeded"}
+			return nil
+		}
+		cloudFoundry.UpdateServiceStub = func(service out.Service) error {
+			events = append(events, "update "+service.Name)
+			return nil
+		}
+		cloudFoundry.PushAppStub = func(_ string, _ string, _ string, _ string, _ bool, _ bool, _ string, _ func() error) (out.PushResult, error) {
+			events = append(events, "push")
+			return out.PushResult{}, nil
+		}
+
+		request = newRequest()
+		request.Params.Services = []out.Service{
+			{Name: "db", Offering: "postgres", Plan: "small", Parameters: map[string]interface{}{"version": "15"}},
+			{Name: "smtp", Credentials: map[string]interface{}{"password": "mail-s3cret"}},
+		}
+	})
+
+	It("creates the missing services before the push", func() {
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(events).To(Equal([]string{"create db", "create smtp", "push"}))
+		Expect(cloudFoundry.CreateServiceArgsForCall(0)).To(Equal(request.Params.Services[0]))
+		Expect(log).To(gbytes.Say("Creating service db...\nService db is ready.\n"))
+	})
+
+	It("updates the services that exist, only changing the plan when it differs", func() {
+		instances["db"] = out.ServiceInstance{Name: "db", Type: "managed", Plan: "small", Operation: "create", State: "succeeded"}
+		instances["smtp"] = out.ServiceInstance{Name: "smtp", Type: "user-provided", Operation: "create", State: "succeeded"}
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(events).To(Equal([]string{"update db", "update smtp", "push"}))
+		Expect(cloudFoundry.UpdateServiceArgsForCall(0)).To(Equal(out.Service{Name: "db", Offering: "postgres", Parameters: map[string]interface{}{"version": "15"}}))
+	})
+
+	It("waits for an operation that is already in progress before updating", func() {
+		request.Params.Services = request.Params.Services[:1]
+		request.Params.Services[0].Plan = "large"
+		cloudFoundry.ServiceInstanceReturnsOnCall(0, out.ServiceInstance{Name: "db", Type: "managed", Plan: "small", Operation: "update", State: "in progress"}, nil)
+		cloudFoundry.ServiceInstanceReturnsOnCall(1, out.ServiceInstance{Name: "db", Type: "managed", Plan: "small", Operation: "update", State: "succeeded"}, nil)
+		cloudFoundry.ServiceInstanceReturnsOnCall(2, out.ServiceInstance{Name: "db", Type: "managed", Plan: "large", Operation: "update", State: "succeeded"}, nil)
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(cloudFoundry.UpdateServiceArgsForCall(0).Plan).To(Equal("large"))
+		Expect(log).To(gbytes.Say("Service db is ready.\nUpdating service db...\nService db is ready.\n"))
+	})
+
+	It("fails with the broker's message, without pushing", func() {
+		cloudFoundry.CreateServiceStub = func(service out.Service) error {
+			instances[service.Name] = out.ServiceInstance{Name: service.Name, Type: "managed", Operation: "create", State: "failed", Description: "Plan small is not available in this region"}
+			return nil
+		}
+
+		_, err := command.Run(request)
+		Expect(cli.Is(err, cli.ServiceFailed)).To(BeTrue())
+		Expect(err).To(MatchError("cf create-service: service failed: create of service db failed: Plan small is not available in this region"))
+		Expect(events).To(BeEmpty())
+	})
+
+	It("gives up on an operation that runs past the timeout", func() {
+		request.Params.Services = request.Params.Services[:1]
+		request.Params.Services[0].Timeout = "0s"
+		cloudFoundry.CreateServiceStub = func(service out.Service) error {
+			instances[service.Name] = out.ServiceInstance{Name: service.Name, Type: "managed", Operation: "create", State: "in progress", Description: "Creating cluster"}
+			return nil
+		}
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("cf create-service: service failed: create of service db is still in progress: Creating cluster"))
+		Expect(cloudFoundry.PushAppCallCount()).To(Equal(0))
+	})
+
+	It("refuses to turn a service into another kind", func() {
+		instances["db"] = out.ServiceInstance{Name: "db", Type: "user-provided", State: "succeeded"}
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("service db is user-provided, but is declared as managed"))
+	})
+
+	It("rejects services that could never be created, before logging in", func() {
+		request.Params.Services = []out.Service{
+			{Offering: "postgres"},
+			{Name: "smtp", Plan: "small"},
+			{Name: "smtp", Offering: "mail", Plan: "free", Credentials: map[string]interface{}{}},
+		}
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(`invalid services:
+  services[0]: name is required
+  services[0]: plan is required with an offering
+  service "smtp": plan and parameters need an offering
+  service "smtp": declared more than once
+  service "smtp": credentials, syslog_drain_url and route_service_url are only for user-provided services, which have no offering`))
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+
+	It("adds the services to the dry run plan", func() {
+		instances["smtp"] = out.ServiceInstance{Name: "smtp", Type: "user-provided", State: "succeeded"}
+		request.Params.DryRun = true
+		cloudFoundry.GetAppReturns(out.App{}, &cli.Error{Kind: cli.AppNotFound})
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log).To(gbytes.Say(`1\. create service db \(postgres small\)\n  2\. update service smtp\n  3\. push app1, app2\n`))
+		Expect(events).To(BeEmpty())
+	})
+
+	It("treats service parameters and credentials as secrets", func() {
+		Expect(request.Secrets()).To(ContainElement("mail-s3cret"))
+		Expect(request.Secrets()).To(ContainElement("15"))
+	})
+})
+
+var _ = Describe("CloudFoundry services", func() {
+	var (
+		runner       *clifakes.FakeRunner
+		cloudFoundry *out.CloudFoundry
+		files        []string
+	)
+
+	BeforeEach(func() {
+		runner = &clifakes.FakeRunner{}
+		cloudFoundry = out.NewCloudFoundry(context.Background(), runner)
+		files = nil
+
+		runner.RunStub = func(ctx context.Context, args ...string) (cli.Result, error) {
+			switch args[0] {
+			case "space":
+				return cli.Result{Stdout: "space-guid\n"}, nil
+			case "curl":
+				return cli.Result{Stdout: `{
+					"resources": [{
+						"name": "db",
+						"type": "managed",
+						"last_operation": {"type": "update", "state": "in progress", "description": "Resizing"},
+						"relationships": {"service_plan": {"data": {"guid": "plan-2"}}}
+					}],
+					"included": {"service_plans": [{"guid": "plan-1", "name": "small"}, {"guid": "plan-2", "name": "large"}]}
+				}`}, nil
+			}
+			// the JSON files are removed once cf has read them
+			for i, arg := range args {
+				if arg == "-c" || arg == "-p" && args[0] != "update-service" {
+					contents, err := ioutil.ReadFile(args[i+1])
+					Expect(err).NotTo(HaveOccurred())
+					files = append(files, string(contents))
+					args[i+1] = "FILE"
+				}
+			}
+			return cli.Result{}, nil
+		}
+
+		Expect(cloudFoundry.Target("org", "my-space")).To(BeNil())
+	})
+
+	It("looks up a service instance in the targeted space", func() {
+		instance, err := cloudFoundry.ServiceInstance("db")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance).To(Equal(out.ServiceInstance{Name: "db", Type: "managed", Plan: "large", Operation: "update", State: "in progress", Description: "Resizing"}))
+
+		Expect(commands(runner)).To(Equal([]string{
+			"cf target -o org -s my-space",
+			"cf space my-space --guid",
+			"cf curl /v3/service_instances?fields%5Bservice_plan%5D=guid%2Cname&names=db&space_guids=space-guid",
+		}))
+	})
+
+	It("creates a managed service with its parameters in a file", func() {
+		err := cloudFoundry.CreateService(out.Service{Name: "db", Offering: "postgres", Plan: "small", Parameters: map[string]interface{}{"version": "15"}, Tags: []string{"sql", "primary"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(commands(runner)[1]).To(Equal("cf create-service postgres small db -c FILE -t sql,primary"))
+		Expect(files).To(Equal([]string{`{"version":"15"}`}))
+	})
+
+	It("creates a user-provided service with its credentials in a file", func() {
+		err := cloudFoundry.CreateService(out.Service{Name: "smtp", Credentials: map[string]interface{}{"password": "s3cret"}, SyslogDrainURL: "syslog://logs.example.com"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(commands(runner)[1]).To(Equal("cf create-user-provided-service smtp -p FILE -l syslog://logs.example.com"))
+		Expect(files).To(Equal([]string{`{"password":"s3cret"}`}))
+	})
+
+	It("updates a managed service", func() {
+		err := cloudFoundry.UpdateService(out.Service{Name: "db", Offering: "postgres", Plan: "large", Tags: []string{"sql"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(commands(runner)[1]).To(Equal("cf update-service db -p large -t sql"))
+	})
+
+	It("doesn't run an update that changes nothing", func() {
+		Expect(cloudFoundry.UpdateService(out.Service{Name: "db", Offering: "postgres"})).To(BeNil())
+		Expect(cloudFoundry.UpdateService(out.Service{Name: "smtp"})).To(BeNil())
+
+		Expect(commands(runner)).To(Equal([]string{"cf target -o org -s my-space"}))
+	})
+})
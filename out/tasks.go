@@ -0,0 +1,259 @@
+package out
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/concourse/cf-resource/out/cli"
+)
+
+const DefaultTaskTimeout = 30 * time.Minute
+
+// Tasks are one-off commands run with cf run-task against the pushed apps.
+// before_switch tasks run once the new droplet is up but before a zero
+// downtime deploy removes the old app, so that a failure rolls the deploy
+// back; after_push tasks run once the deploy is done.
+type Tasks struct {
+	BeforeSwitch []Task `json:"before_switch"`
+	AfterPush    []Task `json:"after_push"`
+}
+
+type Task struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	Memory  string `json:"memory"`
+	Disk    string `json:"disk"`
+	App     string `json:"app"`
+	Timeout string `json:"timeout"`
+}
+
+// TaskRun identifies a task that cf run-task started.
+type TaskRun struct {
+	Name string
+	ID   int
+}
+
+// TaskState is the state (PENDING, RUNNING, SUCCEEDED, FAILED) of a task,
+// and why it failed.
+type TaskState struct {
+	State         string
+	FailureReason string
+}
+
+var (
+	taskNamePattern = regexp.MustCompile(`(?m)^\s*task name:\s*(\S+)`)
+	taskIDPattern   = regexp.MustCompile(`(?m)^\s*task id:\s*(\d+)`)
+)
+
+// checkTasks fails on tasks that could never run, before anything is
+// changed. A task may leave out the app when only one app is pushed.
+func checkTasks(tasks Tasks, apps []string) error {
+	var problems []string
+
+	check := func(phase string, list []Task) {
+		for i, task := range list {
+			where := fmt.Sprintf("%s[%d]", phase, i)
+			if task.Command == "" {
+				problems = append(problems, where+": command is required")
+			}
+			if task.App == "" && len(apps) != 1 {
+				problems = append(problems, fmt.Sprintf("%s: app is required when %d apps are pushed", where, len(apps)))
+			}
+			if task.App != "" && !contains(apps, task.App) {
+				problems = append(problems, fmt.Sprintf("%s: app %q is not pushed by this put", where, task.App))
+			}
+			for field, size := range map[string]string{"memory": task.Memory, "disk": task.Disk} {
+				if _, err := megabytes(size); size != "" && err != nil {
+					problems = append(problems, fmt.Sprintf("%s: %s %q must be a size such as 512M or 1G", where, field, size))
+				}
+			}
+			if _, err := parseDuration(task.Timeout, DefaultTaskTimeout); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid timeout: %s", where, err))
+			}
+		}
+	}
+	check("before_switch", tasks.BeforeSwitch)
+	check("after_push", tasks.AfterPush)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid tasks:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// runTasks runs the tasks one after another, stopping at the first that
+// fails. apps are the pushed apps, for tasks that leave out the app.
+func (command *Command) runTasks(tasks []Task, apps []string) error {
+	for _, task := range tasks {
+		app := task.App
+		if app == "" {
+			app = apps[0]
+		}
+		if err := command.runTask(app, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runTask starts the task and waits for it to succeed or fail, printing its
+// logs as they come in. A task that runs past its timeout is terminated.
+func (command *Command) runTask(app string, task Task) error {
+	timeout, _ := parseDuration(task.Timeout, DefaultTaskTimeout)
+
+	fmt.Fprintf(command.log, "Running task on %s: %s\n", app, task.Command)
+	run, err := command.paas.RunTask(app, task)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	last := ""
+	warned := false
+
+	for {
+		state, err := command.paas.TaskState(app, run.ID)
+		if err != nil {
+			return err
+		}
+
+		// read after the state, so the logs of a finished task are complete
+		lines, err := command.paas.TaskLogs(app, run.Name)
+		if err != nil && !warned {
+			fmt.Fprintf(command.log, "warning: could not read the logs of task %s: %s\n", run.Name, err)
+			warned = true
+		}
+		for _, line := range linesAfter(lines, last) {
+			fmt.Fprintln(command.log, line)
+			last = line
+		}
+
+		switch state.State {
+		case "SUCCEEDED":
+			fmt.Fprintf(command.log, "Task %s on %s succeeded.\n", run.Name, app)
+			return nil
+		case "FAILED":
+			return &cli.Error{
+				Kind:    cli.TaskFailed,
+				Command: "run-task",
+				Line:    fmt.Sprintf("task %s on %s failed: %s", run.Name, app, state.FailureReason),
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			if err := command.paas.TerminateTask(app, run.ID); err != nil {
+				fmt.Fprintf(command.log, "warning: could not terminate task %s: %s\n", run.Name, err)
+			}
+			return &cli.Error{
+				Kind:    cli.TaskFailed,
+				Command: "run-task",
+				Line:    fmt.Sprintf("task %s on %s was still %s after %s and was terminated", run.Name, app, state.State, timeout),
+			}
+		}
+
+		sleepUntil(deadline)
+	}
+}
+
+// linesAfter returns the lines that come after last. The recent logs are a
+// window that slides as the app logs more, so once last has slid out of it
+// every line is new. Lines start with a timestamp, so they don't repeat.
+func linesAfter(lines []string, last string) []string {
+	for i := len(lines) - 1; i >= 0 && last != ""; i-- {
+		if lines[i] == last {
+			return lines[i+1:]
+		}
+	}
+	return lines
+}
+
+// RunTask starts a task with cf run-task on the app's current droplet. cf
+// CLI v6 takes the command as an argument rather than with --command.
+func (cf *CloudFoundry) RunTask(app string, task Task) (TaskRun, error) {
+	version, err := cf.Version()
+	if err != nil {
+		return TaskRun{}, err
+	}
+
+	args := []string{"run-task", app, task.Command}
+	if version.Supports(cli.TaskCommandFlag) {
+		args = []string{"run-task", app, "--command", task.Command}
+	}
+	if task.Name != "" {
+		args = append(args, "--name", task.Name)
+	}
+	if task.Memory != "" {
+		args = append(args, "-m", task.Memory)
+	}
+	if task.Disk != "" {
+		args = append(args, "-k", task.Disk)
+	}
+
+	result, err := cf.runner.Run(cf.ctx, args...)
+	if err != nil {
+		return TaskRun{}, err
+	}
+
+	name := taskNamePattern.FindStringSubmatch(result.Stdout)
+	id := taskIDPattern.FindStringSubmatch(result.Stdout)
+	if name == nil || id == nil {
+		return TaskRun{}, fmt.Errorf("cf run-task %s: could not read the task name and id from its output", app)
+	}
+
+	sequenceID, _ := strconv.Atoi(id[1])
+	return TaskRun{Name: name[1], ID: sequenceID}, nil
+}
+
+type v3Tasks struct {
+	Resources []struct {
+		State  string `json:"state"`
+		Result struct {
+			FailureReason string `json:"failure_reason"`
+		} `json:"result"`
+	} `json:"resources"`
+}
+
+// TaskState returns the state of the app's task with this id.
+func (cf *CloudFoundry) TaskState(app string, id int) (TaskState, error) {
+	guid, err := cf.appGUID(app)
+	if err != nil {
+		return TaskState{}, err
+	}
+
+	var tasks v3Tasks
+	if err := cf.curl(fmt.Sprintf("/v3/apps/%s/tasks?sequence_ids=%d", guid, id), &tasks); err != nil {
+		return TaskState{}, err
+	}
+	if len(tasks.Resources) == 0 {
+		return TaskState{}, &cli.Error{Kind: cli.ResourceNotFound, Command: "curl", Line: fmt.Sprintf("task %d of %s not found", id, app)}
+	}
+
+	return TaskState{
+		State:         tasks.Resources[0].State,
+		FailureReason: tasks.Resources[0].Result.FailureReason,
+	}, nil
+}
+
+// TaskLogs returns the app's recent log lines that came from the task.
+func (cf *CloudFoundry) TaskLogs(app string, name string) ([]string, error) {
+	result, err := cf.runner.Run(cli.Quietly(cf.ctx), "logs", app, "--recent")
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if strings.Contains(line, "[APP/TASK/"+name+"/") {
+			lines = append(lines, strings.TrimRight(line, "\r"))
+		}
+	}
+	return lines, nil
+}
+
+// TerminateTask stops a running task.
+func (cf *CloudFoundry) TerminateTask(app string, id int) error {
+	return cf.run("terminate-task", app, strconv.Itoa(id))
+}
@@ -0,0 +1,274 @@
+package out_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/concourse/cf-resource/out"
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
+	"github.com/concourse/cf-resource/out/outfakes"
+)
+
+var _ = Describe("Tasks", func() {
+	var (
+		cloudFoundry *outfakes.FakePAAS
+		command      *out.Command
+		request      out.Request
+		log          *gbytes.Buffer
+		events       []string
+	)
+
+	BeforeEach(func() {
+		cloudFoundry = &outfakes.FakePAAS{}
+		log = gbytes.NewBuffer()
+		command = out.NewCommand(cloudFoundry, log)
+		events = nil
+
+		// a zero downtime deploy: afterPush runs before the old app is
+		// deleted, and rolls the deploy back when it fails
+		cloudFoundry.PushAppStub = func(_ string, _ string, _ string, _ string, _ bool, _ bool, _ string, afterPush func() error) (out.PushResult, error) {
+			events = append(events, "push")
+			if afterPush != nil {
+				if err := afterPush(); err != nil {
+					events = append(events, "roll back")
+					return out.PushResult{Strategy: "zdt", RolledBack: true}, err
+				}
+			}
+			events = append(events, "delete old app")
+			return out.PushResult{Strategy: "zdt"}, nil
+		}
+		cloudFoundry.RunTaskStub = func(app string, task out.Task) (out.TaskRun, error) {
+			events = append(events, "task on "+app+": "+task.Command)
+			return out.TaskRun{Name: task.Name, ID: cloudFoundry.RunTaskCallCount()}, nil
+		}
+		cloudFoundry.TaskStateReturns(out.TaskState{State: "SUCCEEDED"}, nil)
+		cloudFoundry.SetAppMetadataStub = func(name string, _ map[string]string, _ map[string]string) error {
+			events = append(events, "stamp "+name)
+			return nil
+		}
+
+		request = newRequest()
+		request.Params.CurrentAppName = "app1"
+		request.Params.Tasks = out.Tasks{
+			BeforeSwitch: []out.Task{{Name: "migrate", Command: "rake db:migrate", Memory: "512M"}},
+			AfterPush:    []out.Task{{Name: "warm", Command: "bin/warm-cache"}},
+		}
+	})
+
+	It("runs before_switch tasks before the old app is deleted and after_push tasks after", func() {
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(events).To(Equal([]string{
+			"push",
+			"task on app1: rake db:migrate",
+			"delete old app",
+			"task on app1: bin/warm-cache",
+			"stamp app1",
+		}))
+
+		app, task := cloudFoundry.RunTaskArgsForCall(0)
+		Expect(app).To(Equal("app1"))
+		Expect(task).To(Equal(out.Task{Name: "migrate", Command: "rake db:migrate", Memory: "512M"}))
+	})
+
+	It("prints each task's logs once", func() {
+		cloudFoundry.TaskLogsReturnsOnCall(0, []string{"[APP/TASK/migrate/0] OUT Migrating to CreateUsers"}, nil)
+		cloudFoundry.TaskLogsReturnsOnCall(1, []string{"[APP/TASK/warm/0] OUT warmed 10 pages"}, nil)
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(log).To(gbytes.Say(`Running task on app1: rake db:migrate\n`))
+		Expect(log).To(gbytes.Say(`\[APP/TASK/migrate/0\] OUT Migrating to CreateUsers\n`))
+		Expect(log).To(gbytes.Say(`Task migrate on app1 succeeded.\n`))
+		Expect(log).To(gbytes.Say(`\[APP/TASK/warm/0\] OUT warmed 10 pages\n`))
+		app, name := cloudFoundry.TaskLogsArgsForCall(0)
+		Expect(app).To(Equal("app1"))
+		Expect(name).To(Equal("migrate"))
+	})
+
+	It("keeps printing new lines as older ones slide out of the recent logs", func() {
+		cloudFoundry.TaskStateReturnsOnCall(0, out.TaskState{State: "RUNNING"}, nil)
+		cloudFoundry.TaskLogsReturnsOnCall(0, []string{
+			"12:00:01 [APP/TASK/migrate/0] OUT Migrating to CreateUsers",
+			"12:00:02 [APP/TASK/migrate/0] OUT Migrating to AddEmailToUsers",
+		}, nil)
+		cloudFoundry.TaskLogsReturnsOnCall(1, []string{
+			"12:00:02 [APP/TASK/migrate/0] OUT Migrating to AddEmailToUsers",
+			"12:00:03 [APP/TASK/migrate/0] OUT Migrating to CreateOrders",
+		}, nil)
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(log).To(gbytes.Say(`OUT Migrating to CreateUsers\n\S+ \S+ OUT Migrating to AddEmailToUsers\n\S+ \S+ OUT Migrating to CreateOrders\n`))
+		Expect(string(log.Contents())).NotTo(MatchRegexp(`AddEmailToUsers[\s\S]*AddEmailToUsers`))
+	})
+
+	It("rolls a zero downtime deploy back when a before_switch task fails", func() {
+		cloudFoundry.TaskStateReturns(out.TaskState{State: "FAILED", FailureReason: "Exited with status 1"}, nil)
+
+		_, err := command.Run(request)
+		Expect(cli.Is(err, cli.TaskFailed)).To(BeTrue())
+		Expect(err).To(MatchError("cf run-task: task failed: task migrate on app1 failed: Exited with status 1"))
+
+		Expect(events).To(Equal([]string{"push", "task on app1: rake db:migrate", "roll back"}))
+		Expect(log).To(gbytes.Say("The zdt deploy failed and was rolled back"))
+	})
+
+	It("fails the put without stamping the apps when an after_push task fails", func() {
+		cloudFoundry.TaskStateReturnsOnCall(1, out.TaskState{State: "FAILED", FailureReason: "Exited with status 2"}, nil)
+
+		_, err := command.Run(request)
+		Expect(cli.Is(err, cli.TaskFailed)).To(BeTrue())
+		Expect(events).To(Equal([]string{
+			"push",
+			"task on app1: rake db:migrate",
+			"delete old app",
+			"task on app1: bin/warm-cache",
+		}))
+	})
+
+	It("terminates a task that runs past its timeout", func() {
+		request.Params.Tasks.BeforeSwitch[0].Timeout = "0s"
+		cloudFoundry.TaskStateReturns(out.TaskState{State: "RUNNING"}, nil)
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError("cf run-task: task failed: task migrate on app1 was still RUNNING after 0s and was terminated"))
+
+		Expect(cloudFoundry.TerminateTaskCallCount()).To(Equal(1))
+		app, id := cloudFoundry.TerminateTaskArgsForCall(0)
+		Expect(app).To(Equal("app1"))
+		Expect(id).To(Equal(1))
+	})
+
+	It("rejects tasks that could never run, before logging in", func() {
+		request.Params.CurrentAppName = ""
+		request.Params.Tasks = out.Tasks{
+			BeforeSwitch: []out.Task{{Command: "rake db:migrate", Memory: "lots"}},
+			AfterPush:    []out.Task{{App: "app3"}},
+		}
+
+		_, err := command.Run(request)
+		Expect(err).To(MatchError(`invalid tasks:
+  before_switch[0]: app is required when 2 apps are pushed
+  before_switch[0]: memory "lots" must be a size such as 512M or 1G
+  after_push[0]: command is required
+  after_push[0]: app "app3" is not pushed by this put`))
+		Expect(cloudFoundry.LoginCallCount()).To(Equal(0))
+	})
+
+	It("adds the tasks to the dry run plan", func() {
+		request.Params.DryRun = true
+		cloudFoundry.GetAppReturns(out.App{Name: "app1", Instances: 1}, nil)
+
+		_, err := command.Run(request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log).To(gbytes.Say(`2\. push app1\n  3\. run task on app1: rake db:migrate\n  4\. delete app1-venerable\n  5\. run task on app1: bin/warm-cache\n`))
+	})
+})
+
+var _ = Describe("CloudFoundry tasks", func() {
+	var (
+		runner       *clifakes.FakeRunner
+		cloudFoundry *out.CloudFoundry
+	)
+
+	BeforeEach(func() {
+		runner = &clifakes.FakeRunner{}
+		cloudFoundry = out.NewCloudFoundry(context.Background(), runner)
+	})
+
+	runTask := func(version string) (out.TaskRun, error) {
+		runner.RunStub = func(_ context.Context, args ...string) (cli.Result, error) {
+			if args[0] == "version" {
+				return cli.Result{Stdout: "cf version " + version + "\n"}, nil
+			}
+			return cli.Result{Stdout: "Creating task for app my-app...\nOK\n\nTask has been submitted successfully for execution.\ntask name:   migrate\ntask id:     7\n"}, nil
+		}
+		return cloudFoundry.RunTask("my-app", out.Task{Name: "migrate", Command: "rake db:migrate", Memory: "512M", Disk: "1G"})
+	}
+
+	It("starts a task and reads its name and id", func() {
+		run, err := runTask("8.7.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run).To(Equal(out.TaskRun{Name: "migrate", ID: 7}))
+
+		Expect(commands(runner)).To(Equal([]string{
+			"cf version",
+			"cf run-task my-app --command rake db:migrate --name migrate -m 512M -k 1G",
+		}))
+	})
+
+	It("passes the command as an argument to cf CLI v6", func() {
+		run, err := runTask("6.53.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(run).To(Equal(out.TaskRun{Name: "migrate", ID: 7}))
+
+		Expect(commands(runner)).To(Equal([]string{
+			"cf version",
+			"cf run-task my-app rake db:migrate --name migrate -m 512M -k 1G",
+		}))
+	})
+
+	It("fails when cf run-task doesn't say which task it started", func() {
+		runner.RunReturns(cli.Result{Stdout: "OK\n"}, nil)
+
+		_, err := cloudFoundry.RunTask("my-app", out.Task{Command: "true"})
+		Expect(err).To(MatchError("cf run-task my-app: could not read the task name and id from its output"))
+	})
+
+	It("reads the state of a task", func() {
+		fakeAPI(runner, map[string]string{
+			"guid:my-app":                            "app-guid",
+			"/v3/apps/app-guid/tasks?sequence_ids=7": `{"resources":[{"state":"FAILED","result":{"failure_reason":"Exited with status 1"}}]}`,
+		})
+
+		state, err := cloudFoundry.TaskState("my-app", 7)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(out.TaskState{State: "FAILED", FailureReason: "Exited with status 1"}))
+	})
+
+	It("keeps only the task's lines of the recent logs", func() {
+		runner.RunStub = func(ctx context.Context, args ...string) (cli.Result, error) {
+			Expect(ctx).To(Equal(cli.Quietly(context.Background())))
+			return cli.Result{Stdout: "Retrieving logs for app my-app...\n" +
+				"   2026-10-19T12:00:00.00+0000 [APP/PROC/WEB/0] OUT listening\n" +
+				"   2026-10-19T12:00:01.00+0000 [APP/TASK/migrate/0] OUT Migrating\n" +
+				"   2026-10-19T12:00:02.00+0000 [APP/TASK/migrate-2/0] OUT other task\n"}, nil
+		}
+
+		lines, err := cloudFoundry.TaskLogs("my-app", "migrate")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lines).To(Equal([]string{"   2026-10-19T12:00:01.00+0000 [APP/TASK/migrate/0] OUT Migrating"}))
+		Expect(commands(runner)).To(Equal([]string{"cf logs my-app --recent"}))
+	})
+
+	It("terminates a task by id", func() {
+		Expect(cloudFoundry.TerminateTask("my-app", 7)).To(BeNil())
+		Expect(commands(runner)).To(Equal([]string{"cf terminate-task my-app 7"}))
+	})
+
+	It("reports a task that isn't there", func() {
+		fakeAPI(runner, map[string]string{
+			"guid:my-app":                            "app-guid",
+			"/v3/apps/app-guid/tasks?sequence_ids=7": `{"resources":[]}`,
+		})
+
+		_, err := cloudFoundry.TaskState("my-app", 7)
+		Expect(cli.Is(err, cli.ResourceNotFound)).To(BeTrue())
+	})
+
+	It("doesn't treat a failed run-task as a started task", func() {
+		runner.RunReturns(cli.Result{ExitCode: 1}, errors.New("exit status 1"))
+
+		_, err := cloudFoundry.RunTask("my-app", out.Task{Command: "true"})
+		Expect(err).To(HaveOccurred())
+	})
+})
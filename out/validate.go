@@ -0,0 +1,258 @@
+package out
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type fieldType int
+
+const (
+	stringField fieldType = iota
+	intField
+	boolField
+	sizeField
+	stringListField
+	envField
+	routesField
+	servicesField
+	mappingField
+	mappingListField
+)
+
+func (t fieldType) String() string {
+	switch t {
+	case intField:
+		return "a whole number"
+	case boolField:
+		return "true or false"
+	case sizeField:
+		return "a size such as 512M or 1G"
+	case stringListField:
+		return "a list of strings"
+	case envField:
+		return "a map of names to values"
+	case routesField:
+		return "a list of `route: host.domain` entries"
+	case servicesField:
+		return "a list of service instance names"
+	case mappingField:
+		return "a map"
+	case mappingListField:
+		return "a list of maps"
+	default:
+		return "a string"
+	}
+}
+
+var manifestFields = map[string]fieldType{
+	"applications": mappingListField,
+	"version":      intField,
+
+	// not supported by cf CLI v7, but reported by Lint
+	"inherit": stringField,
+}
+
+// appFields are the app attributes cf push understands, see
+// https://docs.cloudfoundry.org/devguide/deploy-apps/manifest-attributes.html
+var appFields = map[string]fieldType{
+	"name":                            stringField,
+	"path":                            stringField,
+	"buildpack":                       stringField,
+	"buildpacks":                      stringListField,
+	"command":                         stringField,
+	"disk_quota":                      sizeField,
+	"docker":                          mappingField,
+	"env":                             envField,
+	"health-check-type":               stringField,
+	"health-check-http-endpoint":      stringField,
+	"health-check-interval":           intField,
+	"health-check-invocation-timeout": intField,
+	"instances":                       intField,
+	"log-rate-limit-per-second":       stringField,
+	"memory":                          sizeField,
+	"metadata":                        mappingField,
+	"no-route":                        boolField,
+	"processes":                       mappingListField,
+	"random-route":                    boolField,
+	"default-route":                   boolField,
+	"routes":                          routesField,
+	"services":                        servicesField,
+	"sidecars":                        mappingListField,
+	"stack":                           stringField,
+	"timeout":                         intField,
+	"lifecycle":                       stringField,
+
+	// readiness checks, cf CLI v8 and later
+	"readiness-health-check-type":               stringField,
+	"readiness-health-check-http-endpoint":      stringField,
+	"readiness-health-check-interval":           intField,
+	"readiness-health-check-invocation-timeout": intField,
+
+	// deprecated, but still accepted by cf push
+	"host":        stringField,
+	"hosts":       stringListField,
+	"domain":      stringField,
+	"domains":     stringListField,
+	"no-hostname": boolField,
+}
+
+// ManifestError lists everything that is wrong with a manifest.
+type ManifestError struct {
+	Problems []string
+}
+
+func (e *ManifestError) Error() string {
+	return "invalid manifest:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate checks the manifest's keys and the types of their values, so
+// that a bad manifest fails the put before anything is changed. It should be
+// called on the interpolated manifest. Top-level keys that cf push ignores
+// are left to IgnoredKeys.
+func (manifest *Manifest) Validate() error {
+	root := manifest.root()
+	if root == nil {
+		return nil
+	}
+
+	var problems []string
+	problems = append(problems, validateMapping(root, "", manifestFields, false)...)
+
+	if applications := mappingValue(root, "applications"); applications != nil && applications.Kind == yaml.SequenceNode {
+		for i, app := range applications.Content {
+			// anything that isn't a map was reported as part of applications
+			if app.Kind != yaml.MappingNode {
+				continue
+			}
+
+			path := fmt.Sprintf("applications[%d]", i)
+			if name := mappingValue(app, "name"); name == nil || name.Value == "" {
+				problems = append(problems, problem(app, path, "has no name"))
+			}
+			problems = append(problems, validateMapping(app, path, appFields, true)...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ManifestError{Problems: problems}
+	}
+	return nil
+}
+
+// IgnoredKeys lists the top-level keys cf push ignores, such as one that only
+// holds YAML anchors for the apps to merge in.
+func (manifest *Manifest) IgnoredKeys() []string {
+	root := manifest.root()
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var ignored []string
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		if _, known := manifestFields[key.Value]; !known && key.Tag != "!!merge" {
+			ignored = append(ignored, problem(key, key.Value, "is not a known manifest key, cf push ignores it"))
+		}
+	}
+	return ignored
+}
+
+// validateMapping checks the node's keys against fields. Unknown keys are
+// only a problem when strict.
+func validateMapping(node *yaml.Node, path string, fields map[string]fieldType, strict bool) []string {
+	var problems []string
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Tag == "!!merge" {
+			continue
+		}
+
+		fieldPath := key.Value
+		if path != "" {
+			fieldPath = path + "." + key.Value
+		}
+
+		field, known := fields[key.Value]
+		if !known {
+			if !strict {
+				continue
+			}
+			problems = append(problems, problem(key, fieldPath, "is not a known manifest key"))
+			continue
+		}
+
+		if !validValue(value, field) {
+			problems = append(problems, problem(value, fieldPath, "must be "+field.String()))
+		}
+	}
+
+	return problems
+}
+
+func validValue(node *yaml.Node, field fieldType) bool {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch field {
+	case intField:
+		_, err := strconv.Atoi(node.Value)
+		return node.Kind == yaml.ScalarNode && err == nil
+	case boolField:
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!bool"
+	case sizeField:
+		_, err := megabytes(node.Value)
+		return node.Kind == yaml.ScalarNode && err == nil
+	case stringListField:
+		return isList(node, func(item *yaml.Node) bool { return item.Kind == yaml.ScalarNode })
+	case envField:
+		if node.Kind != yaml.MappingNode {
+			return false
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			if node.Content[i].Kind != yaml.ScalarNode {
+				return false
+			}
+		}
+		return true
+	case routesField:
+		return isList(node, func(item *yaml.Node) bool {
+			route := mappingValue(item, "route")
+			return route != nil && route.Kind == yaml.ScalarNode && route.Value != ""
+		})
+	case servicesField:
+		return isList(node, func(item *yaml.Node) bool {
+			if item.Kind == yaml.MappingNode {
+				item = mappingValue(item, "name")
+			}
+			return item != nil && item.Kind == yaml.ScalarNode && item.Value != ""
+		})
+	case mappingField:
+		return node.Kind == yaml.MappingNode
+	case mappingListField:
+		return isList(node, func(item *yaml.Node) bool { return item.Kind == yaml.MappingNode })
+	default:
+		return node.Kind == yaml.ScalarNode && node.Tag != "!!null"
+	}
+}
+
+func isList(node *yaml.Node, validItem func(*yaml.Node) bool) bool {
+	if node.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, item := range node.Content {
+		if !validItem(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func problem(node *yaml.Node, path string, message string) string {
+	return fmt.Sprintf("line %d: %s %s", node.Line, path, message)
+}
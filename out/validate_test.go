@@ -0,0 +1,94 @@
+package out_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/concourse/cf-resource/out"
+)
+
+var _ = Describe("Validate", func() {
+	It("accepts a valid manifest", func() {
+		manifest, err := out.NewManifest("assets/manifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(manifest.Validate()).To(BeNil())
+	})
+
+	It("reports every problem with its line", func() {
+		manifest, err := out.NewManifest("assets/badSchemaManifest.yml")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = manifest.Validate()
+		Expect(err).To(BeAssignableToTypeOf(&out.ManifestError{}))
+		Expect(err.(*out.ManifestError).Problems).To(Equal([]string{
+			"line 3: applications[0].instances must be a whole number",
+			"line 4: applications[0].memory must be a size such as 512M or 1G",
+			"line 5: applications[0].no-route must be true or false",
+			"line 7: applications[0].env must be a map of names to values",
+			"line 10: applications[0].routes must be a list of `route: host.domain` entries",
+			"line 11: applications[0].colour is not a known manifest key",
+			"line 12: applications[1] has no name",
+		}))
+	})
+
+	It("accepts the health check keys of cf CLI v8", func() {
+		file, err := ioutil.TempFile("", "validate_test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString(`applications:
+- name: app1
+  health-check-type: http
+  health-check-http-endpoint: /health
+  health-check-interval: 10
+  readiness-health-check-type: http
+  readiness-health-check-http-endpoint: /ready
+  readiness-health-check-invocation-timeout: 5
+  readiness-health-check-interval: 15
+`)
+		Expect(err).NotTo(HaveOccurred())
+		file.Close()
+
+		manifest, err := out.NewManifest(file.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(manifest.Validate()).To(BeNil())
+	})
+
+	It("only warns about top-level keys cf push ignores, such as one holding anchors", func() {
+		file, err := ioutil.TempFile("", "validate_test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString("defaults: &defaults\n  memory: 1G\napplications:\n- <<: *defaults\n  name: app1\n  colour: blue\n")
+		Expect(err).NotTo(HaveOccurred())
+		file.Close()
+
+		manifest, err := out.NewManifest(file.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		err = manifest.Validate()
+		Expect(err).To(BeAssignableToTypeOf(&out.ManifestError{}))
+		Expect(err.(*out.ManifestError).Problems).To(Equal([]string{"line 6: applications[0].colour is not a known manifest key"}))
+		Expect(manifest.IgnoredKeys()).To(Equal([]string{"line 1: defaults is not a known manifest key, cf push ignores it"}))
+	})
+
+	It("follows anchors and merge keys", func() {
+		file, err := ioutil.TempFile("", "validate_test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.Remove(file.Name())
+
+		_, err = file.WriteString("applications:\n- &app1\n  name: app1\n  memory: &memory 1G\n- <<: *app1\n  name: app2\n  disk_quota: *memory\n")
+		Expect(err).NotTo(HaveOccurred())
+		file.Close()
+
+		manifest, err := out.NewManifest(file.Name())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(manifest.Validate()).To(BeNil())
+	})
+})
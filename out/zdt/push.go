@@ -1,12 +1,21 @@
 package zdt
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
+	"time"
+
+	"github.com/concourse/cf-resource/out/cli"
 )
 
+// RollbackTimeout bounds each command that rolls back a failed push or
+// deletes the venerable app. They don't stop when the build is aborted, so
+// an abort can't leave the app renamed.
+const RollbackTimeout = 5 * time.Minute
+
 func CanPush(
-	cf func(args ...string) *exec.Cmd,
+	ctx context.Context,
+	cf cli.Runner,
 	currentAppName string,
 ) bool {
 
@@ -14,14 +23,15 @@ func CanPush(
 		return false
 	}
 
-	findErr := cf("app", currentAppName).Run()
+	_, findErr := cf.Run(ctx, "app", currentAppName)
 	appExists := findErr == nil
 
 	return appExists
 }
 
 func Push(
-	cf func(args ...string) *exec.Cmd,
+	ctx context.Context,
+	cf cli.Runner,
 	currentAppName string,
 	pushFunction func() error,
 	showLogs bool,
@@ -29,22 +39,42 @@ func Push(
 
 	venerableAppName := fmt.Sprintf("%s-venerable", currentAppName)
 
+	run := func(args ...string) func() error {
+		return func() error {
+			_, err := cf.Run(ctx, args...)
+			return err
+		}
+	}
+
+	cleanup := func(args ...string) func() error {
+		return func() error {
+			ctx, cancel := cli.Detached(ctx, RollbackTimeout)
+			defer cancel()
+			_, err := cf.Run(ctx, args...)
+			return err
+		}
+	}
+
 	actions := []Action{
 		{
-			Forward: cf("rename", currentAppName, venerableAppName).Run,
+			Forward: run("rename", currentAppName, venerableAppName),
 		},
 		{
 			Forward: pushFunction,
 			ReversePrevious: func() error {
-				if showLogs {
-					_ = cf("logs", currentAppName, "--recent").Run()
+				// whatever the push failed on, an app under the current name
+				// is in the way of renaming the venerable app back
+				if cleanup("app", currentAppName)() == nil {
+					if showLogs {
+						_ = cleanup("logs", currentAppName, "--recent")()
+					}
+					_ = cleanup("delete", "-f", currentAppName)()
 				}
-				_ = cf("delete", "-f", currentAppName).Run()
-				return cf("rename", venerableAppName, currentAppName).Run()
+				return cleanup("rename", venerableAppName, currentAppName)()
 			},
 		},
 		{
-			Forward: cf("delete", "-f", venerableAppName).Run,
+			Forward: cleanup("delete", "-f", venerableAppName),
 		},
 	}
 
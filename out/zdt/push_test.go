@@ -1,95 +1,200 @@
 package zdt_test
 
 import (
+	"context"
 	"errors"
-	"os/exec"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/gbytes"
 
+	"github.com/concourse/cf-resource/out/cli"
+	"github.com/concourse/cf-resource/out/cli/clifakes"
 	"github.com/concourse/cf-resource/out/zdt"
 )
 
-var stdout *gbytes.Buffer
-
-func cli(path string) func(args ...string) *exec.Cmd {
-	return func(args ...string) *exec.Cmd {
-		cmd := exec.Command(path, args...)
-		cmd.Stdout = stdout
-		return cmd
+func commands(cf *clifakes.FakeRunner) []string {
+	var commands []string
+	for i := 0; i < cf.RunCallCount(); i++ {
+		_, args := cf.RunArgsForCall(i)
+		commands = append(commands, "cf "+strings.Join(args, " "))
 	}
+	return commands
 }
 
 var _ = Describe("CanPush", func() {
-	cf := cli("assets/cf")
-	errCf := cli("assets/erroringCf")
+	var (
+		ctx context.Context
+		cf  *clifakes.FakeRunner
+	)
 
 	BeforeEach(func() {
-		stdout = gbytes.NewBuffer()
+		ctx = context.Background()
+		cf = &clifakes.FakeRunner{}
 	})
 
 	It("needs a currentAppName", func() {
-		Expect(zdt.CanPush(cf, "")).To(BeFalse())
-		Expect(stdout.Contents()).To(BeEmpty())
+		Expect(zdt.CanPush(ctx, cf, "")).To(BeFalse())
+		Expect(cf.RunCallCount()).To(Equal(0))
 	})
 
 	It("needs the app to exist", func() {
-		Expect(zdt.CanPush(errCf, "my-app")).To(BeFalse())
-		Expect(stdout).To(gbytes.Say("cf app my-app"))
+		cf.RunReturns(cli.Result{ExitCode: 1}, errors.New("App 'my-app' not found"))
+
+		Expect(zdt.CanPush(ctx, cf, "my-app")).To(BeFalse())
+		Expect(commands(cf)).To(Equal([]string{"cf app my-app"}))
 	})
 
 	It("is ok when app exists", func() {
-		Expect(zdt.CanPush(cf, "my-app")).To(BeTrue())
-		Expect(stdout).To(gbytes.Say("cf app my-app"))
+		Expect(zdt.CanPush(ctx, cf, "my-app")).To(BeTrue())
+		Expect(commands(cf)).To(Equal([]string{"cf app my-app"}))
 	})
 })
 
 var _ = Describe("Push", func() {
-	cf := cli("assets/cf")
+	var (
+		ctx context.Context
+		cf  *clifakes.FakeRunner
+	)
 
 	BeforeEach(func() {
-		stdout = gbytes.NewBuffer()
+		ctx = context.Background()
+		cf = &clifakes.FakeRunner{}
 	})
 
 	It("pushes an app with zero downtime", func() {
-		pushFunction := func() error { return cf("push", "my-app").Run() }
-		err := zdt.Push(cf, "my-app", pushFunction, false)
+		pushFunction := func() error {
+			_, err := cf.Run(ctx, "push", "my-app")
+			return err
+		}
+		err := zdt.Push(ctx, cf, "my-app", pushFunction, false)
 
 		Expect(err).NotTo(HaveOccurred())
-		Expect(stdout).To(gbytes.Say("cf rename my-app my-app-venerable"))
-		Expect(stdout).To(gbytes.Say("cf push my-app"))
-		Expect(stdout).To(gbytes.Say("cf delete -f my-app-venerable"))
+		Expect(commands(cf)).To(Equal([]string{
+			"cf rename my-app my-app-venerable",
+			"cf push my-app",
+			"cf delete -f my-app-venerable",
+		}))
 	})
 
 	It("rolls back on failed push", func() {
 		pushErr := errors.New("push failed")
 		pushFunction := func() error {
-			_ = cf("push", "my-app").Run()
+			_, _ = cf.Run(ctx, "push", "my-app")
 			return pushErr
 		}
-		err := zdt.Push(cf, "my-app", pushFunction, false)
-
-		Expect(err).To(Equal(pushErr))
-		Expect(stdout).To(gbytes.Say("cf rename my-app my-app-venerable"))
-		Expect(stdout).To(gbytes.Say("cf push my-app"))
-		Expect(stdout).ToNot(gbytes.Say("cf logs"))
-		Expect(stdout).To(gbytes.Say("cf delete -f my-app"))
-		Expect(stdout).To(gbytes.Say("cf rename my-app-venerable my-app"))
+		err := zdt.Push(ctx, cf, "my-app", pushFunction, false)
+
+		Expect(errors.Is(err, pushErr)).To(BeTrue())
+
+		var rolledBack *zdt.RolledBackError
+		Expect(errors.As(err, &rolledBack)).To(BeTrue())
+		Expect(commands(cf)).To(Equal([]string{
+			"cf rename my-app my-app-venerable",
+			"cf push my-app",
+			"cf app my-app",
+			"cf delete -f my-app",
+			"cf rename my-app-venerable my-app",
+		}))
 	})
 
 	It("shows logs on failure when flag is set", func() {
 		pushFunction := func() error {
-			_ = cf("push", "my-app").Run()
+			_, _ = cf.Run(ctx, "push", "my-app")
 			return errors.New("push failed")
 		}
-		err := zdt.Push(cf, "my-app", pushFunction, true)
+		err := zdt.Push(ctx, cf, "my-app", pushFunction, true)
 
 		Expect(err).To(HaveOccurred())
-		Expect(stdout).To(gbytes.Say("cf rename my-app my-app-venerable"))
-		Expect(stdout).To(gbytes.Say("cf push my-app"))
-		Expect(stdout).To(gbytes.Say("cf logs my-app --recent"))
-		Expect(stdout).To(gbytes.Say("cf delete -f my-app"))
-		Expect(stdout).To(gbytes.Say("cf rename my-app-venerable my-app"))
+		Expect(commands(cf)).To(Equal([]string{
+			"cf rename my-app my-app-venerable",
+			"cf push my-app",
+			"cf app my-app",
+			"cf logs my-app --recent",
+			"cf delete -f my-app",
+			"cf rename my-app-venerable my-app",
+		}))
+	})
+
+	It("only renames back when the push left no app behind", func() {
+		cf.RunStub = func(_ context.Context, args ...string) (cli.Result, error) {
+			if args[0] == "app" {
+				return cli.Result{ExitCode: 1}, errors.New("App 'my-app' not found")
+			}
+			return cli.Result{}, nil
+		}
+		pushFunction := func() error {
+			return cli.Classify([]string{"push"}, "Not logged in.", errors.New("exit status 1"))
+		}
+		err := zdt.Push(ctx, cf, "my-app", pushFunction, true)
+
+		Expect(cli.Is(err, cli.AuthenticationFailed)).To(BeTrue())
+		Expect(commands(cf)).To(Equal([]string{
+			"cf rename my-app my-app-venerable",
+			"cf app my-app",
+			"cf rename my-app-venerable my-app",
+		}))
+	})
+
+	It("deletes the half-staged app whatever the push output says", func() {
+		pushFunction := func() error {
+			_, _ = cf.Run(ctx, "push", "my-app")
+			output := "npm ERR! 401 Unauthorized - GET https://npm.example.com/left-pad\nError staging application\nFAILED"
+			return cli.Classify([]string{"push"}, output, errors.New("exit status 1"))
+		}
+		err := zdt.Push(ctx, cf, "my-app", pushFunction, false)
+
+		Expect(cli.Is(err, cli.StagingFailed)).To(BeTrue())
+		Expect(commands(cf)).To(Equal([]string{
+			"cf rename my-app my-app-venerable",
+			"cf push my-app",
+			"cf app my-app",
+			"cf delete -f my-app",
+			"cf rename my-app-venerable my-app",
+		}))
+	})
+
+	It("still rolls back when the build is aborted during the push", func() {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		var cancelled []string
+		cf.RunStub = func(runCtx context.Context, args ...string) (cli.Result, error) {
+			if runCtx.Err() != nil {
+				cancelled = append(cancelled, args[0])
+				return cli.Result{ExitCode: -1}, runCtx.Err()
+			}
+			return cli.Result{}, nil
+		}
+		pushFunction := func() error {
+			cancel()
+			_, err := cf.Run(ctx, "push", "my-app")
+			return err
+		}
+		err := zdt.Push(ctx, cf, "my-app", pushFunction, false)
+
+		var rolledBack *zdt.RolledBackError
+		Expect(errors.As(err, &rolledBack)).To(BeTrue())
+		Expect(cancelled).To(Equal([]string{"push"}))
+		Expect(commands(cf)).To(Equal([]string{
+			"cf rename my-app my-app-venerable",
+			"cf push my-app",
+			"cf app my-app",
+			"cf delete -f my-app",
+			"cf rename my-app-venerable my-app",
+		}))
+	})
+
+	It("passes the context to every command", func() {
+		type key struct{}
+		ctx = context.WithValue(ctx, key{}, "build-1")
+
+		err := zdt.Push(ctx, cf, "my-app", func() error { return nil }, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := 0; i < cf.RunCallCount(); i++ {
+			runCtx, _ := cf.RunArgsForCall(i)
+			Expect(runCtx.Value(key{})).To(Equal("build-1"))
+		}
 	})
 })
@@ -25,13 +25,27 @@ func (actions Actions) Execute() error {
 				}
 			}
 
-			return err
+			return &RolledBackError{Err: err}
 		}
 	}
 
 	return nil
 }
 
+// RolledBackError is returned when an action failed and the previous one
+// was successfully reversed.
+type RolledBackError struct {
+	Err error
+}
+
+func (e *RolledBackError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RolledBackError) Unwrap() error {
+	return e.Err
+}
+
 type Action struct {
 	Forward         func() error
 	ReversePrevious func() error
@@ -73,6 +73,7 @@ var _ = Describe("Rewind", func() {
 
 		err := actions.Execute()
 		Expect(err).To(MatchError("disaster"))
+		Expect(err).To(BeAssignableToTypeOf(&zdt.RolledBackError{}))
 
 		Expect(firstRun).To(BeTrue())
 		Expect(secondRun).To(BeTrue())